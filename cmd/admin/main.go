@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"golangApp/config"
+	"golangApp/jobs"
+	"golangApp/models"
+)
+
+// Tareas de mantenimiento de la base de datos.
+// Uso: go run -tags sqlite_fts5 ./cmd/admin <comando>
+func main() {
+	if len(os.Args) != 2 {
+		usage()
+	}
+
+	config.InitDB()
+
+	switch os.Args[1] {
+	case "rebuild-search-index":
+		if err := config.RebuildClientSearch(); err != nil {
+			log.Fatal("Failed to rebuild client search index: ", err)
+		}
+		log.Println("Client search index rebuilt")
+	case "purge-clients":
+		purged, err := jobs.PurgeDeletedClients(config.DB, config.Settings.ClientRetention)
+		if err != nil {
+			log.Fatal("Failed to purge deleted clients: ", err)
+		}
+		log.Printf("Purged %d clients deleted more than %s ago", purged, config.Settings.ClientRetention)
+	case "snapshot-kpi":
+		snapshot, err := jobs.TakeKPISnapshot(config.DB)
+		if err != nil {
+			log.Fatal("Failed to take client KPI snapshot: ", err)
+		}
+		log.Printf("Client KPI snapshot %d taken with %d clients", snapshot.ID, snapshot.Count)
+	case "send-reminders":
+		sent, err := jobs.SendTreatmentReminders(config.DB, config.Settings.TreatmentReminderDays)
+		if err != nil {
+			log.Fatal("Failed to send treatment reminders: ", err)
+		}
+		log.Printf("Sent %d treatment reminders due in the next %d days", sent, config.Settings.TreatmentReminderDays)
+	case "expire-points":
+		expired, err := models.ExpireLoyaltyPoints(config.DB, 0)
+		if err != nil {
+			log.Fatal("Failed to expire loyalty points: ", err)
+		}
+		log.Printf("Expired %d loyalty points", expired)
+	case "verify-kpi-aggregates":
+		diffs, err := models.VerifyClientAgeStats(config.DB)
+		if err != nil {
+			log.Fatal("Failed to verify client KPI aggregates: ", err)
+		}
+		loyaltyDiffs, err := models.VerifyClientLoyaltyStats(config.DB)
+		if err != nil {
+			log.Fatal("Failed to verify client KPI aggregates: ", err)
+		}
+		diffs = append(diffs, loyaltyDiffs...)
+		for _, diff := range diffs {
+			log.Println("Client KPI aggregates differ:", diff)
+		}
+		if len(diffs) > 0 {
+			log.Fatal("Client KPI aggregates are out of date, run rebuild-kpi-aggregates")
+		}
+		log.Println("Client KPI aggregates are up to date")
+	case "rebuild-kpi-aggregates":
+		if err := models.RebuildClientAgeStats(config.DB); err != nil {
+			log.Fatal("Failed to rebuild client KPI aggregates: ", err)
+		}
+		if err := models.RebuildClientLoyaltyStats(config.DB); err != nil {
+			log.Fatal("Failed to rebuild client KPI aggregates: ", err)
+		}
+		log.Println("Client KPI aggregates rebuilt")
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: admin <command>
+
+Commands:
+  rebuild-search-index   Rebuild the client full-text search index
+  purge-clients          Permanently remove clients deleted before the retention window
+  snapshot-kpi           Record a snapshot of the client KPIs for the history endpoint
+  send-reminders         Record reminders for the pet treatments due in the next TREATMENT_REMINDER_DAYS
+  expire-points          Record the expiry of loyalty points older than LOYALTY_POINTS_EXPIRY_MONTHS
+  verify-kpi-aggregates  Compare the running client KPI aggregates with the clients, orders and points
+  rebuild-kpi-aggregates Recompute the running client KPI aggregates from the clients, orders and points`)
+	os.Exit(2)
+}
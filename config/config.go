@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"golangApp/models"
 	"log"
 	"time"
@@ -27,13 +28,13 @@ func InitDB() {
 	// Para producción (SQLite en este caso)
 	dbPath := "./database/app.db"
 	var err error
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(dbPath), GormConfig())
 	if err != nil {
 		log.Fatal("Failed to connect to SQLite database:", err)
 	}
 
 	// Auto migrar tablas
-	DB.AutoMigrate(&models.User{}, &models.Group{}, &models.Client{})
+	Migrate(DB)
 
 	log.Println("Connected to SQLite database successfully")
 
@@ -50,13 +51,147 @@ func SetupTestDB() {
 
 	// Configurar la base de datos en memoria para pruebas
 	var err error
-	DB, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), GormConfig())
 	if err != nil {
 		panic("failed to connect to the database")
 	}
 
 	// Auto migrar tablas
-	DB.AutoMigrate(&models.Client{}, &models.User{}, &models.Group{})
+	Migrate(DB)
+}
+
+// GormConfig es la configuración de GORM. Las fechas de alta y modificación usan models.Now, para que
+// los tests puedan fijarlas
+func GormConfig() *gorm.Config {
+	return &gorm.Config{NowFunc: func() time.Time { return models.Now() }}
+}
+
+// Migrate crea o actualiza el esquema de la base de datos
+func Migrate(db *gorm.DB) {
+	addClientVersion := !db.Migrator().HasColumn(&models.Client{}, "version")
+	addTelephoneType := !db.Migrator().HasColumn(&models.Client{}, "telephone_type")
+	addClientTimestamps := db.Migrator().HasTable(&models.Client{}) && !db.Migrator().HasColumn(&models.Client{}, "created_at")
+	addLoyaltyNextExpiry := db.Migrator().HasTable(&models.ClientLoyaltyStats{}) && !db.Migrator().HasColumn(&models.ClientLoyaltyStats{}, "next_expiry")
+
+	// La clave de email es única: se agrega y se completa antes de que AutoMigrate cree el índice
+	if db.Migrator().HasTable(&models.Client{}) && !db.Migrator().HasColumn(&models.Client{}, "email_key") {
+		if err := db.Migrator().AddColumn(&models.Client{}, "EmailKey"); err != nil {
+			log.Println("Failed to add clients.email_key column:", err)
+		} else {
+			backfillEmailKeys(db)
+		}
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Group{}, &models.Client{}, &models.ClientVersion{}, &models.IdempotencyKey{}, &models.ClientMerge{}, &models.KPISnapshot{},
+		&models.ClientAgeStats{}, &models.ClientAgeCount{}, &models.CalendarToken{}, &models.Pet{}, &models.PetWeight{}, &models.PetTreatment{},
+		&models.Notification{}, &models.Store{}, &models.ServiceType{}, &models.Appointment{}, &models.AppointmentChange{},
+		&models.Order{}, &models.OrderLine{}, &models.LoyaltyRule{}, &models.LoyaltyEntry{}, &models.ClientLoyaltyStats{})
+
+	// La versión de un cliente continúa la numeración de su historial
+	if addClientVersion {
+		if err := db.Exec(`UPDATE clients SET version = (SELECT MAX(version) FROM client_versions WHERE client_id = clients.id)
+			WHERE EXISTS (SELECT 1 FROM client_versions WHERE client_id = clients.id)`).Error; err != nil {
+			log.Println("Failed to backfill clients.version:", err)
+		}
+	}
+
+	// Las fechas de alta y modificación se toman del historial; los clientes sin historial quedan en null
+	if addClientTimestamps {
+		if err := db.Exec(`UPDATE clients SET
+			created_at = (SELECT MIN(created_at) FROM client_versions WHERE client_id = clients.id),
+			updated_at = (SELECT MAX(created_at) FROM client_versions WHERE client_id = clients.id)`).Error; err != nil {
+			log.Println("Failed to backfill clients timestamps:", err)
+		}
+	}
+
+	// Los teléfonos anteriores se guardaban tal como se escribieron; se pasan a E.164
+	if addTelephoneType {
+		normalizeClientPhones(db)
+	}
+
+	// La edad de los clientes se deriva de birth_day; se elimina la columna de versiones anteriores
+	if db.Migrator().HasColumn("clients", "age") {
+		if err := db.Migrator().DropColumn(&models.Client{}, "age"); err != nil {
+			log.Println("Failed to drop clients.age column:", err)
+		}
+	}
+
+	// El microchip es único solo entre las mascotas activas, para poder registrar el de una eliminada en otra.
+	// Las versiones anteriores tenían un índice único que incluía las eliminadas
+	if db.Migrator().HasIndex(&models.Pet{}, "idx_pets_microchip") {
+		if err := db.Migrator().DropIndex(&models.Pet{}, "idx_pets_microchip"); err != nil {
+			log.Println("Failed to drop pets.microchip index:", err)
+		}
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_pets_active_microchip ON pets(microchip) WHERE deleted_at IS NULL").Error; err != nil {
+		log.Println("Failed to create pets.microchip index:", err)
+	}
+
+	// Los agregados de edades, compras y puntos del KPI se calculan desde cero la primera vez y después se mantienen con cada cambio
+	var ageStats int64
+	db.Model(&models.ClientAgeStats{}).Count(&ageStats)
+	if ageStats == 0 {
+		if err := models.RebuildClientAgeStats(db); err != nil {
+			log.Println("Failed to build client age aggregates:", err)
+		}
+	}
+	// Los de compras y puntos también se recalculan para completar el próximo vencimiento, que se agregó después
+	var loyaltyStats int64
+	db.Model(&models.ClientLoyaltyStats{}).Count(&loyaltyStats)
+	if loyaltyStats == 0 || addLoyaltyNextExpiry {
+		if err := models.RebuildClientLoyaltyStats(db); err != nil {
+			log.Println("Failed to build client loyalty aggregates:", err)
+		}
+	}
+
+	setupClientSearch(db)
+}
+
+// backfillEmailKeys calcula la clave de email de los clientes existentes. Si dos clientes solo se
+// diferencian en mayúsculas, el más reciente recibe una clave con su id para poder fusionarlos después
+func backfillEmailKeys(db *gorm.DB) {
+	var clients []models.Client
+	if err := db.Unscoped().Order("id").Find(&clients).Error; err != nil {
+		log.Println("Failed to load clients to backfill email keys:", err)
+		return
+	}
+	seen := map[string]int{}
+	for _, client := range clients {
+		key := models.EmailKey(client.Email)
+		if first, ok := seen[key]; ok {
+			log.Printf("Client %d has the same email as client %d (%s), merge them", client.ID, first, client.Email)
+			key = fmt.Sprintf("%s#%d", key, client.ID)
+		}
+		seen[key] = client.ID
+		if err := db.Model(&models.Client{}).Unscoped().Where("id = ?", client.ID).UpdateColumn("email_key", key).Error; err != nil {
+			log.Printf("Failed to backfill email key of client %d: %v", client.ID, err)
+		}
+	}
+}
+
+// normalizeClientPhones pasa a E.164 los teléfonos guardados antes de validarlos por país.
+// Los que no corresponden a ningún número real se dejan como están y se informan en el log
+func normalizeClientPhones(db *gorm.DB) {
+	var clients []models.Client
+	if err := db.Unscoped().Where("telephone <> ''").Find(&clients).Error; err != nil {
+		log.Println("Failed to load clients to normalize phones:", err)
+		return
+	}
+	for _, client := range clients {
+		raw := client.Telephone
+		if err := client.NormalizeTelephone(models.DefaultPhoneRegion); err != nil {
+			log.Printf("Client %d has an invalid phone number %q", client.ID, raw)
+			continue
+		}
+		err := db.Model(&models.Client{}).Unscoped().Where("id = ?", client.ID).UpdateColumns(map[string]interface{}{
+			"telephone":         client.Telephone,
+			"telephone_type":    client.TelephoneType,
+			"telephone_display": client.TelephoneDisplay,
+		}).Error
+		if err != nil {
+			log.Printf("Failed to normalize phone of client %d: %v", client.ID, err)
+		}
+	}
 }
 
 // seedData crea datos iniciales en la base de datos
@@ -79,6 +214,9 @@ func seedClients() {
 		for _, client := range clients {
 			DB.Create(&client)
 		}
+		if err := models.RebuildClientAgeStats(DB); err != nil {
+			log.Println("Failed to build client age aggregates:", err)
+		}
 		log.Println("Seeded initial clients")
 	}
 }
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ClientSearchEnabled indica si SQLite tiene FTS5 disponible para la búsqueda de clientes.
+// Requiere compilar con el tag sqlite_fts5 (go build -tags sqlite_fts5)
+var ClientSearchEnabled bool
+
+// ErrSearchUnavailable se devuelve al reconstruir el índice sin soporte de FTS5
+var ErrSearchUnavailable = errors.New("FTS5 is not available, build with -tags sqlite_fts5")
+
+var clientSearchDDL = []string{
+	`CREATE TRIGGER IF NOT EXISTS clients_fts_ai AFTER INSERT ON clients BEGIN
+		INSERT INTO clients_fts(rowid, name, last_name, email, telephone)
+		VALUES (new.id, new.name, new.last_name, new.email, new.telephone);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS clients_fts_ad AFTER DELETE ON clients BEGIN
+		INSERT INTO clients_fts(clients_fts, rowid, name, last_name, email, telephone)
+		VALUES ('delete', old.id, old.name, old.last_name, old.email, old.telephone);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS clients_fts_au AFTER UPDATE ON clients BEGIN
+		INSERT INTO clients_fts(clients_fts, rowid, name, last_name, email, telephone)
+		VALUES ('delete', old.id, old.name, old.last_name, old.email, old.telephone);
+		INSERT INTO clients_fts(rowid, name, last_name, email, telephone)
+		VALUES (new.id, new.name, new.last_name, new.email, new.telephone);
+	END`,
+}
+
+// setupClientSearch crea la tabla virtual FTS5 de clientes y los triggers que la mantienen
+// sincronizada. Si la tabla no existía se indexan los clientes ya cargados
+func setupClientSearch(db *gorm.DB) {
+	var existing int64
+	db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'clients_fts'").Scan(&existing)
+
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS clients_fts USING fts5(
+		name, last_name, email, telephone,
+		content='clients', content_rowid='id',
+		tokenize='unicode61 remove_diacritics 2', prefix='2 3'
+	)`).Error
+	if err != nil {
+		ClientSearchEnabled = false
+		if strings.Contains(err.Error(), "no such module") {
+			log.Println("FTS5 is not available, client search falls back to LIKE queries")
+		} else {
+			log.Println("Failed to create client search index:", err)
+		}
+		return
+	}
+
+	for _, ddl := range clientSearchDDL {
+		if err := db.Exec(ddl).Error; err != nil {
+			ClientSearchEnabled = false
+			log.Println("Failed to create client search triggers:", err)
+			return
+		}
+	}
+	ClientSearchEnabled = true
+
+	if existing == 0 {
+		if err := rebuildClientSearch(db); err != nil {
+			log.Println("Failed to index existing clients:", err)
+		}
+	}
+}
+
+// RebuildClientSearch reconstruye el índice de búsqueda a partir de la tabla clients
+func RebuildClientSearch() error {
+	return rebuildClientSearch(DB)
+}
+
+func rebuildClientSearch(db *gorm.DB) error {
+	if !ClientSearchEnabled {
+		return ErrSearchUnavailable
+	}
+	return db.Exec("INSERT INTO clients_fts(clients_fts) VALUES ('rebuild')").Error
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bufio"
+	"golangApp/models"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppSettings agrupa la configuración de la aplicación, leída de variables de entorno
+type AppSettings struct {
+	// ClientRetention es el tiempo que se conserva un cliente eliminado antes de purgarlo (CLIENT_RETENTION)
+	ClientRetention time.Duration
+	// PurgeInterval es cada cuánto se purgan los clientes eliminados, las claves de idempotencia vencidas y las fotos de KPI antiguas; 0 lo desactiva (PURGE_INTERVAL)
+	PurgeInterval time.Duration
+	// RequireIfMatch exige la cabecera If-Match en PUT, PATCH y DELETE de clientes y usuarios (REQUIRE_IF_MATCH)
+	RequireIfMatch bool
+	// IdempotencyTTL es el tiempo que se guarda la respuesta de un POST con Idempotency-Key (IDEMPOTENCY_TTL)
+	IdempotencyTTL time.Duration
+	// PhoneRegion es el país con el que se interpretan los teléfonos sin prefijo internacional (DEFAULT_PHONE_REGION)
+	PhoneRegion string
+	// StripEmailPlusTag hace que ana+promo@x.com y ana@x.com cuenten como el mismo email (EMAIL_STRIP_PLUS_TAG)
+	StripEmailPlusTag bool
+	// CheckEmailMX rechaza los emails cuyo dominio no tiene servidores de correo (EMAIL_CHECK_MX)
+	CheckEmailMX bool
+	// DisposableEmailDomainsFile es un archivo con un dominio de correo temporal por línea que
+	// reemplaza la lista incorporada (DISPOSABLE_EMAIL_DOMAINS_FILE)
+	DisposableEmailDomainsFile string
+	// KPISnapshotInterval es cada cuánto se guarda una foto de los KPI de clientes; 0 lo desactiva (KPI_SNAPSHOT_INTERVAL)
+	KPISnapshotInterval time.Duration
+	// KPISnapshotRetention es el tiempo que se conservan las fotos de KPI; 0 las conserva siempre (KPI_SNAPSHOT_RETENTION)
+	KPISnapshotRetention time.Duration
+	// ReminderInterval es cada cuánto se emiten los recordatorios de vacunas y tratamientos; 0 lo desactiva (REMINDER_INTERVAL)
+	ReminderInterval time.Duration
+	// TreatmentReminderDays es cuántos días antes de la fecha de repetición se recuerda un tratamiento al dueño (TREATMENT_REMINDER_DAYS)
+	TreatmentReminderDays int
+	// LoyaltyExpiryMonths son los meses que duran los puntos de fidelidad desde que se acumulan; 0 hace que no venzan (LOYALTY_POINTS_EXPIRY_MONTHS)
+	LoyaltyExpiryMonths int
+}
+
+// Settings es la configuración en uso
+var Settings = LoadSettings()
+
+// LoadSettings lee la configuración del entorno, usando valores por defecto para lo que no esté definido
+func LoadSettings() AppSettings {
+	return AppSettings{
+		ClientRetention: envDuration("CLIENT_RETENTION", 30*24*time.Hour),
+		PurgeInterval:   envDuration("PURGE_INTERVAL", 24*time.Hour),
+		RequireIfMatch:  envBool("REQUIRE_IF_MATCH", false),
+		IdempotencyTTL:  envDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		PhoneRegion:     envPhoneRegion("DEFAULT_PHONE_REGION", "ES"),
+
+		StripEmailPlusTag:          envBool("EMAIL_STRIP_PLUS_TAG", false),
+		CheckEmailMX:               envBool("EMAIL_CHECK_MX", false),
+		DisposableEmailDomainsFile: os.Getenv("DISPOSABLE_EMAIL_DOMAINS_FILE"),
+
+		KPISnapshotInterval:  envDuration("KPI_SNAPSHOT_INTERVAL", 24*time.Hour),
+		KPISnapshotRetention: envDuration("KPI_SNAPSHOT_RETENTION", 365*24*time.Hour),
+
+		ReminderInterval:      envDuration("REMINDER_INTERVAL", time.Hour),
+		TreatmentReminderDays: envCount("TREATMENT_REMINDER_DAYS", 14),
+
+		LoyaltyExpiryMonths: envCount("LOYALTY_POINTS_EXPIRY_MONTHS", 12),
+	}
+}
+
+func init() {
+	models.DefaultPhoneRegion = Settings.PhoneRegion
+	models.StripEmailPlusTag = Settings.StripEmailPlusTag
+	models.LoyaltyExpiryMonths = Settings.LoyaltyExpiryMonths
+	if Settings.CheckEmailMX {
+		models.EmailResolver = net.DefaultResolver
+	}
+	if Settings.DisposableEmailDomainsFile != "" {
+		domains, err := LoadDomainList(Settings.DisposableEmailDomainsFile)
+		if err != nil {
+			log.Printf("Failed to read DISPOSABLE_EMAIL_DOMAINS_FILE, using the built-in list: %v", err)
+			return
+		}
+		models.DisposableEmailDomains = domains
+	}
+}
+
+// LoadDomainList lee un dominio por línea, ignorando líneas vacías y comentarios (#)
+func LoadDomainList(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	domains := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if domain := models.EmailDomain("x@" + line); domain != "" {
+			domains[domain] = true
+		} else {
+			log.Printf("Ignoring invalid domain %q in %s", line, path)
+		}
+	}
+	return domains, scanner.Err()
+}
+
+// ParseDuration interpreta duraciones de Go ("36h") y además días ("30d")
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	d, err := ParseDuration(raw)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", name, raw, fallback)
+		return fallback
+	}
+	return d
+}
+
+func envPhoneRegion(name string, fallback string) string {
+	raw := strings.ToUpper(strings.TrimSpace(os.Getenv(name)))
+	if raw == "" {
+		return fallback
+	}
+	if !models.IsPhoneRegion(raw) {
+		log.Printf("Invalid %s %q, using %s", name, raw, fallback)
+		return fallback
+	}
+	return raw
+}
+
+func envCount(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using %d", name, raw, fallback)
+		return fallback
+	}
+	return n
+}
+
+func envBool(name string, fallback bool) bool {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %t", name, raw, fallback)
+		return fallback
+	}
+	return b
+}
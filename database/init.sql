@@ -5,7 +5,6 @@ CREATE TABLE IF NOT EXISTS clients (
     last_name TEXT NOT NULL,
     email TEXT UNIQUE NOT NULL,
     birth_day DATE NOT NULL,
-    age INTEGER NOT NULL,
     telephone TEXT
 );
 
@@ -15,30 +15,87 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/clients": {
+        "/api/v1/appointments": {
             "get": {
-                "description": "Recupera una lista de todos los clientes en la base de datos",
+                "description": "Devuelve los turnos que empiezan desde el día from hasta el día to inclusive, en UTC, ordenados por hora. Por defecto lista los próximos 30 días, con un máximo de 366",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Clientes"
+                    "Turnos"
+                ],
+                "summary": "Listar turnos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Día desde (YYYY-MM-DD), por defecto hoy",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Día hasta (YYYY-MM-DD), por defecto 29 días después de from",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Tienda",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del miembro del personal",
+                        "name": "staff_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "client_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "scheduled",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Estado del turno",
+                        "name": "status",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Obtiene todos los clientes",
                 "responses": {
                     "200": {
-                        "description": "Lista de clientes",
+                        "description": "Turnos",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Client"
+                                "$ref": "#/definitions/models.Appointment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     }
                 }
             },
             "post": {
-                "description": "Crea un nuevo cliente con los datos proporcionados",
+                "description": "Reserva un turno de un cliente, y opcionalmente de una de sus mascotas, para un servicio en una tienda con un miembro del personal. ends_at se calcula con la duración del servicio. La tienda debe estar abierta durante todo el turno y ni el miembro del personal ni la mascota pueden tener otro turno que se superponga",
                 "consumes": [
                     "application/json"
                 ],
@@ -46,64 +103,65 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Clientes"
+                    "Turnos"
                 ],
-                "summary": "Crear cliente",
+                "summary": "Reservar turno",
                 "parameters": [
                     {
-                        "description": "Información del Cliente",
-                        "name": "client",
+                        "description": "Información del Turno",
+                        "name": "appointment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Client"
+                            "$ref": "#/definitions/models.Appointment"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Cliente creado exitosamente",
+                        "description": "Turno reservado",
                         "schema": {
-                            "$ref": "#/definitions/models.Client"
+                            "$ref": "#/definitions/models.Appointment"
                         }
-                    }
-                }
-            }
-        },
-        "/api/v1/clients/kpi": {
-            "get": {
-                "description": "Calcula el promedio y la desviación estándar de edad de los clientes",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Clientes"
-                ],
-                "summary": "KPI de clientes",
-                "responses": {
-                    "200": {
-                        "description": "KPI de clientes calculado",
+                    },
+                    "400": {
+                        "description": "Datos inválidos o tienda cerrada",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ClientKPI"
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "El miembro del personal o la mascota ya tienen un turno a esa hora",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/v1/clients/{id}": {
+        "/api/v1/appointments/{id}": {
             "get": {
-                "description": "Recupera un cliente específico usando su ID",
+                "description": "Recupera un turno con su historial de reprogramaciones y cancelaciones",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Clientes"
+                    "Turnos"
                 ],
-                "summary": "Obtener cliente por ID",
+                "summary": "Obtener turno",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID del Cliente",
+                        "description": "ID del Turno",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -111,95 +169,248 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Detalles del cliente",
+                        "description": "Detalles del turno",
                         "schema": {
-                            "$ref": "#/definitions/models.Client"
+                            "$ref": "#/definitions/models.Appointment"
+                        }
+                    },
+                    "404": {
+                        "description": "Turno no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Actualiza la información de un cliente existente",
+            }
+        },
+        "/api/v1/appointments/{id}/cancel": {
+            "post": {
+                "description": "Cancela un turno vigente y libera su horario. El turno se conserva, y la cancelación queda en su historial con su motivo",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Clientes"
+                    "Turnos"
                 ],
-                "summary": "Actualizar cliente",
+                "summary": "Cancelar turno",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID del Cliente",
+                        "description": "ID del Turno",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Información actualizada del Cliente",
-                        "name": "client",
+                        "description": "Motivo de la cancelación",
+                        "name": "change",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Client"
+                            "$ref": "#/definitions/handlers.AppointmentChangeRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Cliente actualizado exitosamente",
+                        "description": "Turno cancelado",
                         "schema": {
-                            "$ref": "#/definitions/models.Client"
+                            "$ref": "#/definitions/models.Appointment"
+                        }
+                    },
+                    "400": {
+                        "description": "Falta el motivo",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Turno no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El turno ya estaba cancelado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Elimina un cliente específico usando su ID",
+            }
+        },
+        "/api/v1/appointments/{id}/reschedule": {
+            "post": {
+                "description": "Mueve un turno vigente a starts_at y, si se envía staff_id, a otro miembro del personal. Se comprueban el horario de la tienda y las superposiciones como al reservar, y el cambio queda en el historial del turno con su motivo",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Clientes"
+                    "Turnos"
                 ],
-                "summary": "Eliminar cliente",
+                "summary": "Reprogramar turno",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID del Cliente",
+                        "description": "ID del Turno",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Nuevo horario y motivo",
+                        "name": "change",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AppointmentChangeRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Cliente eliminado exitosamente"
+                    "200": {
+                        "description": "Turno reprogramado",
+                        "schema": {
+                            "$ref": "#/definitions/models.Appointment"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos o tienda cerrada",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Turno no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Turno cancelado, o el miembro del personal o la mascota ya tienen un turno a esa hora",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/api/v1/users": {
+        "/api/v1/clients": {
             "get": {
-                "description": "Recupera una lista de todos los usuarios registrados",
+                "description": "Recupera una página de clientes. Admite paginación por limit/offset o por cursor, filtros y orden por varios campos. Los enlaces a las páginas siguiente y anterior se envían en la cabecera Link (RFC 8288)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
+                ],
+                "summary": "Obtiene los clientes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Cantidad máxima de clientes por página (por defecto 50, máximo 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cantidad de clientes a saltar",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor opaco devuelto en next_cursor o prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Campos de orden separados por coma, con prefijo - para descendente (id, name, last_name, email, birth_day, age)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del apellido",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dominio del email",
+                        "name": "email_domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad mínima",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad máxima",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento desde (YYYY-MM-DD)",
+                        "name": "birth_day_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento hasta (YYYY-MM-DD)",
+                        "name": "birth_day_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del teléfono, nacional (600) o internacional (+34600)",
+                        "name": "telephone_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir clientes eliminados",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Obtener todos los usuarios",
                 "responses": {
                     "200": {
-                        "description": "Lista de usuarios",
+                        "description": "Página de clientes",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/handlers.ClientPage"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Crea un nuevo usuario con los datos proporcionados",
+                "description": "Crea un nuevo cliente con los datos proporcionados. La edad se deriva de birth_day; si se envía solo se comprueba que coincida",
                 "consumes": [
                     "application/json"
                 ],
@@ -207,449 +418,4922 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Crear usuario",
+                "summary": "Crear cliente",
                 "parameters": [
                     {
-                        "description": "Información del Usuario",
-                        "name": "user",
+                        "description": "Información del Cliente",
+                        "name": "client",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Client"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Usuario creado exitosamente",
+                        "description": "Cliente creado exitosamente",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Client"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "El email ya lo usa otro cliente o hay una petición en curso con la misma Idempotency-Key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key usada con otra petición",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/v1/users/{id}": {
+        "/api/v1/clients/birthdays": {
             "get": {
-                "description": "Recupera un usuario específico usando su ID",
+                "description": "Devuelve los clientes que cumplen años desde hoy hasta antes de que pase within, ordenados por fecha. Quien nació un 29 de febrero lo celebra el 1 de marzo en los años no bisiestos",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Obtener usuario por ID",
+                "summary": "Próximos cumpleaños",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID del Usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "default": "7d",
+                        "description": "Cantidad de días, incluido hoy (1d a 366d)",
+                        "name": "within",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "Zona horaria IANA con la que se determina el día de hoy",
+                        "name": "tz",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Detalles del usuario",
+                        "description": "Clientes con su próximo cumpleaños",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ClientBirthday"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Actualiza los datos de un usuario específico",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/v1/clients/cohorts": {
+            "get": {
+                "description": "Agrupa a los clientes por el mes o trimestre en que se dieron de alta, con el tamaño, el acumulado y las estadísticas de edad de cada cohorte. Los períodos sin altas entre la primera y la última cohorte aparecen con count 0. Acepta los mismos filtros que el listado",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Actualizar usuario",
+                "summary": "Cohortes de alta de clientes",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID del Usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "enum": [
+                            "month",
+                            "quarter"
+                        ],
+                        "type": "string",
+                        "default": "month",
+                        "description": "Tamaño de cada cohorte",
+                        "name": "interval",
+                        "in": "query"
                     },
                     {
-                        "description": "Información del Usuario",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
+                        "type": "string",
+                        "description": "Fecha de alta desde (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de alta hasta (YYYY-MM-DD), incluida",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)",
+                        "name": "percentiles",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "population",
+                            "sample"
+                        ],
+                        "type": "string",
+                        "default": "population",
+                        "description": "Desviación estándar poblacional o muestral",
+                        "name": "std",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)",
+                        "name": "buckets",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del apellido",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dominio del email",
+                        "name": "email_domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del teléfono, nacional (600) o internacional (+34600)",
+                        "name": "telephone_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad mínima",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad máxima",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento desde (YYYY-MM-DD)",
+                        "name": "birth_day_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento hasta (YYYY-MM-DD)",
+                        "name": "birth_day_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir clientes eliminados",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Usuario actualizado exitosamente",
+                        "description": "Cohortes de alta",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/handlers.ClientCohorts"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Elimina un usuario específico usando su ID",
+            }
+        },
+        "/api/v1/clients/duplicates": {
+            "get": {
+                "description": "Compara los clientes que comparten teléfono, inicio del email o inicio del apellido y puntúa cada par por similitud del nombre completo, teléfono normalizado y parte local del email. Se devuelven los pares con mayor puntuación primero",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Eliminar usuario",
+                "summary": "Posibles clientes duplicados",
                 "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Puntuación mínima entre 0 y 1 (por defecto 0.8)",
+                        "name": "min_score",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID del Usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Cantidad máxima de pares (por defecto 50, máximo 500)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Usuario eliminado exitosamente"
+                    "200": {
+                        "description": "Pares de clientes candidatos",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ClientDuplicate"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/api/v1/users/{id}/disable": {
-            "put": {
-                "description": "Cambia el estado de un usuario a deshabilitado",
+        "/api/v1/clients/export": {
+            "get": {
+                "description": "Exporta los clientes en CSV, NDJSON o XLSX. Acepta los mismos filtros y orden que el listado y lee la base por lotes, enviando el archivo a medida que se genera. Las columnas se escriben siempre en el mismo orden, sea cual sea el orden en que se pidan",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Deshabilitar usuario",
+                "summary": "Exportar clientes",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (por defecto), ndjson o xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Columnas separadas por coma (id, name, last_name, email, birth_day, age, telephone, deleted_at)",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idioma de las cabeceras: en, es, pt o it. Por defecto se usa Accept-Language",
+                        "name": "lang",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Separador de columnas CSV (por defecto ,)",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Campos de orden separados por coma, con prefijo - para descendente",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del apellido",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dominio del email",
+                        "name": "email_domain",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID del Usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Edad mínima",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad máxima",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento desde (YYYY-MM-DD)",
+                        "name": "birth_day_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento hasta (YYYY-MM-DD)",
+                        "name": "birth_day_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del teléfono, nacional (600) o internacional (+34600)",
+                        "name": "telephone_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir clientes eliminados",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Usuario deshabilitado",
+                        "description": "Archivo exportado",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/v1/users/{id}/enable": {
-            "put": {
-                "description": "Cambia el estado de un usuario a habilitado",
+        "/api/v1/clients/import": {
+            "post": {
+                "description": "Importa clientes desde CSV (text/csv) o NDJSON (application/x-ndjson), enviados como cuerpo o subidos en el campo file de un formulario multipart; el formato del archivo subido sale de su tipo o de su extensión (.csv, .ndjson o .jsonl). Cada fila se valida igual que un alta; si el email ya existe se actualiza el cliente. En modo all_or_nothing una fila rechazada descarta toda la importación; en modo partial se guardan las filas válidas. Con dry_run=true se informa el resultado sin guardar nada",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Habilitar usuario",
+                "summary": "Importar clientes",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID del Usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "all_or_nothing (por defecto) o partial",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validar sin guardar",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Mapeo de columnas CSV como campo:cabecera separados por coma, p. ej. name:Nombre,last_name:Apellido",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Separador de columnas CSV (por defecto ,)",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Usuario habilitado",
+                        "description": "Resultado por fila",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/handlers.ClientImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Archivo o parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Formato no soportado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Importación all_or_nothing descartada por filas rechazadas",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClientImportReport"
                         }
                     }
                 }
             }
         },
-        "/api/v1/users/{id}/reset_password": {
-            "put": {
-                "description": "Cambia la contraseña de un usuario a una nueva",
+        "/api/v1/clients/kpi": {
+            "get": {
+                "description": "Calcula la cantidad de clientes y la media, desviación estándar, mínimo, máximo, mediana, percentiles e histograma de sus edades, el total medio de sus pedidos y sus puntos de fidelidad vigentes; si ya venció alguno, primero se registran los vencimientos pendientes. Los percentiles interpolan linealmente entre las dos edades más cercanas. Acepta los mismos filtros que el listado. Con group_by la respuesta es {\"group_by\": ..., \"segments\": [...]} con las estadísticas de cada segmento",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Usuarios"
+                    "Clientes"
                 ],
-                "summary": "Restablecer contraseña",
+                "summary": "KPI de clientes",
                 "parameters": [
+                    {
+                        "enum": [
+                            "birth_decade",
+                            "birth_month",
+                            "email_domain",
+                            "signup_month"
+                        ],
+                        "type": "string",
+                        "description": "Segmentar por década o mes de nacimiento, dominio del email o mes de alta",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)",
+                        "name": "percentiles",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "population",
+                            "sample"
+                        ],
+                        "type": "string",
+                        "default": "population",
+                        "description": "Desviación estándar poblacional o muestral",
+                        "name": "std",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)",
+                        "name": "buckets",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del apellido",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dominio del email",
+                        "name": "email_domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del teléfono, nacional (600) o internacional (+34600)",
+                        "name": "telephone_prefix",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID del Usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Edad mínima",
+                        "name": "age_min",
+                        "in": "query"
                     },
                     {
-                        "description": "Nueva contraseña",
-                        "name": "new_password",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "integer",
+                        "description": "Edad máxima",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento desde (YYYY-MM-DD)",
+                        "name": "birth_day_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento hasta (YYYY-MM-DD)",
+                        "name": "birth_day_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir clientes eliminados",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Contraseña restablecida exitosamente"
+                        "description": "KPI de clientes calculado",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClientKPI"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/groups": {
+        "/api/v1/clients/kpi/history": {
             "get": {
-                "description": "Recupera todos los grupos de la base de datos",
-                "tags": [
-                    "Group"
+                "description": "Devuelve un punto por día, semana (de lunes a domingo) o mes entre from y to con la última foto de KPI de cada período. Los períodos sin foto repiten la anterior salvo con fill=none",
+                "produces": [
+                    "application/json"
                 ],
-                "summary": "Obtiene todos los grupos",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Group"
-                            }
-                        }
-                    }
-                }
-            },
-            "post": {
-                "description": "Crea un nuevo grupo en la base de datos",
                 "tags": [
-                    "Group"
+                    "Clientes"
                 ],
-                "summary": "Crea un grupo",
+                "summary": "Historial de KPI",
                 "parameters": [
                     {
-                        "description": "Datos del grupo",
-                        "name": "group",
+                        "type": "string",
+                        "description": "Fecha inicial (YYYY-MM-DD); por defecto 30 días, 12 semanas o 12 meses antes de to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha final (YYYY-MM-DD); por defecto hoy",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Tamaño de cada período",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "previous",
+                            "none"
+                        ],
+                        "type": "string",
+                        "default": "previous",
+                        "description": "Cómo completar los períodos sin foto",
+                        "name": "fill",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)",
+                        "name": "percentiles",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "population",
+                            "sample"
+                        ],
+                        "type": "string",
+                        "default": "population",
+                        "description": "Desviación estándar poblacional o muestral",
+                        "name": "std",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)",
+                        "name": "buckets",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Serie histórica",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClientKPIHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/kpi/snapshots": {
+            "post": {
+                "description": "Guarda en el momento una foto de los KPI de clientes, además de las que se guardan periódicamente",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Guardar foto de KPI",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)",
+                        "name": "percentiles",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "population",
+                            "sample"
+                        ],
+                        "type": "string",
+                        "default": "population",
+                        "description": "Desviación estándar poblacional o muestral",
+                        "name": "std",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)",
+                        "name": "buckets",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Foto guardada",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClientKPISnapshot"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/merge": {
+            "post": {
+                "description": "Fusiona los clientes merged_ids en survivor_id. Cada campo de fields indica el id del cliente del que se toma su valor; los demás campos se conservan del que sobrevive. Los registros relacionados pasan al cliente que sobrevive, los puntos de fidelidad vigentes se le traspasan, los fusionados se eliminan definitivamente y la fusión queda auditada",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Fusionar clientes",
+                "parameters": [
+                    {
+                        "description": "Clientes a fusionar y campos elegidos",
+                        "name": "merge",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Group"
+                            "$ref": "#/definitions/handlers.ClientMergeRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "Registro de la fusión",
                         "schema": {
-                            "$ref": "#/definitions/models.Group"
+                            "$ref": "#/definitions/models.ClientMerge"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición o cliente resultante inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "El cliente cambió durante la fusión",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/groups/{group_id}": {
+        "/api/v1/clients/search": {
+            "get": {
+                "description": "Busca clientes por nombre, apellido, email o teléfono. Cada término se compara por prefijo y los resultados se ordenan por relevancia",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Buscar clientes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Texto a buscar",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cantidad máxima de resultados (por defecto 50, máximo 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cantidad de resultados a saltar",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Clientes encontrados",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClientPage"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}": {
+            "get": {
+                "description": "Recupera un cliente específico usando su ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Obtener cliente por ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir clientes eliminados",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Detalles del cliente",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Actualiza la información de un cliente existente",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Actualizar cliente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Información actualizada del Cliente",
+                        "name": "client",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cliente actualizado exitosamente",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "El email ya lo usa otro cliente",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
             "delete": {
-                "description": "Elimina un grupo de la base de datos basado en el ID proporcionado",
+                "description": "Elimina lógicamente un cliente y sus mascotas y cancela sus turnos pendientes; puede restaurarse hasta que se purgue al vencer el período de retención",
                 "tags": [
-                    "Group"
+                    "Clientes"
                 ],
-                "summary": "Elimina un grupo",
+                "summary": "Eliminar cliente",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID del grupo",
-                        "name": "group_id",
+                        "description": "ID del Cliente",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "Group deleted"
+                        "description": "Cliente eliminado exitosamente"
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
+            },
+            "patch": {
+                "description": "Aplica un JSON Merge Patch (RFC 7396) o un JSON Patch (RFC 6902) sobre un cliente. El resultado se valida igual que un alta",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Modificar cliente parcialmente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Documento de patch",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cliente modificado exitosamente",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Falló una operación test o el email ya lo usa otro cliente",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Tipo de patch no soportado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "El patch no se puede aplicar",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/history": {
+            "get": {
+                "description": "Devuelve las versiones registradas de un cliente, con el usuario, la fecha y los cambios de cada una",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Historial de un cliente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Versiones del cliente",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ClientVersion"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/loyalty": {
+            "get": {
+                "description": "Devuelve los puntos vigentes del cliente, los totales acumulados, canjeados y vencidos, y cuándo vence cada parte del saldo. Antes de calcularlo registra los vencimientos pendientes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Saldo de puntos",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Saldo de puntos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoyaltyBalance"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/loyalty/redemptions": {
+            "post": {
+                "description": "Descuenta puntos del saldo del cliente, empezando por los que vencen antes. order_id es opcional y vincula el canje a un pedido del cliente. Si el saldo vigente no alcanza no se canjea nada",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Canjear puntos",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Puntos a canjear",
+                        "name": "redemption",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoyaltyRedemptionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Canje registrado",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoyaltyEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El saldo no alcanza",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/loyalty/statement": {
+            "get": {
+                "description": "Devuelve los movimientos de puntos del cliente en orden cronológico, cada uno con el saldo que dejó, y el saldo al comienzo y al final del período. from y to filtran por el día del movimiento en UTC; sin ellos el extracto abarca todo el historial. Los vencimientos figuran en la fecha en que vencieron los puntos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Extracto de puntos",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Movimientos desde este día (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Movimientos hasta este día inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Extracto de puntos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoyaltyStatement"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/notifications": {
+            "get": {
+                "description": "Devuelve los avisos emitidos para el cliente, como los recordatorios de vacunas y tratamientos, del más reciente al más antiguo",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Notificaciones de un cliente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Notificaciones del cliente",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Notification"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/orders": {
+            "get": {
+                "description": "Devuelve los pedidos del cliente con sus líneas, del más reciente al más antiguo. from y to filtran por el día del pedido en UTC",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Pedidos de un cliente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pedidos desde este día (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pedidos hasta este día inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Pedidos del cliente",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registra un pedido con sus líneas. Los importes van en céntimos; el subtotal y el total se calculan con las líneas y discount_cents. store_id es obligatorio en los pedidos en tienda. placed_at es por defecto el momento del registro. El pedido suma los puntos de las reglas de fidelidad que cumple, que vencen LOYALTY_POINTS_EXPIRY_MONTHS meses después",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Registrar pedido",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Información del Pedido",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Pedido registrado",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/pets": {
+            "get": {
+                "description": "Devuelve las mascotas de un cliente con su historial de pesos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Mascotas de un cliente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir mascotas eliminadas",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mascotas del cliente",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Pet"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registra una mascota del cliente. El microchip tiene 15 dígitos y el último es un dígito de control Luhn; se aceptan espacios y guiones. weights es opcional y carga el historial de pesos inicial",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Crear mascota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Información de la Mascota",
+                        "name": "pet",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Pet"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Mascota creada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Pet"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El microchip ya está registrado en otra mascota",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/pets/{pet_id}": {
+            "get": {
+                "description": "Recupera una mascota de un cliente con su historial de pesos",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Obtener mascota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir mascotas eliminadas",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Detalles de la mascota",
+                        "schema": {
+                            "$ref": "#/definitions/models.Pet"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente o mascota no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Actualiza los datos de una mascota. El historial de pesos no se modifica aquí sino con /weights",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Actualizar mascota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Información actualizada de la Mascota",
+                        "name": "pet",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Pet"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mascota actualizada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Pet"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente o mascota no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "El microchip ya está registrado en otra mascota",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Elimina lógicamente una mascota; se purga junto con los clientes eliminados al vencer el período de retención",
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Eliminar mascota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Mascota eliminada"
+                    },
+                    "404": {
+                        "description": "Cliente o mascota no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/pets/{pet_id}/treatments": {
+            "get": {
+                "description": "Devuelve las vacunas, desparasitaciones y tratamientos de la mascota, del más antiguo al más reciente",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Historial sanitario de una mascota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Historial sanitario",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PetTreatment"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente o mascota no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registra una vacuna, desparasitación o tratamiento aplicado el día administered_on, con el producto y el lote. next_due_on es cuándo toca repetirlo; el nuevo registro da por cumplidos los anteriores del mismo tipo y producto",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Registrar tratamiento",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Registro sanitario",
+                        "name": "treatment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PetTreatment"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Tratamiento registrado",
+                        "schema": {
+                            "$ref": "#/definitions/models.PetTreatment"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente o mascota no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/pets/{pet_id}/treatments/{treatment_id}": {
+            "put": {
+                "description": "Corrige un registro sanitario; los campos que no se envían no cambian",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Actualizar tratamiento",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del Registro",
+                        "name": "treatment_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Registro sanitario",
+                        "name": "treatment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PetTreatment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tratamiento actualizado",
+                        "schema": {
+                            "$ref": "#/definitions/models.PetTreatment"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente, mascota o registro no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Elimina definitivamente un registro cargado por error; si había dado por cumplido uno anterior, ese vuelve a estar pendiente",
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Eliminar tratamiento",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del Registro",
+                        "name": "treatment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Registro eliminado"
+                    },
+                    "404": {
+                        "description": "Cliente, mascota o registro no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/pets/{pet_id}/weights": {
+            "post": {
+                "description": "Agrega un peso en kilos, medido el día measured_on, al historial de la mascota",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Registrar peso",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Peso de la Mascota",
+                        "name": "weight",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PetWeight"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Peso registrado",
+                        "schema": {
+                            "$ref": "#/definitions/models.PetWeight"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente o mascota no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/pets/{pet_id}/weights/{weight_id}": {
+            "delete": {
+                "description": "Elimina definitivamente un peso cargado por error",
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Eliminar peso",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID de la Mascota",
+                        "name": "pet_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del Peso",
+                        "name": "weight_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Peso eliminado"
+                    },
+                    "404": {
+                        "description": "Cliente, mascota o peso no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/restore": {
+            "post": {
+                "description": "Deshace la eliminación lógica de un cliente que todavía no fue purgado, junto con la de las mascotas que se eliminaron con él. Los turnos cancelados al eliminarlo siguen cancelados",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Restaurar cliente",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cliente restaurado",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Hay una petición en curso con la misma Idempotency-Key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key usada con otra petición",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/clients/{id}/revert/{version}": {
+            "post": {
+                "description": "Vuelve los datos del cliente a los registrados en la versión indicada y registra una nueva versión. El estado de eliminación no se modifica",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Revertir cliente a una versión",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Cliente",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Número de versión",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cliente revertido",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "La versión ya no es válida",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Cliente o versión no encontrados",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Hay una petición en curso con la misma Idempotency-Key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key usada con otra petición",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/loyalty/rules": {
+            "get": {
+                "description": "Devuelve las reglas con las que los pedidos suman puntos de fidelidad",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Listar reglas de fidelidad",
+                "responses": {
+                    "200": {
+                        "description": "Reglas de fidelidad",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LoyaltyRule"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Crea una regla de acumulación. Un pedido suma, por cada regla que cumple, points_per_euro puntos por euro del total, redondeando hacia abajo, más bonus_points. channel, store_id, min_total_cents, starts_on y ends_on son condiciones opcionales",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Crear regla de fidelidad",
+                "parameters": [
+                    {
+                        "description": "Información de la Regla",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoyaltyRule"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Regla creada",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoyaltyRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Ya existe una regla con ese nombre",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/loyalty/rules/{id}": {
+            "put": {
+                "description": "Actualiza una regla de acumulación; para retirarla se le pone ends_on. Los cambios solo se aplican a los pedidos que se registren después",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Fidelidad"
+                ],
+                "summary": "Actualizar regla de fidelidad",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID de la Regla",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Información actualizada de la Regla",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoyaltyRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Regla actualizada",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoyaltyRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Regla no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Ya existe una regla con ese nombre",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/pets/kpi": {
+            "get": {
+                "description": "Calcula la distribución de las mascotas por especie y raza, la edad media y su desviación estándar por especie, y la cantidad de mascotas por cliente (media, mediana, máximo y proporción de clientes sin mascotas, con una o con varias). Acepta los mismos filtros que el listado de clientes, que se aplican a los dueños",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "KPI de mascotas",
+                "parameters": [
+                    {
+                        "enum": [
+                            "population",
+                            "sample"
+                        ],
+                        "type": "string",
+                        "default": "population",
+                        "description": "Desviación estándar poblacional o muestral",
+                        "name": "std",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del apellido del dueño",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dominio del email del dueño",
+                        "name": "email_domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefijo del teléfono del dueño, nacional (600) o internacional (+34600)",
+                        "name": "telephone_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad mínima del dueño",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Edad máxima del dueño",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento del dueño desde (YYYY-MM-DD)",
+                        "name": "birth_day_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fecha de nacimiento del dueño hasta (YYYY-MM-DD)",
+                        "name": "birth_day_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Incluir clientes y mascotas eliminados",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "KPI de mascotas calculado",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PetKPI"
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/service_types": {
+            "get": {
+                "description": "Devuelve los servicios que se pueden reservar, como baños o consultas veterinarias, con su duración",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Turnos"
+                ],
+                "summary": "Listar servicios",
+                "responses": {
+                    "200": {
+                        "description": "Servicios",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ServiceType"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Crea un servicio con su duración en minutos, que determina cuánto ocupa cada turno",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Turnos"
+                ],
+                "summary": "Crear servicio",
+                "parameters": [
+                    {
+                        "description": "Información del Servicio",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ServiceType"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Servicio creado",
+                        "schema": {
+                            "$ref": "#/definitions/models.ServiceType"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Ya existe un servicio con ese nombre",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stores": {
+            "get": {
+                "description": "Devuelve las tiendas con su zona horaria y su horario de apertura",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Turnos"
+                ],
+                "summary": "Listar tiendas",
+                "responses": {
+                    "200": {
+                        "description": "Tiendas",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Store"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Crea una tienda. opening_hours son los tramos en que abre cada día (monday a sunday) en la hora local de time_zone; un día puede tener varios tramos y los días sin tramos está cerrada",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Turnos"
+                ],
+                "summary": "Crear tienda",
+                "parameters": [
+                    {
+                        "description": "Información de la Tienda",
+                        "name": "store",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Store"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Tienda creada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Store"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Ya existe una tienda con ese nombre",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stores/{id}": {
+            "put": {
+                "description": "Actualiza el nombre, la zona horaria o el horario de una tienda. El nuevo horario solo se aplica a los turnos que se reserven o reprogramen después",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Turnos"
+                ],
+                "summary": "Actualizar tienda",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID de la Tienda",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Información actualizada de la Tienda",
+                        "name": "store",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Store"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tienda actualizada",
+                        "schema": {
+                            "$ref": "#/definitions/models.Store"
+                        }
+                    },
+                    "400": {
+                        "description": "Datos inválidos",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ValidationErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Tienda no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Ya existe una tienda con ese nombre",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/treatments/overdue": {
+            "get": {
+                "description": "Devuelve los registros sanitarios pendientes cuya fecha de repetición ya pasó, de las mascotas y clientes activos, del más atrasado al más reciente",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Tratamientos vencidos",
+                "parameters": [
+                    {
+                        "enum": [
+                            "vaccination",
+                            "deworming",
+                            "treatment"
+                        ],
+                        "type": "string",
+                        "description": "Tipo de registro",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "Zona horaria IANA con la que se determina el día de hoy",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tratamientos vencidos",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.DueTreatment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/treatments/upcoming": {
+            "get": {
+                "description": "Devuelve los registros sanitarios pendientes que vencen desde hoy hasta antes de que pase within, de las mascotas y clientes activos, ordenados por fecha",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Mascotas"
+                ],
+                "summary": "Próximos tratamientos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "30d",
+                        "description": "Cantidad de días, incluido hoy (1d a 366d)",
+                        "name": "within",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "vaccination",
+                            "deworming",
+                            "treatment"
+                        ],
+                        "type": "string",
+                        "description": "Tipo de registro",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "Zona horaria IANA con la que se determina el día de hoy",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Próximos tratamientos",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.DueTreatment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Parámetros inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users": {
+            "get": {
+                "description": "Recupera una lista de todos los usuarios registrados",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Obtener todos los usuarios",
+                "responses": {
+                    "200": {
+                        "description": "Lista de usuarios",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Crea un nuevo usuario con los datos proporcionados",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Crear usuario",
+                "parameters": [
+                    {
+                        "description": "Información del Usuario",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Usuario creado exitosamente",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "409": {
+                        "description": "Hay una petición en curso con la misma Idempotency-Key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key usada con otra petición",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}": {
+            "get": {
+                "description": "Recupera un usuario específico usando su ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Obtener usuario por ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Detalles del usuario",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Actualiza los datos de un usuario específico",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Actualizar usuario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Información del Usuario",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usuario actualizado exitosamente",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Elimina un usuario específico usando su ID",
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Eliminar usuario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Usuario eliminado exitosamente"
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/calendar_token": {
+            "put": {
+                "description": "Genera un token nuevo para suscribirse sin usar la contraseña al calendario de cumpleaños de clientes y al de los turnos del usuario. El token solo se muestra en esta respuesta y reemplaza al anterior, que deja de funcionar",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Generar token de calendario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token generado",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CalendarTokenResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Usuario no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Anula el token de calendario del usuario; las suscripciones que lo usan dejan de funcionar",
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Anular token de calendario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Token anulado"
+                    },
+                    "404": {
+                        "description": "Usuario no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/disable": {
+            "put": {
+                "description": "Cambia el estado de un usuario a deshabilitado",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Deshabilitar usuario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usuario deshabilitado",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/enable": {
+            "put": {
+                "description": "Cambia el estado de un usuario a habilitado",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Habilitar usuario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usuario habilitado",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versión del recurso"
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}/reset_password": {
+            "put": {
+                "description": "Cambia la contraseña de un usuario a una nueva",
+                "tags": [
+                    "Usuarios"
+                ],
+                "summary": "Restablecer contraseña",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del Usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la versión que se modifica",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Nueva contraseña",
+                        "name": "new_password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Contraseña restablecida exitosamente"
+                    },
+                    "412": {
+                        "description": "La versión no coincide con la actual",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "428": {
+                        "description": "Falta la cabecera If-Match (modo estricto)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/{token}/appointments.ics": {
+            "get": {
+                "description": "Calendario (.ics) con los turnos del usuario dueño del token, desde 30 días atrás, para suscribirse desde una aplicación de calendario. Los turnos cancelados se publican como cancelados para que desaparezcan de la agenda y los de clientes eliminados no se publican. El token se obtiene con PUT /api/v1/users/{id}/calendar_token y reemplaza a la contraseña",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "Turnos"
+                ],
+                "summary": "Calendario de turnos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token de calendario del usuario",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Calendario iCalendar",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Token inválido o usuario deshabilitado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/{token}/birthdays.ics": {
+            "get": {
+                "description": "Calendario (.ics) con los cumpleaños de los clientes del próximo año, como eventos de día completo, para suscribirse desde una aplicación de calendario. El token se obtiene con PUT /api/v1/users/{id}/calendar_token y reemplaza a la contraseña",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "Clientes"
+                ],
+                "summary": "Calendario de cumpleaños",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token de calendario del usuario",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "UTC",
+                        "description": "Zona horaria IANA con la que se determina el día de hoy",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Calendario iCalendar",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Zona horaria inválida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Token inválido o usuario deshabilitado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups": {
+            "get": {
+                "description": "Recupera todos los grupos de la base de datos",
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Obtiene todos los grupos",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Group"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Crea un nuevo grupo en la base de datos",
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Crea un grupo",
+                "parameters": [
+                    {
+                        "description": "Datos del grupo",
+                        "name": "group",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Group"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición sin repetir sus efectos",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Group"
+                        }
+                    },
+                    "409": {
+                        "description": "Hay una petición en curso con la misma Idempotency-Key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key usada con otra petición",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{group_id}": {
+            "delete": {
+                "description": "Elimina un grupo de la base de datos basado en el ID proporcionado",
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Elimina un grupo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del grupo",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Group deleted"
+                    }
+                }
+            }
+        },
+        "/groups/{id}": {
+            "get": {
+                "description": "Recupera un grupo específico basado en el ID proporcionado",
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Obtiene un grupo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del grupo",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Group"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Autentica un usuario y crea una sesión",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Autenticación"
+                ],
+                "summary": "Autenticar usuario",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Nombre de usuario",
+                        "name": "username",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Contraseña",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Inicio de sesión exitoso",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/groups/{group_id}": {
+            "put": {
+                "description": "Asigna un grupo existente a un usuario basado en el ID del usuario y del grupo",
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Asigna un grupo a un usuario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del grupo",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Group assigned"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Elimina la relación de un grupo asignado a un usuario basado en sus IDs",
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Elimina la asignación de un grupo a un usuario",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID del usuario",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID del grupo",
+                        "name": "group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Group unassigned"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AppointmentChangeRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "staff_id": {
+                    "type": "integer"
+                },
+                "starts_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CalendarTokenResponse": {
+            "type": "object",
+            "properties": {
+                "appointments_url": {
+                    "type": "string"
+                },
+                "birthdays_url": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientBirthday": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "birth_day": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "days_until": {
+                    "type": "integer"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_birthday": {
+                    "type": "string"
+                },
+                "telephone": {
+                    "type": "string"
+                },
+                "telephone_display": {
+                    "type": "string"
+                },
+                "telephone_type": {
+                    "type": "string"
+                },
+                "turns": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ClientCohort": {
+            "type": "object",
+            "properties": {
+                "age_standard_deviation": {
+                    "type": "number"
+                },
+                "average_age": {
+                    "type": "number"
+                },
+                "average_spend": {
+                    "type": "number"
+                },
+                "cohort": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "cumulative": {
+                    "type": "integer"
+                },
+                "histogram": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.HistogramBucket"
+                    }
+                },
+                "max_age": {
+                    "type": "number"
+                },
+                "median_age": {
+                    "type": "number"
+                },
+                "min_age": {
+                    "type": "number"
+                },
+                "percentiles": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "points_outstanding": {
+                    "type": "integer"
+                },
+                "standard_deviation": {
+                    "type": "string",
+                    "enum": [
+                        "population",
+                        "sample"
+                    ]
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientCohorts": {
+            "type": "object",
+            "properties": {
+                "cohorts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ClientCohort"
+                    }
+                },
+                "interval": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientDuplicate": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Client"
+                    }
+                },
+                "score": {
+                    "$ref": "#/definitions/models.DuplicateScore"
+                }
+            }
+        },
+        "handlers.ClientImportReport": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "type": "boolean"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ClientImportRow"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/handlers.ClientImportSummary"
+                }
+            }
+        },
+        "handlers.ClientImportRow": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientImportSummary": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ClientKPI": {
+            "type": "object",
+            "properties": {
+                "age_standard_deviation": {
+                    "type": "number"
+                },
+                "average_age": {
+                    "type": "number"
+                },
+                "average_spend": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "histogram": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.HistogramBucket"
+                    }
+                },
+                "max_age": {
+                    "type": "number"
+                },
+                "median_age": {
+                    "type": "number"
+                },
+                "min_age": {
+                    "type": "number"
+                },
+                "percentiles": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "points_outstanding": {
+                    "type": "integer"
+                },
+                "standard_deviation": {
+                    "type": "string",
+                    "enum": [
+                        "population",
+                        "sample"
+                    ]
+                }
+            }
+        },
+        "handlers.ClientKPIHistory": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ClientKPIPoint"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientKPIPoint": {
+            "type": "object",
+            "properties": {
+                "deleted_count": {
+                    "type": "integer"
+                },
+                "filled": {
+                    "type": "boolean"
+                },
+                "kpi": {
+                    "$ref": "#/definitions/handlers.ClientKPI"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "taken_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientKPISnapshot": {
+            "type": "object",
+            "properties": {
+                "deleted_count": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kpi": {
+                    "$ref": "#/definitions/handlers.ClientKPI"
+                },
+                "taken_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ClientMergeRequest": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "merged_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "survivor_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ClientPage": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Client"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "prev_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DueTreatment": {
+            "type": "object",
+            "properties": {
+                "administered_on": {
+                    "type": "string"
+                },
+                "batch": {
+                    "type": "string"
+                },
+                "client_email": {
+                    "type": "string"
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "client_last_name": {
+                    "type": "string"
+                },
+                "client_name": {
+                    "type": "string"
+                },
+                "client_telephone": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "days_until": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "vaccination",
+                        "deworming",
+                        "treatment"
+                    ]
+                },
+                "next_due_on": {
+                    "type": "string"
+                },
+                "pet_id": {
+                    "type": "integer"
+                },
+                "pet_name": {
+                    "type": "string"
+                },
+                "pet_species": {
+                    "type": "string"
+                },
+                "product": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LoyaltyBalance": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                },
+                "earned": {
+                    "type": "integer"
+                },
+                "expired": {
+                    "type": "integer"
+                },
+                "lots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LoyaltyLot"
+                    }
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "redeemed": {
+                    "type": "integer"
+                },
+                "transferred": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.LoyaltyRedemptionRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Descuento de 5 € en caja"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer",
+                    "example": 500
+                }
+            }
+        },
+        "handlers.LoyaltyStatement": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                },
+                "closing_balance": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LoyaltyStatementEntry"
+                    }
+                },
+                "opening_balance": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.LoyaltyStatementEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "balance": {
+                    "type": "integer"
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "accrual",
+                        "redemption",
+                        "expiry",
+                        "transfer"
+                    ]
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "source_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.PetBreedKPI": {
+            "type": "object",
+            "properties": {
+                "breed": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "share": {
+                    "type": "number"
+                },
+                "species": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PetKPI": {
+            "type": "object",
+            "properties": {
+                "breeds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PetBreedKPI"
+                    }
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "pets_per_client": {
+                    "$ref": "#/definitions/handlers.PetsPerClientKPI"
+                },
+                "species": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PetSpeciesKPI"
+                    }
+                },
+                "standard_deviation": {
+                    "type": "string",
+                    "enum": [
+                        "population",
+                        "sample"
+                    ]
+                }
+            }
+        },
+        "handlers.PetOwnersShare": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "share": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.PetSpeciesKPI": {
+            "type": "object",
+            "properties": {
+                "age_standard_deviation": {
+                    "type": "number"
+                },
+                "aged_count": {
+                    "type": "integer"
+                },
+                "average_age": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "share": {
+                    "type": "number"
+                },
+                "species": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PetsPerClientKPI": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "mean": {
+                    "type": "number"
+                },
+                "median": {
+                    "type": "number"
+                },
+                "none": {
+                    "$ref": "#/definitions/handlers.PetOwnersShare"
+                },
+                "one": {
+                    "$ref": "#/definitions/handlers.PetOwnersShare"
+                },
+                "several": {
+                    "$ref": "#/definitions/handlers.PetOwnersShare"
+                }
+            }
+        },
+        "handlers.ValidationErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                }
+            }
+        },
+        "models.Appointment": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AppointmentChange"
+                    }
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "pet_id": {
+                    "type": "integer"
+                },
+                "service_type_id": {
+                    "type": "integer"
+                },
+                "staff_id": {
+                    "type": "integer"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "scheduled",
+                        "cancelled"
+                    ]
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AppointmentChange": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "reschedule",
+                        "cancel"
+                    ]
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "appointment_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "previous_staff_id": {
+                    "type": "integer"
+                },
+                "previous_starts_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Client": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "birth_day": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "telephone": {
+                    "type": "string"
+                },
+                "telephone_display": {
+                    "type": "string"
+                },
+                "telephone_type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ClientMerge": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "choices": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "merged": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Client"
+                    }
+                },
+                "merged_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "result": {
+                    "$ref": "#/definitions/models.Client"
+                },
+                "survivor_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ClientVersion": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.FieldChange"
+                    }
+                },
+                "client": {
+                    "$ref": "#/definitions/models.Client"
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DuplicateScore": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "number"
+                },
+                "phone": {
+                    "type": "number"
+                },
+                "score": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.FieldChange": {
+            "type": "object",
+            "properties": {
+                "from": {},
+                "to": {}
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Group": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.HistogramBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "number"
+                },
+                "to": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.LoyaltyEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "accrual",
+                        "redemption",
+                        "expiry",
+                        "transfer"
+                    ]
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "source_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LoyaltyLot": {
+            "type": "object",
+            "properties": {
+                "earned_at": {
+                    "type": "string"
+                },
+                "entry_id": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LoyaltyRule": {
+            "type": "object",
+            "properties": {
+                "bonus_points": {
+                    "type": "integer"
+                },
+                "channel": {
+                    "type": "string",
+                    "enum": [
+                        "in_store",
+                        "online",
+                        "phone"
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ends_on": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "min_total_cents": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "points_per_euro": {
+                    "type": "number"
+                },
+                "starts_on": {
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Notification": {
+            "type": "object",
+            "properties": {
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "due_on": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "treatment_due"
+                    ]
+                },
+                "message": {
+                    "type": "string"
+                },
+                "pet_id": {
+                    "type": "integer"
+                },
+                "treatment_id": {
+                    "type": "integer"
+                }
             }
         },
-        "/groups/{id}": {
-            "get": {
-                "description": "Recupera un grupo específico basado en el ID proporcionado",
-                "tags": [
-                    "Group"
-                ],
-                "summary": "Obtiene un grupo",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID del grupo",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.Group"
-                        }
-                    }
+        "models.OpeningHours": {
+            "type": "object",
+            "properties": {
+                "closes": {
+                    "type": "string",
+                    "example": "14:00"
+                },
+                "day": {
+                    "type": "string",
+                    "enum": [
+                        "monday",
+                        "tuesday",
+                        "wednesday",
+                        "thursday",
+                        "friday",
+                        "saturday",
+                        "sunday"
+                    ]
+                },
+                "opens": {
+                    "type": "string",
+                    "example": "09:00"
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "Autentica un usuario y crea una sesión",
-                "consumes": [
-                    "application/x-www-form-urlencoded"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Autenticación"
-                ],
-                "summary": "Autenticar usuario",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Nombre de usuario",
-                        "name": "username",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Contraseña",
-                        "name": "password",
-                        "in": "formData",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Inicio de sesión exitoso",
-                        "schema": {
-                            "type": "string"
-                        }
+        "models.Order": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string",
+                    "enum": [
+                        "in_store",
+                        "online",
+                        "phone"
+                    ]
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "discount_cents": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderLine"
                     }
+                },
+                "placed_at": {
+                    "type": "string"
+                },
+                "points_earned": {
+                    "type": "integer"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "subtotal_cents": {
+                    "type": "integer"
+                },
+                "total_cents": {
+                    "type": "integer"
                 }
             }
         },
-        "/users/{id}/groups/{group_id}": {
-            "put": {
-                "description": "Asigna un grupo existente a un usuario basado en el ID del usuario y del grupo",
-                "tags": [
-                    "Group"
-                ],
-                "summary": "Asigna un grupo a un usuario",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID del usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "ID del grupo",
-                        "name": "group_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Group assigned"
-                    }
+        "models.OrderLine": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "product": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "total_cents": {
+                    "type": "integer"
+                },
+                "unit_price_cents": {
+                    "type": "integer"
                 }
-            },
-            "delete": {
-                "description": "Elimina la relación de un grupo asignado a un usuario basado en sus IDs",
-                "tags": [
-                    "Group"
-                ],
-                "summary": "Elimina la asignación de un grupo a un usuario",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID del usuario",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "ID del grupo",
-                        "name": "group_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Group unassigned"
+            }
+        },
+        "models.Pet": {
+            "type": "object",
+            "properties": {
+                "birth_day": {
+                    "type": "string"
+                },
+                "breed": {
+                    "type": "string"
+                },
+                "client_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "microchip": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "neutered": {
+                    "type": "boolean"
+                },
+                "sex": {
+                    "type": "string",
+                    "enum": [
+                        "male",
+                        "female",
+                        "unknown"
+                    ]
+                },
+                "species": {
+                    "type": "string",
+                    "enum": [
+                        "dog",
+                        "cat",
+                        "bird",
+                        "rabbit",
+                        "rodent",
+                        "reptile",
+                        "fish",
+                        "other"
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "weights": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PetWeight"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.ClientKPI": {
+        },
+        "models.PetTreatment": {
             "type": "object",
             "properties": {
-                "age_standard_deviation": {
-                    "type": "number"
+                "administered_on": {
+                    "type": "string"
                 },
-                "average_age": {
-                    "type": "number"
+                "batch": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "vaccination",
+                        "deworming",
+                        "treatment"
+                    ]
+                },
+                "next_due_on": {
+                    "type": "string"
+                },
+                "pet_id": {
+                    "type": "integer"
+                },
+                "product": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "models.Client": {
+        "models.PetWeight": {
             "type": "object",
             "properties": {
-                "email": {
+                "created_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "last_login": {
+                "kilograms": {
+                    "type": "number"
+                },
+                "measured_on": {
                     "type": "string"
                 },
-                "last_name": {
+                "pet_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ServiceType": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
+                "duration_minutes": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "models.Group": {
+        "models.Store": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
-                    "type": "string"
-                },
                 "id": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
                 },
+                "opening_hours": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpeningHours"
+                    }
+                },
+                "time_zone": {
+                    "type": "string",
+                    "example": "Europe/Madrid"
+                },
                 "updated_at": {
                     "type": "string"
                 }
@@ -693,6 +5377,9 @@ const docTemplate = `{
                 },
                 "username": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         }
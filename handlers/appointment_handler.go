@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Ventanas de turnos: la del listado por defecto y como máximo, y cuánto hacia atrás muestra el calendario
+const (
+	defaultAppointmentDays   = 30
+	maxAppointmentDays       = 366
+	appointmentCalendarsPast = 30
+)
+
+// Errores de reserva de turnos, que se responden con un 409
+var (
+	errStaffDoubleBooked = errors.New("Staff member already has an appointment at that time")
+	errPetDoubleBooked   = errors.New("Pet already has an appointment at that time")
+	errAppointmentClosed = errors.New("Appointment is cancelled")
+)
+
+// AppointmentChangeRequest es el cuerpo con el que se reprograma o cancela un turno. Al cancelar solo se
+// usa reason
+type AppointmentChangeRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+	StaffID  *int      `json:"staff_id"`
+	Reason   string    `json:"reason"`
+}
+
+// GetAppointments lista los turnos
+// @Summary Listar turnos
+// @Description Devuelve los turnos que empiezan desde el día from hasta el día to inclusive, en UTC, ordenados por hora. Por defecto lista los próximos 30 días, con un máximo de 366
+// @Tags Turnos
+// @Produce json
+// @Param from query string false "Día desde (YYYY-MM-DD), por defecto hoy"
+// @Param to query string false "Día hasta (YYYY-MM-DD), por defecto 29 días después de from"
+// @Param store_id query int false "ID de la Tienda"
+// @Param staff_id query int false "ID del miembro del personal"
+// @Param client_id query int false "ID del Cliente"
+// @Param pet_id query int false "ID de la Mascota"
+// @Param status query string false "Estado del turno" Enums(scheduled, cancelled)
+// @Success 200 {array} models.Appointment "Turnos"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/appointments [get]
+func GetAppointments(c echo.Context) error {
+	from, err := parseOptionalDate(c, "from")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	to, err := parseOptionalDate(c, "to")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if from == nil {
+		today := models.Now().UTC().Truncate(24 * time.Hour)
+		from = &today
+	}
+	if to == nil {
+		last := from.AddDate(0, 0, defaultAppointmentDays-1)
+		to = &last
+	}
+	if from.After(*to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+	}
+	if to.Sub(*from) >= maxAppointmentDays*24*time.Hour {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from and to must be at most 366 days apart"})
+	}
+
+	db := withAppointmentChanges(config.DB.Model(&models.Appointment{})).
+		Where("starts_at >= ? AND starts_at < ?", *from, to.AddDate(0, 0, 1))
+	for _, column := range []string{"store_id", "staff_id", "client_id", "pet_id"} {
+		id, err := parseOptionalInt(c, column)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if id != nil {
+			db = db.Where(column+" = ?", *id)
+		}
+	}
+	switch status := c.QueryParam("status"); status {
+	case "":
+	case models.AppointmentScheduled, models.AppointmentCancelled:
+		db = db.Where("status = ?", status)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "status must be scheduled or cancelled"})
+	}
+
+	appointments := []models.Appointment{}
+	if err := db.Order("starts_at, id").Find(&appointments).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve appointments"})
+	}
+	return c.JSON(http.StatusOK, appointments)
+}
+
+// GetAppointment obtiene un turno
+// @Summary Obtener turno
+// @Description Recupera un turno con su historial de reprogramaciones y cancelaciones
+// @Tags Turnos
+// @Param id path int true "ID del Turno"
+// @Produce json
+// @Success 200 {object} models.Appointment "Detalles del turno"
+// @Failure 404 {object} map[string]string "Turno no encontrado"
+// @Router /api/v1/appointments/{id} [get]
+func GetAppointment(c echo.Context) error {
+	var appointment models.Appointment
+	if err := withAppointmentChanges(config.DB).First(&appointment, c.Param("id")).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Appointment not found"})
+	}
+	return c.JSON(http.StatusOK, appointment)
+}
+
+// CreateAppointment reserva un turno
+// @Summary Reservar turno
+// @Description Reserva un turno de un cliente, y opcionalmente de una de sus mascotas, para un servicio en una tienda con un miembro del personal. ends_at se calcula con la duración del servicio. La tienda debe estar abierta durante todo el turno y ni el miembro del personal ni la mascota pueden tener otro turno que se superponga
+// @Tags Turnos
+// @Accept json
+// @Produce json
+// @Param appointment body models.Appointment true "Información del Turno"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.Appointment "Turno reservado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos o tienda cerrada"
+// @Failure 409 {object} map[string]string "El miembro del personal o la mascota ya tienen un turno a esa hora"
+// @Router /api/v1/appointments [post]
+func CreateAppointment(c echo.Context) error {
+	var appointment models.Appointment
+	if err := c.Bind(&appointment); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	appointment.ID, appointment.Status, appointment.Changes = 0, models.AppointmentScheduled, nil
+
+	if errs := checkAppointment(&appointment); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&appointment).Error; err != nil {
+			return err
+		}
+		return checkDoubleBooking(tx, &appointment)
+	})
+	if err != nil {
+		return appointmentWriteFailed(c, err)
+	}
+	appointment.Changes = []models.AppointmentChange{}
+	return c.JSON(http.StatusCreated, appointment)
+}
+
+// RescheduleAppointment cambia el horario de un turno
+// @Summary Reprogramar turno
+// @Description Mueve un turno vigente a starts_at y, si se envía staff_id, a otro miembro del personal. Se comprueban el horario de la tienda y las superposiciones como al reservar, y el cambio queda en el historial del turno con su motivo
+// @Tags Turnos
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Turno"
+// @Param change body AppointmentChangeRequest true "Nuevo horario y motivo"
+// @Success 200 {object} models.Appointment "Turno reprogramado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos o tienda cerrada"
+// @Failure 404 {object} map[string]string "Turno no encontrado"
+// @Failure 409 {object} map[string]string "Turno cancelado, o el miembro del personal o la mascota ya tienen un turno a esa hora"
+// @Router /api/v1/appointments/{id}/reschedule [post]
+func RescheduleAppointment(c echo.Context) error {
+	var appointment models.Appointment
+	if err := config.DB.First(&appointment, c.Param("id")).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Appointment not found"})
+	}
+	var req AppointmentChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	if appointment.Status == models.AppointmentCancelled {
+		return c.JSON(http.StatusConflict, map[string]string{"error": errAppointmentClosed.Error()})
+	}
+
+	previousStart, previousStaff := appointment.StartsAt, appointment.StaffID
+	change := models.AppointmentChange{
+		AppointmentID:    appointment.ID,
+		Action:           models.ActionReschedule,
+		Reason:           strings.TrimSpace(req.Reason),
+		PreviousStartsAt: &previousStart,
+		PreviousStaffID:  &previousStaff,
+		Actor:            currentActor(c),
+	}
+	appointment.StartsAt = req.StartsAt
+	if req.StaffID != nil {
+		appointment.StaffID = *req.StaffID
+	}
+
+	errs := checkAppointment(&appointment)
+	if change.Reason == "" {
+		errs = append(errs, models.FieldError{Field: "reason", Code: models.CodeRequired, Message: "Reason is required"})
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&appointment).Where("status = ?", models.AppointmentScheduled).
+			Updates(map[string]interface{}{"starts_at": appointment.StartsAt, "ends_at": appointment.EndsAt, "staff_id": appointment.StaffID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errAppointmentClosed
+		}
+		if err := checkDoubleBooking(tx, &appointment); err != nil {
+			return err
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return appointmentWriteFailed(c, err)
+	}
+	return GetAppointment(c)
+}
+
+// CancelAppointment cancela un turno
+// @Summary Cancelar turno
+// @Description Cancela un turno vigente y libera su horario. El turno se conserva, y la cancelación queda en su historial con su motivo
+// @Tags Turnos
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Turno"
+// @Param change body AppointmentChangeRequest true "Motivo de la cancelación"
+// @Success 200 {object} models.Appointment "Turno cancelado"
+// @Failure 400 {object} ValidationErrorResponse "Falta el motivo"
+// @Failure 404 {object} map[string]string "Turno no encontrado"
+// @Failure 409 {object} map[string]string "El turno ya estaba cancelado"
+// @Router /api/v1/appointments/{id}/cancel [post]
+func CancelAppointment(c echo.Context) error {
+	var appointment models.Appointment
+	if err := config.DB.First(&appointment, c.Param("id")).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Appointment not found"})
+	}
+	var req AppointmentChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	change := models.AppointmentChange{
+		AppointmentID: appointment.ID,
+		Action:        models.ActionCancel,
+		Reason:        strings.TrimSpace(req.Reason),
+		Actor:         currentActor(c),
+	}
+	if change.Reason == "" {
+		return validationFailed(c, models.ValidationErrors{{Field: "reason", Code: models.CodeRequired, Message: "Reason is required"}})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&appointment).Where("status = ?", models.AppointmentScheduled).Update("status", models.AppointmentCancelled)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errAppointmentClosed
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return appointmentWriteFailed(c, err)
+	}
+	return GetAppointment(c)
+}
+
+// GetStaffAppointmentCalendar devuelve los turnos de un miembro del personal como calendario iCalendar
+// @Summary Calendario de turnos
+// @Description Calendario (.ics) con los turnos del usuario dueño del token, desde 30 días atrás, para suscribirse desde una aplicación de calendario. Los turnos cancelados se publican como cancelados para que desaparezcan de la agenda y los de clientes eliminados no se publican. El token se obtiene con PUT /api/v1/users/{id}/calendar_token y reemplaza a la contraseña
+// @Tags Turnos
+// @Produce text/calendar
+// @Param token path string true "Token de calendario del usuario"
+// @Success 200 {string} string "Calendario iCalendar"
+// @Failure 404 {object} map[string]string "Token inválido o usuario deshabilitado"
+// @Router /calendar/{token}/appointments.ics [get]
+func GetStaffAppointmentCalendar(c echo.Context) error {
+	token, err := findCalendarToken(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Calendar not found"})
+	}
+
+	var events []struct {
+		models.Appointment
+		ServiceName     string
+		StoreName       string
+		ClientName      string
+		ClientLastName  string
+		ClientEmail     string
+		ClientTelephone string
+		PetName         *string
+		ChangeCount     int
+	}
+	err = config.DB.Model(&models.Appointment{}).
+		Select(`appointments.*, service_types.name AS service_name, stores.name AS store_name,
+			clients.name AS client_name, clients.last_name AS client_last_name, clients.email AS client_email,
+			clients.telephone AS client_telephone, pets.name AS pet_name,
+			(SELECT COUNT(*) FROM appointment_changes WHERE appointment_changes.appointment_id = appointments.id) AS change_count`).
+		Joins("JOIN service_types ON service_types.id = appointments.service_type_id").
+		Joins("JOIN stores ON stores.id = appointments.store_id").
+		Joins("JOIN clients ON clients.id = appointments.client_id AND clients.deleted_at IS NULL").
+		Joins("LEFT JOIN pets ON pets.id = appointments.pet_id").
+		Where("appointments.staff_id = ? AND appointments.starts_at >= ?", token.UserID, models.Now().UTC().AddDate(0, 0, -appointmentCalendarsPast)).
+		Order("appointments.starts_at, appointments.id").
+		Scan(&events).Error
+	if err != nil {
+		log.Printf("Appointment calendar failed: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve appointments"})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/calendar; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `inline; filename="appointments.ics"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := newICalWriter(res, "Appointments", models.Now())
+	for _, e := range events {
+		summary := e.ServiceName + " - " + e.ClientName + " " + e.ClientLastName
+		if e.PetName != nil {
+			summary = e.ServiceName + " - " + *e.PetName + " (" + e.ClientName + " " + e.ClientLastName + ")"
+		}
+		description := e.ClientEmail + "\n" + e.ClientTelephone
+		if e.Notes != "" {
+			description += "\n" + e.Notes
+		}
+		err := w.WriteEvent(icalEvent{
+			UID:         fmt.Sprintf("appointment-%d@golangapp", e.ID),
+			Date:        e.StartsAt,
+			End:         e.EndsAt,
+			Summary:     summary,
+			Description: description,
+			Location:    e.StoreName,
+			Sequence:    e.ChangeCount,
+			Cancelled:   e.Status == models.AppointmentCancelled,
+		})
+		if err != nil {
+			log.Printf("Appointment calendar failed: %v", err)
+			return nil
+		}
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("Appointment calendar failed: %v", err)
+	}
+	return nil
+}
+
+// withAppointmentChanges carga el historial de cambios de los turnos, del más antiguo al más reciente
+func withAppointmentChanges(db *gorm.DB) *gorm.DB {
+	return db.Preload("Changes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+// checkAppointment comprueba que el cliente y la mascota estén activos y sean del mismo dueño, que existan
+// el servicio, la tienda y el miembro del personal, y que la tienda esté abierta. Calcula el fin del turno
+func checkAppointment(a *models.Appointment) models.ValidationErrors {
+	var errs models.ValidationErrors
+	notFound := func(field, message string) {
+		errs = append(errs, models.FieldError{Field: field, Code: models.CodeNotFound, Message: message})
+	}
+
+	if err := config.DB.Select("id").First(&models.Client{}, a.ClientID).Error; err != nil {
+		notFound("client_id", "Client not found")
+	} else if a.PetID != nil {
+		if err := config.DB.Where("client_id = ?", a.ClientID).Select("id").First(&models.Pet{}, *a.PetID).Error; err != nil {
+			notFound("pet_id", "Pet not found among the client's pets")
+		}
+	}
+	var service models.ServiceType
+	if err := config.DB.First(&service, a.ServiceTypeID).Error; err != nil {
+		notFound("service_type_id", "Service type not found")
+	}
+	var store models.Store
+	if err := config.DB.First(&store, a.StoreID).Error; err != nil {
+		notFound("store_id", "Store not found")
+	}
+	if err := config.DB.Where("is_enabled = ?", true).Select("id").First(&models.User{}, a.StaffID).Error; err != nil {
+		notFound("staff_id", "Staff member not found or disabled")
+	}
+	if service.ID != 0 && store.ID != 0 {
+		a.Schedule(a.StartsAt, service)
+		errs = append(errs, models.ValidateAppointmentTime(a, &store)...)
+	}
+	return errs
+}
+
+// checkDoubleBooking devuelve un error si otro turno vigente se superpone con a. Se llama dentro de la
+// transacción después de guardar a, cuando SQLite ya bloqueó la base para escritura, de modo que dos
+// reservas simultáneas no pueden pasar la comprobación a la vez
+func checkDoubleBooking(tx *gorm.DB, a *models.Appointment) error {
+	var other models.Appointment
+	err := models.AppointmentConflicts(tx, a).Order("starts_at").First(&other).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil
+	case err != nil:
+		return err
+	case other.StaffID == a.StaffID:
+		return errStaffDoubleBooked
+	default:
+		return errPetDoubleBooked
+	}
+}
+
+// appointmentWriteFailed responde a un error al guardar un turno; las reservas superpuestas y los turnos
+// cancelados son un 409
+func appointmentWriteFailed(c echo.Context, err error) error {
+	if errors.Is(err, errStaffDoubleBooked) || errors.Is(err, errPetDoubleBooked) || errors.Is(err, errAppointmentClosed) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// appointmentRequest ejecuta handler con el parámetro de ruta id si se pasa
+func appointmentRequest(t *testing.T, handler echo.HandlerFunc, method, query, body string, id ...int) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/api/v1/appointments?"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if len(id) > 0 {
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(id[0]))
+	}
+	assert.NoError(t, handler(c))
+	return rec
+}
+
+type appointmentFixture struct {
+	store              models.Store
+	grooming, vetVisit models.ServiceType
+	ana, ben           models.User
+	owner              models.Client
+	luna               models.Pet
+}
+
+// seedAppointments crea una tienda en Madrid que abre de lunes a viernes de 9 a 14 y de 16 a 20, un baño de
+// una hora, una consulta de media hora, dos miembros del personal y un cliente con su mascota
+func seedAppointments(t *testing.T) appointmentFixture {
+	var f appointmentFixture
+	f.store = models.Store{Name: "Centro", TimeZone: "Europe/Madrid"}
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday"} {
+		f.store.OpeningHours = append(f.store.OpeningHours, models.OpeningHours{Day: day, Opens: "09:00", Closes: "14:00"}, models.OpeningHours{Day: day, Opens: "16:00", Closes: "20:00"})
+	}
+	f.grooming = models.ServiceType{Name: "Grooming", DurationMinutes: 60}
+	f.vetVisit = models.ServiceType{Name: "Vet visit", DurationMinutes: 30}
+	f.ana = models.User{Username: "ana", Email: "ana@example.com", Password: "x"}
+	f.ben = models.User{Username: "ben", Email: "ben@example.com", Password: "x"}
+	for _, record := range []interface{}{&f.store, &f.grooming, &f.vetVisit, &f.ana, &f.ben} {
+		if err := config.DB.Create(record).Error; err != nil {
+			t.Fatalf("Error al crear los datos de turnos: %v", err)
+		}
+	}
+	f.owner = createTestOwner(t, "john.doe@example.com")
+	f.luna = models.Pet{ClientID: f.owner.ID, Name: "Luna", Species: models.SpeciesCat}
+	config.DB.Create(&f.luna)
+	return f
+}
+
+func cleanupAppointments() {
+	for _, model := range []interface{}{&models.AppointmentChange{}, &models.Appointment{}, &models.ServiceType{}, &models.Store{}, &models.CalendarToken{}, &models.User{}} {
+		config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model)
+	}
+	cleanupPets()
+}
+
+// book reserva un turno del servicio con staff a la hora starts (UTC), con la mascota si pet no es 0
+func (f appointmentFixture) book(t *testing.T, service models.ServiceType, staff models.User, pet int, starts string) *httptest.ResponseRecorder {
+	petID := "null"
+	if pet != 0 {
+		petID = strconv.Itoa(pet)
+	}
+	body := fmt.Sprintf(`{"client_id": %d, "pet_id": %s, "service_type_id": %d, "store_id": %d, "staff_id": %d, "starts_at": %q}`,
+		f.owner.ID, petID, service.ID, f.store.ID, staff.ID, starts)
+	return appointmentRequest(t, CreateAppointment, http.MethodPost, "", body)
+}
+
+func TestCreateAppointmentRejectsDoubleBookings(t *testing.T) {
+	setupTestDB()
+	defer cleanupAppointments()
+	f := seedAppointments(t)
+
+	// El lunes 17 a las 10 en Madrid son las 8 UTC
+	rec := f.book(t, f.grooming, f.ana, f.luna.ID, "2024-06-17T10:00:00+02:00")
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var appointment models.Appointment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &appointment))
+	assert.Equal(t, "2024-06-17T08:00:00Z", appointment.StartsAt.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "2024-06-17T09:00:00Z", appointment.EndsAt.Format("2006-01-02T15:04:05Z07:00"), "El fin se calcula con la duración del servicio")
+	assert.Equal(t, models.AppointmentScheduled, appointment.Status)
+
+	rec = f.book(t, f.vetVisit, f.ana, 0, "2024-06-17T08:30:00Z")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error": "Staff member already has an appointment at that time"}`, rec.Body.String())
+	rec = f.book(t, f.vetVisit, f.ben, f.luna.ID, "2024-06-17T08:30:00Z")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error": "Pet already has an appointment at that time"}`, rec.Body.String())
+	rec = f.book(t, f.vetVisit, f.ben, 0, "2024-06-17T08:30:00Z")
+	assert.Equal(t, http.StatusCreated, rec.Code, "Otro miembro del personal está libre")
+	rec = f.book(t, f.vetVisit, f.ana, 0, "2024-06-17T09:00:00Z")
+	assert.Equal(t, http.StatusCreated, rec.Code, "Un turno puede empezar cuando termina el anterior")
+
+	var count int64
+	config.DB.Model(&models.Appointment{}).Count(&count)
+	assert.Equal(t, int64(3), count, "Las reservas rechazadas no se guardan")
+}
+
+func TestDeleteClientFreesAppointments(t *testing.T) {
+	setupTestDB()
+	defer cleanupAppointments()
+	f := seedAppointments(t)
+	var booked models.Appointment
+	json.Unmarshal(f.book(t, f.grooming, f.ana, f.luna.ID, "2024-06-17T08:00:00Z").Body.Bytes(), &booked)
+	token, hash, _ := models.NewCalendarToken()
+	config.DB.Create(&models.CalendarToken{UserID: f.ana.ID, TokenHash: hash})
+
+	rec := petRequest(t, DeleteClient, http.MethodDelete, "", "", f.owner.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = appointmentRequest(t, GetAppointment, http.MethodGet, "", "", booked.ID)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &booked))
+	assert.Equal(t, models.AppointmentCancelled, booked.Status, "Los turnos del cliente eliminado se cancelan")
+	if assert.Len(t, booked.Changes, 1) {
+		assert.Equal(t, models.ActionCancel, booked.Changes[0].Action)
+		assert.Equal(t, models.ClientDeletedReason, booked.Changes[0].Reason)
+	}
+
+	f.owner = createTestOwner(t, "jane.doe@example.com")
+	rec = f.book(t, f.grooming, f.ana, 0, "2024-06-17T08:00:00Z")
+	assert.Equal(t, http.StatusCreated, rec.Code, "El horario queda libre para otro cliente")
+	// Los turnos que quedaron vigentes de clientes eliminados antes de que se cancelaran tampoco ocupan el horario
+	config.DB.Delete(&f.owner)
+	f.owner = createTestOwner(t, "jim.doe@example.com")
+	rec = f.book(t, f.grooming, f.ana, 0, "2024-06-17T08:00:00Z")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	e := echo.New()
+	rec = httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/calendar/"+token+"/appointments.ics", nil), rec)
+	c.SetParamNames("token")
+	c.SetParamValues(token)
+	assert.NoError(t, GetStaffAppointmentCalendar(c))
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "BEGIN:VEVENT"), "Los turnos de clientes eliminados no se publican")
+	assert.NotContains(t, rec.Body.String(), "appointment-"+strconv.Itoa(booked.ID)+"@")
+}
+
+func TestCreateAppointmentValidation(t *testing.T) {
+	setupTestDB()
+	defer cleanupAppointments()
+	f := seedAppointments(t)
+
+	codes := func(rec *httptest.ResponseRecorder) map[string]string {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var response ValidationErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		fields := map[string]string{}
+		for _, e := range response.Errors {
+			fields[e.Field] = e.Code
+		}
+		return fields
+	}
+
+	assert.Equal(t, map[string]string{"starts_at": models.CodeOutsideHours}, codes(f.book(t, f.grooming, f.ana, 0, "2024-06-17T13:30:00+02:00")), "Termina después del cierre")
+	assert.Equal(t, map[string]string{"starts_at": models.CodeOutsideHours}, codes(f.book(t, f.grooming, f.ana, 0, "2024-06-22T10:00:00+02:00")), "Los sábados está cerrada")
+	assert.Equal(t, map[string]string{"starts_at": models.CodeOutOfRange}, codes(f.book(t, f.grooming, f.ana, 0, "2024-06-14T10:00:00+02:00")), "Ya pasó")
+
+	other := createTestOwner(t, "jane.doe@example.com")
+	f.owner = other
+	assert.Equal(t, map[string]string{"pet_id": models.CodeNotFound}, codes(f.book(t, f.grooming, f.ana, f.luna.ID, "2024-06-17T10:00:00+02:00")), "La mascota es de otro cliente")
+
+	config.DB.Model(&f.ben).Update("is_enabled", false)
+	f.store.ID, f.grooming.ID = 999, 999
+	assert.Equal(t, map[string]string{"store_id": models.CodeNotFound, "service_type_id": models.CodeNotFound, "staff_id": models.CodeNotFound},
+		codes(f.book(t, f.grooming, f.ben, 0, "2024-06-17T10:00:00+02:00")))
+}
+
+func TestRescheduleAndCancelAppointment(t *testing.T) {
+	setupTestDB()
+	defer cleanupAppointments()
+	f := seedAppointments(t)
+	rec := f.book(t, f.grooming, f.ana, f.luna.ID, "2024-06-17T08:00:00Z")
+	var appointment models.Appointment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &appointment))
+
+	rec = appointmentRequest(t, RescheduleAppointment, http.MethodPost, "", `{"starts_at": "2024-06-17T14:00:00Z"}`, appointment.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "El motivo es obligatorio")
+	rec = appointmentRequest(t, RescheduleAppointment, http.MethodPost, "", `{"starts_at": "2024-06-17T14:00:00Z", "staff_id": `+strconv.Itoa(f.ben.ID)+`, "reason": "Client asked for the afternoon"}`, appointment.ID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var moved models.Appointment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &moved))
+	assert.Equal(t, f.ben.ID, moved.StaffID)
+	assert.Equal(t, "2024-06-17 15:00", moved.EndsAt.UTC().Format("2006-01-02 15:04"))
+	if assert.Len(t, moved.Changes, 1) {
+		assert.Equal(t, models.ActionReschedule, moved.Changes[0].Action)
+		assert.Equal(t, "Client asked for the afternoon", moved.Changes[0].Reason)
+		assert.Equal(t, f.ana.ID, *moved.Changes[0].PreviousStaffID)
+		assert.Equal(t, "2024-06-17 08:00", moved.Changes[0].PreviousStartsAt.UTC().Format("2006-01-02 15:04"))
+	}
+
+	// El horario que dejó libre se puede reservar, y el nuevo queda ocupado
+	assert.Equal(t, http.StatusCreated, f.book(t, f.vetVisit, f.ana, 0, "2024-06-17T08:00:00Z").Code)
+	assert.Equal(t, http.StatusConflict, f.book(t, f.vetVisit, f.ben, 0, "2024-06-17T14:30:00Z").Code)
+	rec = appointmentRequest(t, RescheduleAppointment, http.MethodPost, "", `{"starts_at": "2024-06-17T08:15:00Z", "staff_id": `+strconv.Itoa(f.ana.ID)+`, "reason": "Back to the morning"}`, appointment.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	config.DB.First(&moved, appointment.ID)
+	assert.Equal(t, f.ben.ID, moved.StaffID, "Una reprogramación rechazada no cambia el turno")
+
+	rec = appointmentRequest(t, CancelAppointment, http.MethodPost, "", `{"reason": " "}`, appointment.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = appointmentRequest(t, CancelAppointment, http.MethodPost, "", `{"reason": "Pet is ill"}`, appointment.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var cancelled models.Appointment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cancelled))
+	assert.Equal(t, models.AppointmentCancelled, cancelled.Status)
+	assert.Len(t, cancelled.Changes, 2)
+	assert.Equal(t, http.StatusCreated, f.book(t, f.vetVisit, f.ben, 0, "2024-06-17T14:30:00Z").Code, "Cancelar libera el horario")
+
+	rec = appointmentRequest(t, CancelAppointment, http.MethodPost, "", `{"reason": "Again"}`, appointment.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = appointmentRequest(t, RescheduleAppointment, http.MethodPost, "", `{"starts_at": "2024-06-18T08:00:00Z", "reason": "Too late"}`, appointment.ID)
+	assert.JSONEq(t, `{"error": "Appointment is cancelled"}`, rec.Body.String())
+
+	var listed []models.Appointment
+	rec = appointmentRequest(t, GetAppointments, http.MethodGet, "staff_id="+strconv.Itoa(f.ben.ID), "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	assert.Len(t, listed, 2)
+	rec = appointmentRequest(t, GetAppointments, http.MethodGet, "from=2024-06-17&to=2024-06-17&status=cancelled", "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, appointment.ID, listed[0].ID)
+	}
+	rec = appointmentRequest(t, GetAppointments, http.MethodGet, "from=2024-06-18", "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	assert.Empty(t, listed)
+	for _, query := range []string{"from=2024-06-18&to=2024-06-17", "from=2024-01-01&to=2025-01-01", "status=done", "staff_id=x"} {
+		rec = appointmentRequest(t, GetAppointments, http.MethodGet, query, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestStaffAppointmentCalendar(t *testing.T) {
+	setupTestDB()
+	defer cleanupAppointments()
+	f := seedAppointments(t)
+	var first, second models.Appointment
+	json.Unmarshal(f.book(t, f.grooming, f.ana, f.luna.ID, "2024-06-17T08:00:00Z").Body.Bytes(), &first)
+	json.Unmarshal(f.book(t, f.vetVisit, f.ana, 0, "2024-06-17T10:00:00Z").Body.Bytes(), &second)
+	f.book(t, f.vetVisit, f.ben, 0, "2024-06-17T10:00:00Z")
+	appointmentRequest(t, CancelAppointment, http.MethodPost, "", `{"reason": "Pet is ill"}`, first.ID)
+
+	token, hash, _ := models.NewCalendarToken()
+	config.DB.Create(&models.CalendarToken{UserID: f.ana.ID, TokenHash: hash})
+	feed := func(token string) *httptest.ResponseRecorder {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/calendar/"+token+"/appointments.ics", nil), rec)
+		c.SetParamNames("token")
+		c.SetParamValues(token)
+		assert.NoError(t, GetStaffAppointmentCalendar(c))
+		return rec
+	}
+
+	rec := feed(token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Equal(t, 2, strings.Count(body, "BEGIN:VEVENT"), "Solo los turnos del dueño del token")
+	assert.Contains(t, body, "UID:appointment-"+strconv.Itoa(first.ID)+"@golangapp\r\nDTSTAMP:20240615T120000Z\r\nDTSTART:20240617T080000Z\r\nDTEND:20240617T090000Z\r\nSEQUENCE:1\r\nSUMMARY:Grooming - Luna (John Doe)\r\n")
+	assert.Contains(t, body, "LOCATION:Centro\r\nSTATUS:CANCELLED\r\nTRANSP:OPAQUE\r\n")
+	assert.Contains(t, body, "SUMMARY:Vet visit - John Doe\r\n")
+	assert.Equal(t, http.StatusNotFound, feed("unknown").Code)
+}
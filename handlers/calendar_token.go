@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// CalendarTokenResponse es el token de calendario recién generado y las direcciones de los calendarios de
+// cumpleaños y de turnos del usuario
+type CalendarTokenResponse struct {
+	Token           string `json:"token"`
+	BirthdaysURL    string `json:"birthdays_url"`
+	AppointmentsURL string `json:"appointments_url"`
+}
+
+// RegenerateCalendarToken genera el token con el que un usuario se suscribe a los calendarios
+// @Summary Generar token de calendario
+// @Description Genera un token nuevo para suscribirse sin usar la contraseña al calendario de cumpleaños de clientes y al de los turnos del usuario. El token solo se muestra en esta respuesta y reemplaza al anterior, que deja de funcionar
+// @Tags Usuarios
+// @Param id path int true "ID del Usuario"
+// @Produce json
+// @Success 200 {object} CalendarTokenResponse "Token generado"
+// @Failure 404 {object} map[string]string "Usuario no encontrado"
+// @Router /api/v1/users/{id}/calendar_token [put]
+func RegenerateCalendarToken(c echo.Context) error {
+	id := c.Param("id")
+	var user models.User
+
+	if err := config.DB.First(&user, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"message": "User not found",
+		})
+	}
+
+	token, hash, err := models.NewCalendarToken()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to generate calendar token",
+		})
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.CalendarToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.CalendarToken{UserID: user.ID, TokenHash: hash, CreatedAt: models.Now()}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to generate calendar token",
+		})
+	}
+
+	base := c.Scheme() + "://" + c.Request().Host + "/calendar/" + token
+	return c.JSON(http.StatusOK, CalendarTokenResponse{
+		Token:           token,
+		BirthdaysURL:    base + "/birthdays.ics",
+		AppointmentsURL: base + "/appointments.ics",
+	})
+}
+
+// RevokeCalendarToken anula el token de calendario de un usuario
+// @Summary Anular token de calendario
+// @Description Anula el token de calendario del usuario; las suscripciones que lo usan dejan de funcionar
+// @Tags Usuarios
+// @Param id path int true "ID del Usuario"
+// @Success 204 "Token anulado"
+// @Failure 404 {object} map[string]string "Usuario no encontrado"
+// @Router /api/v1/users/{id}/calendar_token [delete]
+func RevokeCalendarToken(c echo.Context) error {
+	id := c.Param("id")
+	var user models.User
+
+	if err := config.DB.First(&user, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"message": "User not found",
+		})
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Delete(&models.CalendarToken{}).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"message": "Failed to revoke calendar token",
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findCalendarToken busca el token de calendario del parámetro token, de un usuario habilitado
+func findCalendarToken(c echo.Context) (models.CalendarToken, error) {
+	var token models.CalendarToken
+	err := config.DB.Joins("JOIN users ON users.id = calendar_tokens.user_id AND users.is_enabled = ?", true).
+		Where("calendar_tokens.token_hash = ?", models.HashCalendarToken(c.Param("token"))).
+		First(&token).Error
+	return token, err
+}
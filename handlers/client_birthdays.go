@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+	// Las zonas horarias se incluyen en el binario porque la imagen de Docker no trae tzdata
+	_ "time/tzdata"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Ventanas de cumpleaños: la del listado por defecto y la del calendario
+const (
+	defaultBirthdayWindow = "7d"
+	birthdayCalendarDays  = 365
+)
+
+// maxWithinDays es la ventana más larga que aceptan los listados con el parámetro within
+const maxWithinDays = 366
+
+// birthdayCalendarBatchSize es la cantidad de clientes que se leen por vez al generar el calendario
+const birthdayCalendarBatchSize = 500
+
+// ClientBirthday es un cliente con su próximo cumpleaños, los días que faltan y la edad que cumple
+type ClientBirthday struct {
+	models.Client
+	NextBirthday string `json:"next_birthday"`
+	DaysUntil    int    `json:"days_until"`
+	Turns        int    `json:"turns"`
+}
+
+// GetClientBirthdays lista los clientes que cumplen años en los próximos días
+// @Summary Próximos cumpleaños
+// @Description Devuelve los clientes que cumplen años desde hoy hasta antes de que pase within, ordenados por fecha. Quien nació un 29 de febrero lo celebra el 1 de marzo en los años no bisiestos
+// @Tags Clientes
+// @Produce json
+// @Param within query string false "Cantidad de días, incluido hoy (1d a 366d)" default(7d)
+// @Param tz query string false "Zona horaria IANA con la que se determina el día de hoy" default(UTC)
+// @Success 200 {array} ClientBirthday "Clientes con su próximo cumpleaños"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/birthdays [get]
+func GetClientBirthdays(c echo.Context) error {
+	days, err := parseWithinDays(c, defaultBirthdayWindow)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	today, err := requestToday(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var clients []models.Client
+	if err := birthdayScope(config.DB.Model(&models.Client{}), today, days).Find(&clients).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve clients"})
+	}
+	birthdays := make([]ClientBirthday, len(clients))
+	for i, client := range clients {
+		birthdays[i] = newClientBirthday(client, today)
+	}
+	sort.SliceStable(birthdays, func(i, j int) bool {
+		a, b := birthdays[i], birthdays[j]
+		if a.DaysUntil != b.DaysUntil {
+			return a.DaysUntil < b.DaysUntil
+		}
+		if a.LastName != b.LastName {
+			return a.LastName < b.LastName
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return c.JSON(http.StatusOK, birthdays)
+}
+
+// GetClientBirthdayCalendar devuelve los cumpleaños de los clientes como calendario iCalendar
+// @Summary Calendario de cumpleaños
+// @Description Calendario (.ics) con los cumpleaños de los clientes del próximo año, como eventos de día completo, para suscribirse desde una aplicación de calendario. El token se obtiene con PUT /api/v1/users/{id}/calendar_token y reemplaza a la contraseña
+// @Tags Clientes
+// @Produce text/calendar
+// @Param token path string true "Token de calendario del usuario"
+// @Param tz query string false "Zona horaria IANA con la que se determina el día de hoy" default(UTC)
+// @Success 200 {string} string "Calendario iCalendar"
+// @Failure 400 {object} map[string]string "Zona horaria inválida"
+// @Failure 404 {object} map[string]string "Token inválido o usuario deshabilitado"
+// @Router /calendar/{token}/birthdays.ics [get]
+func GetClientBirthdayCalendar(c echo.Context) error {
+	if _, err := findCalendarToken(c); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Calendar not found"})
+	}
+	today, err := requestToday(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/calendar; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `inline; filename="birthdays.ics"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := newICalWriter(res, "Client birthdays", models.Now())
+	var clients []models.Client
+	err = birthdayScope(config.DB.Model(&models.Client{}), today, birthdayCalendarDays).
+		FindInBatches(&clients, birthdayCalendarBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, client := range clients {
+				b := newClientBirthday(client, today)
+				event := icalEvent{
+					UID:         fmt.Sprintf("client-%d-birthday-%s@golangapp", client.ID, b.NextBirthday),
+					Date:        today.AddDate(0, 0, b.DaysUntil),
+					Summary:     fmt.Sprintf("%s %s turns %d", client.Name, client.LastName, b.Turns),
+					Description: client.Email + "\n" + client.Telephone,
+				}
+				if err := w.WriteEvent(event); err != nil {
+					return err
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			res.Flush()
+			return nil
+		}).Error
+	if err != nil {
+		log.Printf("Birthday calendar failed: %v", err)
+		return nil
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("Birthday calendar failed: %v", err)
+	}
+	return nil
+}
+
+// parseWithinDays lee el parámetro within, una cantidad de días entre 1d y 366d, o fallback si no se envía
+func parseWithinDays(c echo.Context, fallback string) (int, error) {
+	raw := c.QueryParam("within")
+	if raw == "" {
+		raw = fallback
+	}
+	within, err := config.ParseDuration(raw)
+	days := int(within / (24 * time.Hour))
+	if err != nil || within%(24*time.Hour) != 0 || days < 1 || days > maxWithinDays {
+		return 0, errors.New("within must be a whole number of days between 1d and 366d")
+	}
+	return days, nil
+}
+
+// requestToday devuelve la fecha de hoy en la zona horaria tz (UTC por defecto), a las 0 UTC
+func requestToday(c echo.Context) (time.Time, error) {
+	loc := time.UTC
+	if tz := c.QueryParam("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return time.Time{}, errors.New("tz must be an IANA time zone such as Europe/Madrid")
+		}
+	}
+	y, m, d := models.Now().In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// birthdayScope filtra los clientes que cumplen años en los days días que empiezan en today. Se compara
+// el mes y el día de birth_day con los de la ventana, que puede pasar al año siguiente
+func birthdayScope(db *gorm.DB, today time.Time, days int) *gorm.DB {
+	seen := map[string]bool{}
+	monthDays := []string{}
+	add := func(md string) {
+		if !seen[md] {
+			seen[md] = true
+			monthDays = append(monthDays, md)
+		}
+	}
+	for i := 0; i < days; i++ {
+		day := today.AddDate(0, 0, i)
+		add(day.Format("01-02"))
+		// En los años no bisiestos, los nacidos un 29 de febrero cumplen el 1 de marzo
+		if day.Month() == time.March && day.Day() == 1 && time.Date(day.Year(), time.February, 29, 0, 0, 0, 0, time.UTC).Month() == time.March {
+			add("02-29")
+		}
+	}
+	return db.Where("strftime('%m-%d', birth_day) IN ?", monthDays)
+}
+
+// newClientBirthday calcula el próximo cumpleaños del cliente a partir de today
+func newClientBirthday(client models.Client, today time.Time) ClientBirthday {
+	next := models.NextBirthday(client.BirthDay, today)
+	return ClientBirthday{
+		Client:       client,
+		NextBirthday: next.Format("2006-01-02"),
+		DaysUntil:    int(next.Sub(today).Hours() / 24),
+		Turns:        next.Year() - client.BirthDay.Year(),
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func clientBirthdays(t *testing.T, query string) (*httptest.ResponseRecorder, []ClientBirthday) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/birthdays?"+query, nil)
+	rec := httptest.NewRecorder()
+
+	var birthdays []ClientBirthday
+	if assert.NoError(t, GetClientBirthdays(e.NewContext(req, rec))) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &birthdays))
+	}
+	return rec, birthdays
+}
+
+// birthdaySummary resume cada cumpleaños como "nombre fecha edad"
+func birthdaySummary(birthdays []ClientBirthday) []string {
+	summary := []string{}
+	for _, b := range birthdays {
+		summary = append(summary, b.Name+" "+b.NextBirthday+" "+strconv.Itoa(b.Turns))
+	}
+	return summary
+}
+
+func seedBirthdayClients(t *testing.T) {
+	births := map[string]time.Time{
+		"Today":    time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC),
+		"Tomorrow": time.Date(1984, time.June, 16, 0, 0, 0, 0, time.UTC),
+		"Week":     time.Date(2000, time.June, 21, 0, 0, 0, 0, time.UTC),
+		"Outside":  time.Date(2000, time.June, 22, 0, 0, 0, 0, time.UTC),
+		"January":  time.Date(1970, time.January, 3, 0, 0, 0, 0, time.UTC),
+		"Leap":     time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC),
+	}
+	for name, birthDay := range births {
+		client := models.Client{Name: name, LastName: "L", Email: strings.ToLower(name) + "@example.com", BirthDay: birthDay, Telephone: "600123456"}
+		if err := config.DB.Create(&client).Error; err != nil {
+			t.Fatalf("Error al insertar el cliente: %v", err)
+		}
+	}
+}
+
+func TestGetClientBirthdays(t *testing.T) {
+	setupTestDB()
+	seedBirthdayClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer func() { models.Now = func() time.Time { return testNow } }()
+
+	rec, birthdays := clientBirthdays(t, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"Today 2024-06-15 34", "Tomorrow 2024-06-16 40", "Week 2024-06-21 24"}, birthdaySummary(birthdays))
+	assert.Equal(t, []int{0, 1, 6}, []int{birthdays[0].DaysUntil, birthdays[1].DaysUntil, birthdays[2].DaysUntil})
+
+	_, birthdays = clientBirthdays(t, "within=1d")
+	assert.Equal(t, []string{"Today 2024-06-15 34"}, birthdaySummary(birthdays))
+	_, birthdays = clientBirthdays(t, "within=8d")
+	assert.Len(t, birthdays, 4)
+
+	// A las 23:30 UTC ya es el día siguiente en Madrid
+	models.Now = func() time.Time { return time.Date(2024, time.June, 15, 23, 30, 0, 0, time.UTC) }
+	_, birthdays = clientBirthdays(t, "within=1d&tz=Europe/Madrid")
+	assert.Equal(t, []string{"Tomorrow 2024-06-16 40"}, birthdaySummary(birthdays))
+
+	// La ventana pasa al año siguiente
+	models.Now = func() time.Time { return time.Date(2024, time.December, 28, 12, 0, 0, 0, time.UTC) }
+	_, birthdays = clientBirthdays(t, "within=14d")
+	assert.Equal(t, []string{"January 2025-01-03 55"}, birthdaySummary(birthdays))
+	assert.Equal(t, 6, birthdays[0].DaysUntil)
+
+	// Quien nació un 29 de febrero lo celebra el 1 de marzo en los años no bisiestos
+	models.Now = func() time.Time { return time.Date(2023, time.February, 27, 12, 0, 0, 0, time.UTC) }
+	_, birthdays = clientBirthdays(t, "within=2d")
+	assert.Empty(t, birthdays)
+	_, birthdays = clientBirthdays(t, "within=3d")
+	assert.Equal(t, []string{"Leap 2023-03-01 23"}, birthdaySummary(birthdays))
+	models.Now = func() time.Time { return time.Date(2024, time.February, 27, 12, 0, 0, 0, time.UTC) }
+	_, birthdays = clientBirthdays(t, "within=3d")
+	assert.Equal(t, []string{"Leap 2024-02-29 24"}, birthdaySummary(birthdays))
+}
+
+func TestGetClientBirthdaysInvalidParams(t *testing.T) {
+	setupTestDB()
+
+	for _, query := range []string{"within=0d", "within=367d", "within=36h", "within=soon", "tz=Mars/Olympus"} {
+		rec, _ := clientBirthdays(t, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestClientBirthdayCalendar(t *testing.T) {
+	setupTestDB()
+	seedBirthdayClients(t)
+	user := models.User{Username: "store", Email: "store@example.com", Password: "x"}
+	config.DB.Create(&user)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer config.DB.Delete(&models.User{}, user.ID)
+	defer config.DB.Where("user_id = ?", user.ID).Delete(&models.CalendarToken{})
+
+	e := echo.New()
+	userID := strconv.Itoa(user.ID)
+	tokenRequest := func(method string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/users/"+userID+"/calendar_token", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(userID)
+		assert.NoError(t, handler(c))
+		return rec
+	}
+	feed := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/calendar/"+token+"/birthdays.ics", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("token")
+		c.SetParamValues(token)
+		assert.NoError(t, GetClientBirthdayCalendar(c))
+		return rec
+	}
+
+	rec := tokenRequest(http.MethodPut, RegenerateCalendarToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var token CalendarTokenResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &token))
+	assert.Equal(t, "http://example.com/calendar/"+token.Token+"/birthdays.ics", token.BirthdaysURL)
+	assert.Equal(t, "http://example.com/calendar/"+token.Token+"/appointments.ics", token.AppointmentsURL)
+
+	rec = feed(token.Token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Equal(t, 6, strings.Count(body, "BEGIN:VEVENT"), "El calendario incluye el próximo cumpleaños de cada cliente")
+	assert.Contains(t, body, "DTSTART;VALUE=DATE:20240615\r\nDTEND;VALUE=DATE:20240616\r\nSUMMARY:Today L turns 34\r\n")
+	assert.Contains(t, body, "DTSTART;VALUE=DATE:20250103\r\n")
+	assert.Contains(t, body, "DTSTART;VALUE=DATE:20250301\r\n", "2025 no es bisiesto")
+
+	// Un token nuevo anula el anterior
+	rec = tokenRequest(http.MethodPut, RegenerateCalendarToken)
+	var renewed CalendarTokenResponse
+	json.Unmarshal(rec.Body.Bytes(), &renewed)
+	assert.NotEqual(t, token.Token, renewed.Token)
+	assert.Equal(t, http.StatusNotFound, feed(token.Token).Code)
+	assert.Equal(t, http.StatusOK, feed(renewed.Token).Code)
+
+	config.DB.Model(&user).Update("is_enabled", false)
+	assert.Equal(t, http.StatusNotFound, feed(renewed.Token).Code, "Un usuario deshabilitado no accede al calendario")
+	config.DB.Model(&user).Update("is_enabled", true)
+
+	rec = tokenRequest(http.MethodDelete, RevokeCalendarToken)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, http.StatusNotFound, feed(renewed.Token).Code)
+}
+
+func TestICalWriterFoldsAndEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	w := newICalWriter(&buf, "Cumpleaños", time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, w.WriteEvent(icalEvent{
+		UID:         "client-1@golangapp",
+		Date:        time.Date(2024, time.June, 20, 0, 0, 0, 0, time.UTC),
+		Summary:     "Smith, José; " + strings.Repeat("ñ", 40),
+		Description: "a\\b\nc",
+	}))
+	assert.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), icalMaxLineOctets, line)
+	}
+	// Al unir las líneas de continuación se recupera el texto escapado
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.Contains(t, unfolded, "\r\nSUMMARY:Smith\\, José\\; "+strings.Repeat("ñ", 40)+"\r\n")
+	assert.Contains(t, unfolded, "\r\nDESCRIPTION:a\\\\b\\nc\r\n")
+	assert.Contains(t, unfolded, "\r\nX-WR-CALNAME:Cumpleaños\r\n")
+	assert.Contains(t, unfolded, "\r\nDTSTAMP:20240615T120000Z\r\n")
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cohortStartSQL son las expresiones SQL del comienzo (YYYY-MM-DD) del período de alta de un cliente; null
+// si no tiene fecha de alta
+var cohortStartSQL = map[string]string{
+	intervalMonth:   "date(created_at, 'start of month')",
+	intervalQuarter: "date(created_at, 'start of month', printf('-%d months', (CAST(strftime('%m', created_at) AS INTEGER) - 1) % 3))",
+}
+
+// ClientCohort son los clientes que se dieron de alta en un mismo período. cohort es el mes (YYYY-MM) o el
+// trimestre (YYYY-Q1) y start su primer día; ambos son null para los clientes sin fecha de alta, que van al
+// final. cumulative es la cantidad de clientes dados de alta hasta el período, incluido
+type ClientCohort struct {
+	Cohort     *string `json:"cohort"`
+	Start      *string `json:"start"`
+	Cumulative int64   `json:"cumulative"`
+	ClientKPI
+}
+
+// ClientCohorts son las cohortes de alta de los clientes
+type ClientCohorts struct {
+	Interval string         `json:"interval"`
+	Cohorts  []ClientCohort `json:"cohorts"`
+}
+
+// GetClientCohorts agrupa a los clientes por mes o trimestre de alta
+// @Summary Cohortes de alta de clientes
+// @Description Agrupa a los clientes por el mes o trimestre en que se dieron de alta, con el tamaño, el acumulado y las estadísticas de edad de cada cohorte. Los períodos sin altas entre la primera y la última cohorte aparecen con count 0. Acepta los mismos filtros que el listado
+// @Tags Clientes
+// @Produce json
+// @Param interval query string false "Tamaño de cada cohorte" Enums(month, quarter) default(month)
+// @Param from query string false "Fecha de alta desde (YYYY-MM-DD)"
+// @Param to query string false "Fecha de alta hasta (YYYY-MM-DD), incluida"
+// @Param percentiles query string false "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)"
+// @Param std query string false "Desviación estándar poblacional o muestral" Enums(population, sample) default(population)
+// @Param buckets query string false "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)"
+// @Param last_name query string false "Prefijo del apellido"
+// @Param email_domain query string false "Dominio del email"
+// @Param telephone_prefix query string false "Prefijo del teléfono, nacional (600) o internacional (+34600)"
+// @Param age_min query int false "Edad mínima"
+// @Param age_max query int false "Edad máxima"
+// @Param birth_day_from query string false "Fecha de nacimiento desde (YYYY-MM-DD)"
+// @Param birth_day_to query string false "Fecha de nacimiento hasta (YYYY-MM-DD)"
+// @Param include_deleted query bool false "Incluir clientes eliminados"
+// @Success 200 {object} ClientCohorts "Cohortes de alta"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/cohorts [get]
+func GetClientCohorts(c echo.Context) error {
+	opts, err := parseKPIOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter, err := parseClientFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	interval := c.QueryParam("interval")
+	if interval == "" {
+		interval = intervalMonth
+	}
+	startSQL, ok := cohortStartSQL[interval]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "interval must be month or quarter"})
+	}
+	from, err := parseOptionalDate(c, "from")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	to, err := parseOptionalDate(c, "to")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if from != nil && to != nil && from.After(*to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+	}
+
+	db := applyClientFilter(clientScope(c), filter)
+	if from != nil {
+		db = db.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		db = db.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+	// Con un rango de fechas, los clientes sin fecha de alta quedan fuera
+	if from != nil || to != nil {
+		db = db.Where("created_at IS NOT NULL")
+	}
+	segments, err := clientAgeDistributions(db, startSQL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve clients"})
+	}
+
+	// Los segmentos vienen ordenados por comienzo, con los clientes sin fecha de alta al final
+	ages := map[string]models.Distribution{}
+	var undated *ageSegment
+	var first, last time.Time
+	for i, s := range segments {
+		if s.Key == nil {
+			undated = &segments[i]
+			continue
+		}
+		start, err := time.Parse(dateLayout, *s.Key)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve clients"})
+		}
+		if first.IsZero() {
+			first = start
+		}
+		last = start
+		ages[*s.Key] = s.Ages
+	}
+	if from != nil {
+		first = *from
+	}
+	if to != nil {
+		last = *to
+	}
+
+	result := ClientCohorts{Interval: interval, Cohorts: []ClientCohort{}}
+	var cumulative int64
+	if !first.IsZero() && !last.IsZero() {
+		periods, err := historyPeriods(first, last, interval)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		for _, period := range periods {
+			label, start := periodLabel(period, interval), period.Format(dateLayout)
+			kpi := computeClientKPI(ages[start], opts)
+			cumulative += kpi.Count
+			result.Cohorts = append(result.Cohorts, ClientCohort{Cohort: &label, Start: &start, Cumulative: cumulative, ClientKPI: kpi})
+		}
+	}
+	if undated != nil {
+		kpi := computeClientKPI(undated.Ages, opts)
+		cumulative += kpi.Count
+		result.Cohorts = append(result.Cohorts, ClientCohort{Cumulative: cumulative, ClientKPI: kpi})
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func clientCohorts(t *testing.T, query string) (*httptest.ResponseRecorder, ClientCohorts) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/cohorts?"+query, nil)
+	rec := httptest.NewRecorder()
+
+	var cohorts ClientCohorts
+	if assert.NoError(t, GetClientCohorts(e.NewContext(req, rec))) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cohorts))
+	}
+	return rec, cohorts
+}
+
+// cohortSummary resume cada cohorte como "cohorte tamaño acumulado"
+func cohortSummary(cohorts ClientCohorts) []string {
+	summary := []string{}
+	for _, c := range cohorts.Cohorts {
+		label := "null"
+		if c.Cohort != nil {
+			label = *c.Cohort
+		}
+		summary = append(summary, label+" "+strconv.FormatInt(c.Count, 10)+" "+strconv.FormatInt(c.Cumulative, 10))
+	}
+	return summary
+}
+
+// seedCohortClients fija la fecha de alta de los clientes de seedListClients
+func seedCohortClients(t *testing.T) {
+	seedListClients(t)
+	for name, createdAt := range map[string]interface{}{
+		"John":  "2024-01-10 09:00:00+00:00",
+		"Jane":  "2024-01-31 23:59:59+00:00",
+		"Alice": "2024-03-02 10:00:00+00:00",
+		"Bob":   "2024-05-20 10:00:00+00:00",
+		"Carol": nil,
+	} {
+		assert.NoError(t, config.DB.Exec("UPDATE clients SET created_at = ? WHERE name = ?", createdAt, name).Error)
+	}
+}
+
+func TestGetClientCohorts(t *testing.T) {
+	setupTestDB()
+	seedCohortClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	rec, cohorts := clientCohorts(t, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "month", cohorts.Interval)
+	assert.Equal(t, []string{"2024-01 2 2", "2024-02 0 2", "2024-03 1 3", "2024-04 0 3", "2024-05 1 4", "null 1 5"}, cohortSummary(cohorts),
+		"Los meses sin altas aparecen vacíos y los clientes sin fecha de alta van al final")
+	assert.Equal(t, "2024-01-01", *cohorts.Cohorts[0].Start)
+	// John (1990-01-01) tiene 34 y Jane (1985-02-14) 39
+	assert.Equal(t, 36.5, *cohorts.Cohorts[0].AverageAge)
+	assert.Nil(t, cohorts.Cohorts[1].AverageAge)
+
+	rec, cohorts = clientCohorts(t, "interval=quarter")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"2024-Q1 3 3", "2024-Q2 1 4", "null 1 5"}, cohortSummary(cohorts))
+	assert.Equal(t, "2024-04-01", *cohorts.Cohorts[1].Start)
+
+	// El rango completa los períodos sin altas y deja fuera a los clientes sin fecha
+	_, cohorts = clientCohorts(t, "from=2023-12-15&to=2024-01-31")
+	assert.Equal(t, []string{"2023-12 0 0", "2024-01 2 2"}, cohortSummary(cohorts))
+
+	_, cohorts = clientCohorts(t, "last_name=smi")
+	assert.Equal(t, []string{"2024-01 1 1", "null 1 2"}, cohortSummary(cohorts), "Acepta los filtros del listado")
+}
+
+func TestGetClientCohortsInvalidParams(t *testing.T) {
+	setupTestDB()
+
+	for _, query := range []string{"interval=week", "from=2024-06-15&to=2024-06-01", "to=15/06/2024", "from=1900-01-01&to=2024-06-15", "std=unbiased"} {
+		rec, _ := clientCohorts(t, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestClientTimestamps(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer func() { models.Now = func() time.Time { return testNow } }()
+
+	client := models.Client{Name: "John", LastName: "Doe", Email: "john.doe@example.com", BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"}
+	yesterday := testNow.AddDate(0, 0, -1)
+	client.CreatedAt = &yesterday
+	assert.NoError(t, config.DB.Create(&client).Error)
+	assert.True(t, testNow.Equal(*client.CreatedAt), "La fecha de alta la fija el servidor")
+
+	// Al modificar solo cambia la fecha de modificación, aunque el cuerpo traiga otra de alta
+	later := testNow.Add(time.Hour)
+	models.Now = func() time.Time { return later }
+	e := echo.New()
+	body := `{"name":"Johnny","last_name":"Doe","email":"john.doe@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"600123456","created_at":"2020-01-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/clients/"+strconv.Itoa(client.ID), strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(client.ID))
+	assert.NoError(t, UpdateClient(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var updated models.Client
+	config.DB.First(&updated, client.ID)
+	assert.True(t, testNow.Equal(*updated.CreatedAt))
+	assert.True(t, later.Equal(*updated.UpdatedAt))
+}
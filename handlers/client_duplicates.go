@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDuplicateScore = 0.8
+	// maxDuplicateBlock limita el tamaño de un grupo de candidatos para no comparar todos contra todos
+	maxDuplicateBlock = 1000
+)
+
+// ClientDuplicate es un par de clientes que pueden ser la misma persona
+type ClientDuplicate struct {
+	Score   models.DuplicateScore `json:"score"`
+	Clients []models.Client       `json:"clients"`
+}
+
+// ClientMergeRequest indica qué clientes fusionar y de cuál tomar cada campo
+type ClientMergeRequest struct {
+	SurvivorID int            `json:"survivor_id"`
+	MergedIDs  []int          `json:"merged_ids"`
+	Fields     map[string]int `json:"fields"`
+}
+
+// GetClientDuplicates busca posibles clientes duplicados
+// @Summary Posibles clientes duplicados
+// @Description Compara los clientes que comparten teléfono, inicio del email o inicio del apellido y puntúa cada par por similitud del nombre completo, teléfono normalizado y parte local del email. Se devuelven los pares con mayor puntuación primero
+// @Tags Clientes
+// @Produce json
+// @Param min_score query number false "Puntuación mínima entre 0 y 1 (por defecto 0.8)"
+// @Param limit query int false "Cantidad máxima de pares (por defecto 50, máximo 500)"
+// @Success 200 {array} ClientDuplicate "Pares de clientes candidatos"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/duplicates [get]
+func GetClientDuplicates(c echo.Context) error {
+	minScore := defaultDuplicateScore
+	if v := c.QueryParam("min_score"); v != "" {
+		var err error
+		if minScore, err = strconv.ParseFloat(v, 64); err != nil || minScore < 0 || minScore > 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "min_score must be a number between 0 and 1"})
+		}
+	}
+	limit := defaultPageLimit
+	if v := c.QueryParam("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+		}
+		limit = min(limit, maxPageLimit)
+	}
+
+	var clients []models.Client
+	if err := config.DB.Order("id").Find(&clients).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	blocks := map[string][]int{}
+	for i := range clients {
+		for _, key := range models.DuplicateBlockingKeys(&clients[i]) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	duplicates := []ClientDuplicate{}
+	compared := map[[2]int]bool{}
+	for _, block := range blocks {
+		if len(block) > maxDuplicateBlock {
+			continue
+		}
+		for x := 0; x < len(block); x++ {
+			for y := x + 1; y < len(block); y++ {
+				pair := [2]int{block[x], block[y]}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				a, b := &clients[pair[0]], &clients[pair[1]]
+				if score := models.ScoreDuplicate(a, b); score.Score >= minScore {
+					duplicates = append(duplicates, ClientDuplicate{Score: score, Clients: []models.Client{*a, *b}})
+				}
+			}
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Score.Score != duplicates[j].Score.Score {
+			return duplicates[i].Score.Score > duplicates[j].Score.Score
+		}
+		if duplicates[i].Clients[0].ID != duplicates[j].Clients[0].ID {
+			return duplicates[i].Clients[0].ID < duplicates[j].Clients[0].ID
+		}
+		return duplicates[i].Clients[1].ID < duplicates[j].Clients[1].ID
+	})
+	if len(duplicates) > limit {
+		duplicates = duplicates[:limit]
+	}
+	return c.JSON(http.StatusOK, duplicates)
+}
+
+// MergeClients fusiona clientes duplicados en uno
+// @Summary Fusionar clientes
+// @Description Fusiona los clientes merged_ids en survivor_id. Cada campo de fields indica el id del cliente del que se toma su valor; los demás campos se conservan del que sobrevive. Los registros relacionados pasan al cliente que sobrevive, los puntos de fidelidad vigentes se le traspasan, los fusionados se eliminan definitivamente y la fusión queda auditada
+// @Tags Clientes
+// @Accept json
+// @Produce json
+// @Param merge body ClientMergeRequest true "Clientes a fusionar y campos elegidos"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 200 {object} models.ClientMerge "Registro de la fusión"
+// @Failure 400 {object} ValidationErrorResponse "Petición o cliente resultante inválidos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Failure 412 {object} map[string]string "El cliente cambió durante la fusión"
+// @Router /api/v1/clients/merge [post]
+func MergeClients(c echo.Context) error {
+	var req ClientMergeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	if req.SurvivorID == 0 || len(req.MergedIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "survivor_id and merged_ids are required"})
+	}
+	involved := map[int]bool{req.SurvivorID: true}
+	for _, id := range req.MergedIDs {
+		if involved[id] {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "merged_ids must be distinct and cannot include survivor_id"})
+		}
+		involved[id] = true
+	}
+
+	var survivor models.Client
+	if err := config.DB.First(&survivor, req.SurvivorID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	var merged []models.Client
+	if err := config.DB.Where("id IN ?", req.MergedIDs).Order("id").Find(&merged).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if len(merged) != len(req.MergedIDs) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	byID := map[int]*models.Client{survivor.ID: &survivor}
+	for i := range merged {
+		byID[merged[i].ID] = &merged[i]
+	}
+
+	result := survivor
+	result.Age = 0
+	for field, id := range req.Fields {
+		source, ok := byID[id]
+		if !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "fields." + field + " must be the id of one of the merged clients"})
+		}
+		if !models.SetClientField(&result, source, field) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "field " + field + " cannot be merged"})
+		}
+	}
+	if errs := models.ValidateClient(&result); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	audit := models.ClientMerge{
+		SurvivorID: survivor.ID,
+		MergedIDs:  req.MergedIDs,
+		Choices:    req.Fields,
+		Merged:     merged,
+		Actor:      currentActor(c),
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Client{}, survivor.ID, survivor.Version); err != nil {
+			return err
+		}
+		// Un fusionado que cambió después de leerlo haría que se borraran datos que no se revisaron
+		for i := range merged {
+			if err := claimVersion(tx, &models.Client{}, merged[i].ID, merged[i].Version); err != nil {
+				return err
+			}
+		}
+		for _, ref := range models.ClientReferences {
+			if err := tx.Table(ref.Table).Where(ref.Column+" IN ?", req.MergedIDs).Update(ref.Column, survivor.ID).Error; err != nil {
+				return err
+			}
+		}
+		for _, id := range req.MergedIDs {
+			if _, err := models.TransferLoyaltyPoints(tx, id, survivor.ID, audit.Actor); err != nil {
+				return err
+			}
+		}
+		// Los fusionados se borran antes de guardar para liberar el email si se elige uno de ellos
+		res := tx.Where("id IN ?", req.MergedIDs).Unscoped().Delete(&models.Client{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != int64(len(req.MergedIDs)) {
+			return errStaleVersion
+		}
+		for i := range merged {
+			if err := models.ApplyClientAgeChange(tx, &merged[i], nil); err != nil {
+				return err
+			}
+			if err := models.ApplyClientLoyaltyChange(tx, &merged[i], nil); err != nil {
+				return err
+			}
+		}
+		// Los puntos vigentes ya se traspasaron y el libro de los fusionados se va con ellos
+		if err := models.PurgeLoyaltyEntries(tx, req.MergedIDs); err != nil {
+			return err
+		}
+		result.Version = survivor.Version + 1
+		if err := tx.Save(&result).Error; err != nil {
+			return err
+		}
+		if err := recordClientChange(c, tx, models.ActionMerge, &survivor, &result); err != nil {
+			return err
+		}
+		audit.Result = result
+		return tx.Create(&audit).Error
+	})
+	if err != nil {
+		return clientWriteFailed(c, err)
+	}
+	return c.JSON(http.StatusOK, audit)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestClientDuplicatesAndMerge(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	birthDay := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	original := models.Client{Name: "José", LastName: "García", Email: "jose.garcia@example.com", BirthDay: birthDay, Telephone: "600123456"}
+	typo := models.Client{Name: "Jose", LastName: "Garcia", Email: "jose.garcia@exmaple.com", BirthDay: birthDay, Telephone: "+34600123456"}
+	other := models.Client{Name: "Ana", LastName: "Gómez", Email: "ana@example.com", BirthDay: birthDay, Telephone: "911234567"}
+	for _, client := range []*models.Client{&original, &typo, &other} {
+		config.DB.Create(client)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/duplicates", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, GetClientDuplicates(e.NewContext(req, rec)))
+	var duplicates []ClientDuplicate
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &duplicates))
+	if assert.Len(t, duplicates, 1) {
+		assert.Equal(t, []int{original.ID, typo.ID}, []int{duplicates[0].Clients[0].ID, duplicates[0].Clients[1].ID})
+		assert.Equal(t, 1.0, duplicates[0].Score.Phone)
+	}
+
+	merge := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/merge", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("username", "admin")
+		assert.NoError(t, MergeClients(c))
+		return rec
+	}
+
+	rec = merge(fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d]}`, typo.ID, typo.ID))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = merge(fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [999]}`, typo.ID))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = merge(fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d], "fields": {"name": %d}}`, typo.ID, original.ID, other.ID))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Sobrevive el registro con el email mal escrito, pero se quedan el nombre y el email del original
+	rec = merge(fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d], "fields": {"name": %d, "last_name": %d, "email": %d}}`,
+		typo.ID, original.ID, original.ID, original.ID, original.ID))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var audit models.ClientMerge
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &audit))
+	assert.Equal(t, "admin", audit.Actor)
+	assert.Equal(t, []int{original.ID}, audit.MergedIDs)
+	if assert.Len(t, audit.Merged, 1) {
+		assert.Equal(t, original.Email, audit.Merged[0].Email)
+	}
+	assert.Equal(t, original.Email, audit.Result.Email)
+
+	var survivor models.Client
+	config.DB.First(&survivor, typo.ID)
+	assert.Equal(t, "José", survivor.Name)
+	assert.Equal(t, "jose.garcia@example.com", survivor.Email)
+	assert.Equal(t, "+34600123456", survivor.Telephone)
+	assert.Equal(t, 2, survivor.Version)
+
+	var count int64
+	config.DB.Unscoped().Model(&models.Client{}).Where("id = ?", original.ID).Count(&count)
+	assert.Zero(t, count, "El cliente fusionado se elimina definitivamente")
+
+	var version models.ClientVersion
+	config.DB.Where("client_id = ? AND version = ?", typo.ID, 2).First(&version)
+	assert.Equal(t, models.ActionMerge, version.Action)
+	assert.Equal(t, models.FieldChange{From: "jose.garcia@exmaple.com", To: "jose.garcia@example.com"}, version.Changes["email"])
+}
+
+func TestMergeClientsRejectsChangedMergedClient(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	birthDay := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	survivor := models.Client{Name: "Jose", LastName: "Garcia", Email: "jose@exmaple.com", BirthDay: birthDay, Telephone: "600123456"}
+	merged := models.Client{Name: "José", LastName: "García", Email: "jose@example.com", BirthDay: birthDay, Telephone: "600123456"}
+	config.DB.Create(&survivor)
+	config.DB.Create(&merged)
+
+	// Otra petición edita al fusionado justo después de que la fusión lo lea
+	bumped := false
+	assert.NoError(t, config.DB.Callback().Query().After("gorm:query").Register("test:edit_merged", func(db *gorm.DB) {
+		if bumped || db.Statement.Table != "clients" || db.Statement.ReflectValue.Kind() != reflect.Slice {
+			return
+		}
+		bumped = true
+		db.Session(&gorm.Session{NewDB: true}).Model(&models.Client{}).Where("id = ?", merged.ID).
+			UpdateColumns(map[string]interface{}{"telephone": "699999999", "version": gorm.Expr("version + 1")})
+	}))
+	defer config.DB.Callback().Query().Remove("test:edit_merged")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/merge",
+		strings.NewReader(fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d]}`, survivor.ID, merged.ID)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, MergeClients(e.NewContext(req, rec)))
+	assert.True(t, bumped)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	var current models.Client
+	assert.NoError(t, config.DB.First(&current, merged.ID).Error, "El fusionado no se borra")
+	assert.Equal(t, "699999999", current.Telephone)
+	var kept models.Client
+	config.DB.First(&kept, survivor.ID)
+	assert.Equal(t, 1, kept.Version, "La fusión se deshace entera")
+}
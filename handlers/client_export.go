@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize es la cantidad de clientes que se leen de la base por vez al exportar
+const exportBatchSize = 500
+
+// exportColumn es una columna exportable de clientes
+type exportColumn struct {
+	Key     string
+	Headers map[string]string
+	Value   func(*models.Client) interface{}
+}
+
+// exportLanguages son los idiomas disponibles para las cabeceras; el primero es el de por defecto
+var exportLanguages = []string{"en", "es", "pt", "it"}
+
+// clientExportColumns define las columnas exportables en el orden en que siempre se escriben
+var clientExportColumns = []exportColumn{
+	{"id", map[string]string{"en": "ID", "es": "ID", "pt": "ID", "it": "ID"},
+		func(c *models.Client) interface{} { return c.ID }},
+	{"name", map[string]string{"en": "Name", "es": "Nombre", "pt": "Nome", "it": "Nome"},
+		func(c *models.Client) interface{} { return c.Name }},
+	{"last_name", map[string]string{"en": "Last name", "es": "Apellido", "pt": "Apelido", "it": "Cognome"},
+		func(c *models.Client) interface{} { return c.LastName }},
+	{"email", map[string]string{"en": "Email", "es": "Correo electrónico", "pt": "Email", "it": "Email"},
+		func(c *models.Client) interface{} { return c.Email }},
+	{"birth_day", map[string]string{"en": "Birth date", "es": "Fecha de nacimiento", "pt": "Data de nascimento", "it": "Data di nascita"},
+		func(c *models.Client) interface{} { return c.BirthDay.Format("2006-01-02") }},
+	{"age", map[string]string{"en": "Age", "es": "Edad", "pt": "Idade", "it": "Età"},
+		func(c *models.Client) interface{} { return c.Age }},
+	{"telephone", map[string]string{"en": "Telephone", "es": "Teléfono", "pt": "Telefone", "it": "Telefono"},
+		func(c *models.Client) interface{} { return c.Telephone }},
+	{"deleted_at", map[string]string{"en": "Deleted at", "es": "Eliminado el", "pt": "Eliminado em", "it": "Eliminato il"},
+		func(c *models.Client) interface{} {
+			if !c.DeletedAt.Valid {
+				return nil
+			}
+			return c.DeletedAt.Time.UTC().Format(time.RFC3339)
+		}},
+}
+
+// exportWriter escribe los clientes exportados en un formato
+type exportWriter interface {
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// ExportClients exporta los clientes filtrados
+// @Summary Exportar clientes
+// @Description Exporta los clientes en CSV, NDJSON o XLSX. Acepta los mismos filtros y orden que el listado y lee la base por lotes, enviando el archivo a medida que se genera. Las columnas se escriben siempre en el mismo orden, sea cual sea el orden en que se pidan
+// @Tags Clientes
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (por defecto), ndjson o xlsx"
+// @Param columns query string false "Columnas separadas por coma (id, name, last_name, email, birth_day, age, telephone, deleted_at)"
+// @Param lang query string false "Idioma de las cabeceras: en, es, pt o it. Por defecto se usa Accept-Language"
+// @Param delimiter query string false "Separador de columnas CSV (por defecto ,)"
+// @Param sort query string false "Campos de orden separados por coma, con prefijo - para descendente"
+// @Param last_name query string false "Prefijo del apellido"
+// @Param email_domain query string false "Dominio del email"
+// @Param age_min query int false "Edad mínima"
+// @Param age_max query int false "Edad máxima"
+// @Param birth_day_from query string false "Fecha de nacimiento desde (YYYY-MM-DD)"
+// @Param birth_day_to query string false "Fecha de nacimiento hasta (YYYY-MM-DD)"
+// @Param telephone_prefix query string false "Prefijo del teléfono, nacional (600) o internacional (+34600)"
+// @Param include_deleted query bool false "Incluir clientes eliminados"
+// @Success 200 {file} file "Archivo exportado"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/export [get]
+func ExportClients(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be csv, ndjson or xlsx"})
+	}
+
+	filter, err := parseClientFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	sorts, err := parseClientSort(c.QueryParam("sort"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	includeDeleted, _ := strconv.ParseBool(c.QueryParam("include_deleted"))
+	columns, err := parseExportColumns(c.QueryParam("columns"), includeDeleted)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	lang, err := exportLanguage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	delimiter := ','
+	if d := c.QueryParam("delimiter"); d != "" {
+		if len([]rune(d)) != 1 || format != "csv" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "delimiter must be a single character and only applies to csv"})
+		}
+		delimiter = []rune(d)[0]
+	}
+
+	base := applyClientFilter(clientScope(c), filter).Session(&gorm.Session{})
+	nextBatch := func(after *clientCursor) ([]models.Client, error) {
+		query := applyClientSort(base, sorts, false)
+		if after != nil {
+			if query, err = applyClientCursor(query, sorts, after); err != nil {
+				return nil, err
+			}
+		}
+		batch := []models.Client{}
+		return batch, query.Limit(exportBatchSize).Find(&batch).Error
+	}
+
+	// El primer lote se lee antes de enviar la respuesta para poder informar un error
+	batch, err := nextBatch(nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="clients.%s"`, format))
+	res.WriteHeader(http.StatusOK)
+
+	headers := make([]string, len(columns))
+	keys := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i], keys[i] = col.Headers[lang], col.Key
+	}
+	w, err := newExportWriter(format, res, keys, headers, delimiter)
+	if err != nil {
+		log.Printf("Client export failed: %v", err)
+		return nil
+	}
+
+	values := make([]interface{}, len(columns))
+	for len(batch) > 0 {
+		for i := range batch {
+			for j, col := range columns {
+				values[j] = col.Value(&batch[i])
+			}
+			if err := w.WriteRow(values); err != nil {
+				log.Printf("Client export failed: %v", err)
+				return nil
+			}
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("Client export failed: %v", err)
+			return nil
+		}
+		res.Flush()
+		if len(batch) < exportBatchSize {
+			break
+		}
+		if batch, err = nextBatch(newClientCursor(sorts, batch[len(batch)-1], false)); err != nil {
+			log.Printf("Client export failed: %v", err)
+			return nil
+		}
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("Client export failed: %v", err)
+	}
+	return nil
+}
+
+// parseExportColumns devuelve las columnas pedidas en el orden fijo de clientExportColumns.
+// Sin columnas se exportan todas, y deleted_at solo si se incluyen los eliminados
+func parseExportColumns(raw string, includeDeleted bool) ([]exportColumn, error) {
+	requested := map[string]bool{}
+	for _, key := range strings.Split(raw, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			requested[key] = true
+		}
+	}
+	all := len(requested) == 0
+	var columns []exportColumn
+	for _, col := range clientExportColumns {
+		if requested[col.Key] || (all && (col.Key != "deleted_at" || includeDeleted)) {
+			columns = append(columns, col)
+			delete(requested, col.Key)
+		}
+	}
+	for key := range requested {
+		return nil, fmt.Errorf("unknown column %q", key)
+	}
+	return columns, nil
+}
+
+// exportLanguage elige el idioma de las cabeceras por el parámetro lang o por Accept-Language
+func exportLanguage(c echo.Context) (string, error) {
+	supported := func(lang string) bool {
+		for _, l := range exportLanguages {
+			if l == lang {
+				return true
+			}
+		}
+		return false
+	}
+	if lang := strings.ToLower(c.QueryParam("lang")); lang != "" {
+		if !supported(lang) {
+			return "", fmt.Errorf("lang must be one of %s", strings.Join(exportLanguages, ", "))
+		}
+		return lang, nil
+	}
+	for _, part := range strings.Split(c.Request().Header.Get("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if supported(base) {
+			return base, nil
+		}
+	}
+	return exportLanguages[0], nil
+}
+
+func newExportWriter(format string, w io.Writer, keys, headers []string, delimiter rune) (exportWriter, error) {
+	switch format {
+	case "ndjson":
+		return &ndjsonExportWriter{w: w, keys: keys}, nil
+	case "xlsx":
+		x, err := newXLSXWriter(w, "Clients")
+		if err != nil {
+			return nil, err
+		}
+		return x, x.WriteRow(stringsToValues(headers))
+	default:
+		cw := csv.NewWriter(w)
+		cw.Comma = delimiter
+		x := &csvExportWriter{w: cw}
+		return x, x.WriteRow(stringsToValues(headers))
+	}
+}
+
+// csvExportWriter escribe una fila CSV por cliente, con las cabeceras localizadas en la primera
+type csvExportWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (x *csvExportWriter) WriteRow(values []interface{}) error {
+	x.record = x.record[:0]
+	for _, v := range values {
+		x.record = append(x.record, spreadsheetText(exportString(v)))
+	}
+	return x.w.Write(x.record)
+}
+
+func (x *csvExportWriter) Flush() error {
+	x.w.Flush()
+	return x.w.Error()
+}
+
+func (x *csvExportWriter) Close() error {
+	return x.Flush()
+}
+
+// ndjsonExportWriter escribe un objeto JSON por cliente con las claves en el orden de las columnas
+type ndjsonExportWriter struct {
+	w    io.Writer
+	keys []string
+}
+
+func (x *ndjsonExportWriter) WriteRow(values []interface{}) error {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(x.keys[i])
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(x.w, b.String())
+	return err
+}
+
+func (x *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+func (x *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// exportString da formato de texto a un valor exportado
+func exportString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// spreadsheetText antepone ' a los textos que una hoja de cálculo ejecutaría como fórmula al abrir el CSV. En
+// XLSX no hace falta porque las celdas de texto nunca se evalúan. Los números con signo, como los teléfonos en
+// E.164, se dejan como están
+func spreadsheetText(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+func stringsToValues(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func exportClients(t *testing.T, query string, header http.Header) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/export?"+query, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	assert.NoError(t, ExportClients(c))
+	return rec
+}
+
+func TestExportClientsCSV(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	rec := exportClients(t, "email_domain=corp.com&columns=telephone,name,age&delimiter=%3B", http.Header{"Accept-Language": {"es-ES,es;q=0.9,en;q=0.8"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="clients.csv"`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "Nombre;Edad;Teléfono\nJane;39;+34911234567\nBob;53;+34933334444\n", rec.Body.String())
+
+	rec = exportClients(t, "lang=en&sort=-birth_day&columns=email&age_min=30", nil)
+	assert.Equal(t, "Email\njohn.doe@example.com\njane.smith@corp.com\nbob@corp.com\n", rec.Body.String())
+
+	for _, query := range []string{"format=pdf", "columns=password", "lang=fr", "format=ndjson&delimiter=%3B", "sort=telephone"} {
+		rec = exportClients(t, query, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestExportClientsEscapesFormulas(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	// Se guarda directamente para no depender de la validación de los nombres
+	config.DB.Create(&models.Client{Name: "=HYPERLINK(\"http://x\")", LastName: "@SUM(A1)", Email: "-2+3@example.com",
+		BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "+34600123456"})
+
+	rec := exportClients(t, "lang=en&columns=name,last_name,email,telephone", nil)
+	assert.Equal(t, "Name,Last name,Email,Telephone\n\"'=HYPERLINK(\"\"http://x\"\")\",'@SUM(A1),'-2+3@example.com,+34600123456\n", rec.Body.String())
+
+	// Las celdas de texto de XLSX no se evalúan y conservan el valor tal cual
+	sheet := xlsxSheet(t, exportClients(t, "format=xlsx&columns=last_name,email", nil))
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">@SUM(A1)</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">-2+3@example.com</t></is></c>`)
+}
+
+func TestExportClientsNDJSONInBatches(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	total := exportBatchSize*2 + 1
+	clients := make([]models.Client, total)
+	for i := range clients {
+		clients[i] = models.Client{Name: fmt.Sprintf("Client%04d", i), LastName: "Batch", Email: fmt.Sprintf("c%04d@example.com", i),
+			BirthDay: time.Date(1980, time.January, 1+i%28, 0, 0, 0, 0, time.UTC), Telephone: "600000000"}
+	}
+	assert.NoError(t, config.DB.CreateInBatches(&clients, 200).Error)
+
+	rec := exportClients(t, "format=ndjson&columns=id,name,birth_day&sort=birth_day", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var lines []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Len(t, lines, total)
+	assert.Regexp(t, `^\{"id":\d+,"name":"Client0000","birth_day":"1980-01-01"\}$`, lines[0])
+
+	seen := map[float64]bool{}
+	last := ""
+	for _, line := range lines {
+		var row map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &row))
+		assert.False(t, seen[row["id"].(float64)], "Cada cliente se exporta una vez")
+		seen[row["id"].(float64)] = true
+		assert.GreaterOrEqual(t, row["birth_day"].(string), last)
+		last = row["birth_day"].(string)
+	}
+}
+
+// xlsxSheet devuelve el XML de la hoja de un XLSX exportado
+func xlsxSheet(t *testing.T, rec *httptest.ResponseRecorder) string {
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	for _, f := range archive.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := f.Open()
+			raw, _ := io.ReadAll(r)
+			return string(raw)
+		}
+	}
+	return ""
+}
+
+func TestExportClientsXLSX(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	rec := exportClients(t, "format=xlsx&lang=it&columns=name,age&last_name=smi", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	sheet := xlsxSheet(t, rec)
+	assert.Contains(t, sheet, `<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">Nome</t></is></c><c r="B1" t="inlineStr"><is><t xml:space="preserve">Età</t></is></c></row>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>39</v></c>`)
+	assert.Contains(t, sheet, `<row r="3">`)
+	assert.NotContains(t, sheet, `<row r="4">`)
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+}
@@ -1,36 +1,118 @@
 package handlers
 
 import (
-	"math"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
-	"regexp"
+	"net/url"
 	"strconv"
-	"time"
+	"strings"
 
 	"golangApp/config"
 	"golangApp/models"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
-type ClientKPI struct {
-	AverageAge           float64 `json:"average_age"`
-	AgeStandardDeviation float64 `json:"age_standard_deviation"`
-}
-
-// GetAll obtiene todos los clientes
-// @Summary Obtiene todos los clientes
-// @Description Recupera una lista de todos los clientes en la base de datos
+// GetAll obtiene los clientes paginados, filtrados y ordenados
+// @Summary Obtiene los clientes
+// @Description Recupera una página de clientes. Admite paginación por limit/offset o por cursor, filtros y orden por varios campos. Los enlaces a las páginas siguiente y anterior se envían en la cabecera Link (RFC 8288)
 // @Tags Clientes
 // @Produce json
-// @Success 200 {array} models.Client "Lista de clientes"
+// @Param limit query int false "Cantidad máxima de clientes por página (por defecto 50, máximo 500)"
+// @Param offset query int false "Cantidad de clientes a saltar"
+// @Param cursor query string false "Cursor opaco devuelto en next_cursor o prev_cursor"
+// @Param sort query string false "Campos de orden separados por coma, con prefijo - para descendente (id, name, last_name, email, birth_day, age)"
+// @Param last_name query string false "Prefijo del apellido"
+// @Param email_domain query string false "Dominio del email"
+// @Param age_min query int false "Edad mínima"
+// @Param age_max query int false "Edad máxima"
+// @Param birth_day_from query string false "Fecha de nacimiento desde (YYYY-MM-DD)"
+// @Param birth_day_to query string false "Fecha de nacimiento hasta (YYYY-MM-DD)"
+// @Param telephone_prefix query string false "Prefijo del teléfono, nacional (600) o internacional (+34600)"
+// @Param include_deleted query bool false "Incluir clientes eliminados"
+// @Success 200 {object} ClientPage "Página de clientes"
 // @Router /api/v1/clients [get]
 func GetAll(c echo.Context) error {
-	var clients []models.Client
-	if err := config.DB.Find(&clients).Error; err != nil {
+	q, err := parseClientListQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	base := applyClientFilter(clientScope(c), q.Filter).Session(&gorm.Session{})
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	backward := q.Cursor != nil && q.Cursor.Backward
+	query := applyClientSort(base, q.Sort, backward)
+	if q.Cursor != nil {
+		if query, err = applyClientCursor(query, q.Sort, q.Cursor); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	clients := []models.Client{}
+	if err := query.Offset(q.Offset).Limit(q.Limit + 1).Find(&clients).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, clients)
+	hasMore := len(clients) > q.Limit
+	if hasMore {
+		clients = clients[:q.Limit]
+	}
+	if backward {
+		for i, j := 0, len(clients)-1; i < j; i, j = i+1, j-1 {
+			clients[i], clients[j] = clients[j], clients[i]
+		}
+	}
+
+	var hasNext, hasPrev bool
+	switch {
+	case q.Cursor == nil:
+		hasNext = int64(q.Offset+len(clients)) < total
+		hasPrev = q.Offset > 0
+	case backward:
+		hasNext, hasPrev = true, hasMore
+	default:
+		hasNext, hasPrev = hasMore, true
+	}
+
+	page := ClientPage{Data: clients, Total: total, Limit: q.Limit, Offset: q.Offset}
+	links := map[string]url.Values{
+		"first": withQuery(c, map[string]string{"offset": "", "cursor": ""}),
+	}
+	if len(clients) > 0 {
+		if hasNext {
+			page.NextCursor = encodeClientCursor(q.Sort, clients[len(clients)-1], false)
+		}
+		if hasPrev {
+			page.PrevCursor = encodeClientCursor(q.Sort, clients[0], true)
+		}
+	}
+	if q.Cursor == nil {
+		if hasNext {
+			links["next"] = withQuery(c, map[string]string{"offset": strconv.Itoa(q.Offset + q.Limit)})
+		}
+		if hasPrev {
+			links["prev"] = withQuery(c, map[string]string{"offset": strconv.Itoa(max(q.Offset-q.Limit, 0))})
+		}
+	} else {
+		if page.NextCursor != "" {
+			links["next"] = withQuery(c, map[string]string{"cursor": page.NextCursor})
+		}
+		if page.PrevCursor != "" {
+			links["prev"] = withQuery(c, map[string]string{"cursor": page.PrevCursor})
+		}
+	}
+	setLinkHeader(c, links)
+
+	return c.JSON(http.StatusOK, page)
 }
 
 // GetClient obtiene un cliente por ID
@@ -38,32 +120,73 @@ func GetAll(c echo.Context) error {
 // @Description Recupera un cliente específico usando su ID
 // @Tags Clientes
 // @Param id path int true "ID del Cliente"
+// @Param include_deleted query bool false "Incluir clientes eliminados"
 // @Produce json
 // @Success 200 {object} models.Client "Detalles del cliente"
+// @Header 200 {string} ETag "Versión del recurso"
 // @Router /api/v1/clients/{id} [get]
 func GetClient(c echo.Context) error {
 	id := c.Param("id")
 	var client models.Client
-	if err := config.DB.First(&client, id).Error; err != nil {
+	if err := clientScope(c).First(&client, id).Error; err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
 	}
+	setETag(c, client.Version)
 	return c.JSON(http.StatusOK, client)
 }
 
-func validEmail(email string) bool {
-	const emailRegex = `^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`
-	re := regexp.MustCompile(emailRegex)
-	return re.MatchString(email)
+// ValidationErrorResponse es la respuesta a una petición con datos inválidos
+type ValidationErrorResponse struct {
+	Error  string                  `json:"error"`
+	Errors models.ValidationErrors `json:"errors"`
+}
+
+// validationFailed responde con la lista completa de errores de validación
+func validationFailed(c echo.Context, errs models.ValidationErrors) error {
+	return c.JSON(http.StatusBadRequest, ValidationErrorResponse{Error: "Validation failed", Errors: errs})
+}
+
+// clientWriteFailed responde a un error al guardar un cliente; una versión desactualizada es un 412
+// y un email que ya usa otro cliente (aunque difiera en mayúsculas) un 409
+func clientWriteFailed(c echo.Context, err error) error {
+	if errors.Is(err, errStaleVersion) {
+		return c.JSON(http.StatusPreconditionFailed, map[string]string{"error": err.Error()})
+	}
+	if isEmailTaken(err) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Email is already in use by another client"})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+// isEmailTaken indica si err es la violación de la unicidad del email de clients, tanto del email tal como se
+// escribió como de su forma canónica email_key
+func isEmailTaken(err error) bool {
+	const prefix = "UNIQUE constraint failed: "
+	msg := err.Error()
+	i := strings.Index(msg, prefix)
+	if i < 0 {
+		return false
+	}
+	for _, column := range strings.Split(msg[i+len(prefix):], ", ") {
+		if column == "clients.email" || column == "clients.email_key" {
+			return true
+		}
+	}
+	return false
 }
 
 // CreateClient crea un nuevo cliente
 // @Summary Crear cliente
-// @Description Crea un nuevo cliente con los datos proporcionados
+// @Description Crea un nuevo cliente con los datos proporcionados. La edad se deriva de birth_day; si se envía solo se comprueba que coincida
 // @Tags Clientes
 // @Accept json
 // @Produce json
 // @Param client body models.Client true "Información del Cliente"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
 // @Success 201 {object} models.Client "Cliente creado exitosamente"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 409 {object} map[string]string "El email ya lo usa otro cliente o hay una petición en curso con la misma Idempotency-Key"
+// @Failure 422 {object} map[string]string "Idempotency-Key usada con otra petición"
 // @Router /api/v1/clients [post]
 func CreateClient(c echo.Context) error {
 	var client models.Client
@@ -71,30 +194,20 @@ func CreateClient(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
 	}
 
-	if client.Name == "" || client.LastName == "" || client.Email == "" || client.Age == 0 || client.BirthDay.IsZero() {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Name, Last Name, Email, Age, and Birth Day are required"})
-	}
-
-	if !validEmail(client.Email) {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid email format"})
+	if errs := models.ValidateClient(&client); len(errs) > 0 {
+		return validationFailed(c, errs)
 	}
 
-	if len(client.Telephone) < 7 || !isNumeric(client.Telephone) {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Phone number must be numeric and at least 7 digits long"})
-	}
-
-	currentYear := time.Now().Year()
-	calculatedAge := currentYear - client.BirthDay.Year()
-	if client.BirthDay.After(time.Now().AddDate(-client.Age, 0, 0)) {
-		calculatedAge--
-	}
-	if client.Age != calculatedAge {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Age does not match birth date"})
-	}
-
-	if err := config.DB.Create(&client).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&client).Error; err != nil {
+			return err
+		}
+		return recordClientChange(c, tx, models.ActionCreate, nil, &client)
+	})
+	if err != nil {
+		return clientWriteFailed(c, err)
 	}
+	setETag(c, client.Version)
 	return c.JSON(http.StatusCreated, client)
 }
 
@@ -103,9 +216,15 @@ func CreateClient(c echo.Context) error {
 // @Description Actualiza la información de un cliente existente
 // @Tags Clientes
 // @Param id path int true "ID del Cliente"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Param client body models.Client true "Información actualizada del Cliente"
 // @Produce json
 // @Success 200 {object} models.Client "Cliente actualizado exitosamente"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 409 {object} map[string]string "El email ya lo usa otro cliente"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/clients/{id} [put]
 func UpdateClient(c echo.Context) error {
 	id := c.Param("id")
@@ -114,74 +233,253 @@ func UpdateClient(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
 	}
 
+	if status, err := checkIfMatch(c, client.Version); err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	before := client
+	// La edad derivada no se envía de vuelta; solo se valida si viene en el cuerpo
+	client.Age = 0
 	if err := c.Bind(&client); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
 	}
+	client.ID, client.DeletedAt = before.ID, before.DeletedAt
+	client.Version = before.Version
+	client.CreatedAt, client.UpdatedAt = before.CreatedAt, before.UpdatedAt
 
-	if err := config.DB.Save(&client).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	if errs := models.ValidateClient(&client); len(errs) > 0 {
+		return validationFailed(c, errs)
 	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Client{}, client.ID, before.Version); err != nil {
+			return err
+		}
+		client.Version++
+		if err := tx.Save(&client).Error; err != nil {
+			return err
+		}
+		return recordClientChange(c, tx, models.ActionUpdate, &before, &client)
+	})
+	if err != nil {
+		return clientWriteFailed(c, err)
+	}
+	setETag(c, client.Version)
 	return c.JSON(http.StatusOK, client)
 }
 
+// PatchClient modifica parcialmente un cliente
+// @Summary Modificar cliente parcialmente
+// @Description Aplica un JSON Merge Patch (RFC 7396) o un JSON Patch (RFC 6902) sobre un cliente. El resultado se valida igual que un alta
+// @Tags Clientes
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param If-Match header string false "ETag de la versión que se modifica"
+// @Param patch body object true "Documento de patch"
+// @Success 200 {object} models.Client "Cliente modificado exitosamente"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 409 {object} map[string]string "Falló una operación test o el email ya lo usa otro cliente"
+// @Failure 415 {object} map[string]string "Tipo de patch no soportado"
+// @Failure 422 {object} map[string]string "El patch no se puede aplicar"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
+// @Router /api/v1/clients/{id} [patch]
+func PatchClient(c echo.Context) error {
+	id := c.Param("id")
+	var client models.Client
+	if err := config.DB.First(&client, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+
+	if status, err := checkIfMatch(c, client.Version); err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	current, err := json.Marshal(client)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	var patched []byte
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch mediaType {
+	case mimeMergePatch:
+		patched, err = applyMergePatch(current, patch)
+	case mimeJSONPatch:
+		patched, err = applyJSONPatch(current, patch)
+	default:
+		c.Response().Header().Set("Accept-Patch", mimeMergePatch+", "+mimeJSONPatch)
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be " + mimeMergePatch + " or " + mimeJSONPatch})
+	}
+	switch {
+	case errors.Is(err, errPatchInvalid):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, errPatchTestFailed):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	}
+
+	var updated models.Client
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&updated); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Patched client is invalid: " + err.Error()})
+	}
+	if updated.ID != client.ID {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Client id cannot be modified"})
+	}
+	// La edad derivada solo se valida si el patch la modificó
+	if updated.Age == client.Age {
+		updated.Age = 0
+	}
+	// La eliminación solo se gestiona con DELETE y restore, y la versión y las fechas las controla el servidor
+	updated.DeletedAt = client.DeletedAt
+	updated.Version = client.Version
+	updated.CreatedAt, updated.UpdatedAt = client.CreatedAt, client.UpdatedAt
+
+	if errs := models.ValidateClient(&updated); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Client{}, client.ID, client.Version); err != nil {
+			return err
+		}
+		updated.Version++
+		if err := tx.Save(&updated).Error; err != nil {
+			return err
+		}
+		return recordClientChange(c, tx, models.ActionUpdate, &client, &updated)
+	})
+	if err != nil {
+		return clientWriteFailed(c, err)
+	}
+	setETag(c, updated.Version)
+	return c.JSON(http.StatusOK, updated)
+}
+
 // DeleteClient elimina un cliente por ID
 // @Summary Eliminar cliente
-// @Description Elimina un cliente específico usando su ID
+// @Description Elimina lógicamente un cliente y sus mascotas y cancela sus turnos pendientes; puede restaurarse hasta que se purgue al vencer el período de retención
 // @Tags Clientes
 // @Param id path int true "ID del Cliente"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Success 204 "Cliente eliminado exitosamente"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/clients/{id} [delete]
 func DeleteClient(c echo.Context) error {
-	id := c.Param("id")
-	if err := config.DB.Delete(&models.Client{}, id).Error; err != nil {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	var client models.Client
+	if err := config.DB.First(&client, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	if status, err := checkIfMatch(c, client.Version); err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Client{}, client.ID, client.Version); err != nil {
+			return err
+		}
+		res := tx.Delete(&models.Client{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var deleted models.Client
+		if err := tx.Unscoped().First(&deleted, id).Error; err != nil {
+			return err
+		}
+		if err := models.DeleteClientPets(tx, &deleted); err != nil {
+			return err
+		}
+		if err := models.CancelClientAppointments(tx, deleted.ID, currentActor(c)); err != nil {
+			return err
+		}
+		return recordClientChange(c, tx, models.ActionDelete, &client, &deleted)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
 	}
+	if err != nil {
+		return clientWriteFailed(c, err)
+	}
 	return c.NoContent(http.StatusNoContent)
 }
 
-// GetClientKPI calcula el promedio de edad y la desviación estándar de edad de los clientes
-// @Summary KPI de clientes
-// @Description Calcula el promedio y la desviación estándar de edad de los clientes
+// RestoreClient restaura un cliente eliminado
+// @Summary Restaurar cliente
+// @Description Deshace la eliminación lógica de un cliente que todavía no fue purgado, junto con la de las mascotas que se eliminaron con él. Los turnos cancelados al eliminarlo siguen cancelados
 // @Tags Clientes
+// @Param id path int true "ID del Cliente"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
 // @Produce json
-// @Success 200 {object} ClientKPI "KPI de clientes calculado"
-// @Router /api/v1/clients/kpi [get]
-func GetClientKPI(c echo.Context) error {
-	var clients []struct {
-		BirthDay time.Time
-	}
-
-	if err := config.DB.Model(&models.Client{}).Select("birth_day").Find(&clients).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve clients"})
+// @Success 200 {object} models.Client "Cliente restaurado"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Failure 409 {object} map[string]string "El cliente no está eliminado"
+// @Failure 409 {object} map[string]string "Una mascota del cliente tiene un microchip que ya registró otra mascota"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 409 {object} map[string]string "Hay una petición en curso con la misma Idempotency-Key"
+// @Failure 422 {object} map[string]string "Idempotency-Key usada con otra petición"
+// @Router /api/v1/clients/{id}/restore [post]
+func RestoreClient(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
 	}
-
-	var ages []float64
-	currentYear := time.Now().Year()
-	for _, client := range clients {
-		age := float64(currentYear - client.BirthDay.Year())
-		ages = append(ages, age)
+	var client models.Client
+	if err := config.DB.Unscoped().First(&client, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
 	}
-
-	var sum float64
-	for _, age := range ages {
-		sum += age
+	if !client.DeletedAt.Valid {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Client is not deleted"})
 	}
-	averageAge := sum / float64(len(ages))
 
-	var varianceSum float64
-	for _, age := range ages {
-		varianceSum += math.Pow(age-averageAge, 2)
+	restored := client
+	restored.DeletedAt = gorm.DeletedAt{}
+	restored.Version++
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Client{}, client.ID, client.Version); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Client{}).Where("id = ?", client.ID).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := models.RestoreClientPets(tx, client.ID); err != nil {
+			return err
+		}
+		return recordClientChange(c, tx, models.ActionRestore, &client, &restored)
+	})
+	if errors.Is(err, models.ErrMicrochipTaken) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A pet of the client has a microchip that is already registered to another pet"})
 	}
-	ageStandardDeviation := math.Sqrt(varianceSum / float64(len(ages)))
-
-	kpi := ClientKPI{
-		AverageAge:           averageAge,
-		AgeStandardDeviation: ageStandardDeviation,
+	if err != nil {
+		return clientWriteFailed(c, err)
 	}
-	return c.JSON(http.StatusOK, kpi)
+	setETag(c, restored.Version)
+	return c.JSON(http.StatusOK, restored)
 }
 
-func isNumeric(s string) bool {
-	_, err := strconv.Atoi(s)
-	return err == nil
+// clientScope devuelve el query base de clientes, incluyendo los eliminados si se pide include_deleted=true
+func clientScope(c echo.Context) *gorm.DB {
+	db := config.DB.Model(&models.Client{})
+	if include, _ := strconv.ParseBool(c.QueryParam("include_deleted")); include {
+		db = db.Unscoped()
+	}
+	return db
 }
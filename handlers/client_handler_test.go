@@ -3,9 +3,12 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -21,89 +24,25 @@ import (
 
 func setupTestDB() {
 	var err error
-	config.DB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	config.DB, err = gorm.Open(sqlite.Open("file::memory:?cache=shared"), config.GormConfig())
 	if err != nil {
 		panic("failed to connect database")
 	}
 
-	config.DB.AutoMigrate(&models.Client{})
+	config.Migrate(config.DB)
+	// El historial es inmutable para GORM; se vacía para que los ids fijos de los tests no choquen
+	config.DB.Exec("DELETE FROM client_versions")
+	// Los tests insertan clientes directamente; los agregados del KPI se recalculan a partir de ellos
+	models.RebuildClientAgeStats(config.DB)
+	models.RebuildClientLoyaltyStats(config.DB)
 }
 
-func TestValidEmail(t *testing.T) {
-	tests := []struct {
-		name     string
-		email    string
-		expected bool
-	}{
-		{
-			name:     "Valid email",
-			email:    "test@test.com",
-			expected: true,
-		},
-		{
-			name:     "Valid email with subdomain",
-			email:    "test@sub.test.com",
-			expected: true,
-		},
-		{
-			name:     "Invalid email - no domain",
-			email:    "test@",
-			expected: false,
-		},
-		{
-			name:     "Invalid email - no @",
-			email:    "testtest.com",
-			expected: false,
-		},
-		{
-			name:     "Invalid email - empty",
-			email:    "",
-			expected: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := validEmail(tt.email)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestIsNumeric(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected bool
-	}{
-		{
-			name:     "Valid number",
-			input:    "1234567",
-			expected: true,
-		},
-		{
-			name:     "Invalid - contains letters",
-			input:    "123abc",
-			expected: false,
-		},
-		{
-			name:     "Invalid - empty",
-			input:    "",
-			expected: false,
-		},
-		{
-			name:     "Valid - single digit",
-			input:    "0",
-			expected: true,
-		},
-	}
+// testNow es la fecha fija con la que los tests calculan edades
+var testNow = time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isNumeric(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
+func TestMain(m *testing.M) {
+	models.Now = func() time.Time { return testNow }
+	os.Exit(m.Run())
 }
 
 func TestCreateClientValidation(t *testing.T) {
@@ -111,15 +50,17 @@ func TestCreateClientValidation(t *testing.T) {
 	tests := []struct {
 		name          string
 		client        models.Client
+		expectedField string
 		expectedError string
 	}{
 		{
 			name: "Missing required fields",
 			client: models.Client{
 				Email:     "test@test.com",
-				Telephone: "1234567",
+				Telephone: "600123456",
 			},
-			expectedError: "Name, Last Name, Email, Age, and Birth Day are required",
+			expectedField: "name",
+			expectedError: "Name is required",
 		},
 		{
 			name: "Invalid email",
@@ -128,9 +69,10 @@ func TestCreateClientValidation(t *testing.T) {
 				LastName:  "Doe",
 				Email:     "invalid-email",
 				Age:       30,
-				BirthDay:  time.Now().AddDate(-30, 0, 0),
-				Telephone: "1234567",
+				BirthDay:  testNow.AddDate(-30, 0, 0),
+				Telephone: "600123456",
 			},
+			expectedField: "email",
 			expectedError: "Invalid email format",
 		},
 		{
@@ -140,10 +82,11 @@ func TestCreateClientValidation(t *testing.T) {
 				LastName:  "Doe",
 				Email:     "test@test.com",
 				Age:       30,
-				BirthDay:  time.Now().AddDate(-30, 0, 0),
+				BirthDay:  testNow.AddDate(-30, 0, 0),
 				Telephone: "123",
 			},
-			expectedError: "Phone number must be numeric and at least 7 digits long",
+			expectedField: "telephone",
+			expectedError: "Invalid phone number",
 		},
 		{
 			name: "Age mismatch with birthday",
@@ -152,9 +95,10 @@ func TestCreateClientValidation(t *testing.T) {
 				LastName:  "Doe",
 				Email:     "test@test.com",
 				Age:       25,
-				BirthDay:  time.Now().AddDate(-30, 0, 0),
-				Telephone: "1234567",
+				BirthDay:  testNow.AddDate(-30, 0, 0),
+				Telephone: "600123456",
 			},
+			expectedField: "age",
 			expectedError: "Age does not match birth date",
 		},
 	}
@@ -172,10 +116,18 @@ func TestCreateClientValidation(t *testing.T) {
 			if assert.NoError(t, CreateClient(c)) {
 				assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-				var resp map[string]string
+				var resp ValidationErrorResponse
 				err := json.Unmarshal(rec.Body.Bytes(), &resp)
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expectedError, resp["error"])
+				assert.Equal(t, "Validation failed", resp.Error)
+
+				var messages []string
+				for _, fieldErr := range resp.Errors {
+					if fieldErr.Field == tt.expectedField {
+						messages = append(messages, fieldErr.Message)
+					}
+				}
+				assert.Equal(t, []string{tt.expectedError}, messages)
 			}
 		})
 	}
@@ -219,7 +171,7 @@ func TestGetAll(t *testing.T) {
 			Email:     "john.doe@example.com",
 			BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 			Age:       33,
-			Telephone: "123456789",
+			Telephone: "600123456",
 		},
 		{
 			ID:        2,
@@ -237,7 +189,7 @@ func TestGetAll(t *testing.T) {
 			Email:     "alice.johnson@example.com",
 			BirthDay:  time.Date(1995, time.March, 30, 0, 0, 0, 0, time.UTC),
 			Age:       29,
-			Telephone: "555555555",
+			Telephone: "655555555",
 		},
 	}
 
@@ -250,15 +202,22 @@ func TestGetAll(t *testing.T) {
 	if assert.NoError(t, err) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 
-		expectedResponse := `[
+		expectedResponse := `{
+			"data": [
 			{
 				"id": 1,
 				"name": "John",
 				"last_name": "Doe",
 				"email": "john.doe@example.com",
 				"birth_day": "1990-01-01T00:00:00Z",
-				"age": 33,
-				"telephone": "123456789"
+				"age": 34,
+				"telephone": "+34600123456",
+				"telephone_type": "mobile",
+				"telephone_display": "600123456",
+				"deleted_at": null,
+				"version": 1,
+				"created_at": "2024-06-15T12:00:00Z",
+				"updated_at": "2024-06-15T12:00:00Z"
 			},
 			{
 				"id": 2,
@@ -267,7 +226,13 @@ func TestGetAll(t *testing.T) {
 				"email": "jane.smith@example.com",
 				"birth_day": "1985-02-14T00:00:00Z",
 				"age": 39,
-				"telephone": "987654321"
+				"telephone": "+34987654321",
+				"telephone_type": "landline",
+				"telephone_display": "987654321",
+				"deleted_at": null,
+				"version": 1,
+				"created_at": "2024-06-15T12:00:00Z",
+				"updated_at": "2024-06-15T12:00:00Z"
 			},
 			{
 				"id": 3,
@@ -276,13 +241,23 @@ func TestGetAll(t *testing.T) {
 				"email": "alice.johnson@example.com",
 				"birth_day": "1995-03-30T00:00:00Z",
 				"age": 29,
-				"telephone": "555555555"
+				"telephone": "+34655555555",
+				"telephone_type": "mobile",
+				"telephone_display": "655555555",
+				"deleted_at": null,
+				"version": 1,
+				"created_at": "2024-06-15T12:00:00Z",
+				"updated_at": "2024-06-15T12:00:00Z"
 			}
-		]`
+			],
+			"total": 3,
+			"limit": 50,
+			"offset": 0
+		}`
 		assert.JSONEq(t, expectedResponse, rec.Body.String())
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestGetClient(t *testing.T) {
@@ -302,7 +277,7 @@ func TestGetClient(t *testing.T) {
 		Email:     "john.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       33,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 
 	config.DB.Create(&testClient)
@@ -318,13 +293,19 @@ func TestGetClient(t *testing.T) {
 			"last_name": "Doe",
 			"email": "john.doe@example.com",
 			"birth_day": "1990-01-01T00:00:00Z",
-			"age": 33,
-			"telephone": "123456789"
+			"age": 34,
+			"telephone": "+34600123456",
+			"telephone_type": "mobile",
+			"telephone_display": "600123456",
+			"deleted_at": null,
+			"version": 1,
+			"created_at": "2024-06-15T12:00:00Z",
+			"updated_at": "2024-06-15T12:00:00Z"
 		}`
 		assert.JSONEq(t, expectedResponse, rec.Body.String())
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestGetClientNotFound(t *testing.T) {
@@ -361,7 +342,7 @@ func TestUpdateClient(t *testing.T) {
 		Email:     "john.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       33,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 
 	config.DB.Create(&originalClient)
@@ -394,12 +375,44 @@ func TestUpdateClient(t *testing.T) {
 			"email": "johnny.smith@example.com",
 			"birth_day": "1990-01-01T00:00:00Z",
 			"age": 34,
-			"telephone": "987654321"
+			"telephone": "+34987654321",
+			"telephone_type": "landline",
+			"telephone_display": "987654321",
+			"deleted_at": null,
+			"version": 2,
+			"created_at": "2024-06-15T12:00:00Z",
+			"updated_at": "2024-06-15T12:00:00Z"
 		}`
 		assert.JSONEq(t, expectedResponse, rec.Body.String())
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+}
+
+func TestUpdateClientIgnoresBodyID(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	target := createTestOwner(t, "john.doe@example.com")
+	other := createTestOwner(t, "jane.doe@example.com")
+
+	e := echo.New()
+	body := fmt.Sprintf(`{"id": %d, "name": "Johnny", "last_name": "Doe", "email": "johnny@example.com", "birth_day": "1990-01-01T00:00:00Z", "telephone": "600123456"}`, other.ID)
+	req := httptest.NewRequest(http.MethodPut, "/clients/"+strconv.Itoa(target.ID), strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(target.ID))
+
+	if assert.NoError(t, UpdateClient(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	// El id de la ruta manda sobre el del cuerpo
+	var updated, untouched models.Client
+	config.DB.First(&updated, target.ID)
+	config.DB.First(&untouched, other.ID)
+	assert.Equal(t, "Johnny", updated.Name)
+	assert.Equal(t, "jane.doe@example.com", untouched.Email)
 }
 
 func TestDeleteClient(t *testing.T) {
@@ -414,7 +427,7 @@ func TestDeleteClient(t *testing.T) {
 		Email:     "john.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       33,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 
 	config.DB.Create(&testClient)
@@ -436,7 +449,7 @@ func TestDeleteClient(t *testing.T) {
 		assert.Error(t, err)
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestCreateClientIntegration(t *testing.T) {
@@ -449,7 +462,7 @@ func TestCreateClientIntegration(t *testing.T) {
 		Email:     "juan.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       34,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 
 	reqBody := fmt.Sprintf(`{
@@ -476,7 +489,7 @@ func TestCreateClientIntegration(t *testing.T) {
 		assert.Equal(t, client.Email, createdClient.Email)
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestUpdateClientIntegration(t *testing.T) {
@@ -490,7 +503,7 @@ func TestUpdateClientIntegration(t *testing.T) {
 		Email:     "john.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       33,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 
 	config.DB.Create(&client)
@@ -527,7 +540,7 @@ func TestUpdateClientIntegration(t *testing.T) {
 		assert.Equal(t, updatedClient.Email, clientInDB.Email)
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestDeleteClientIntegration(t *testing.T) {
@@ -539,7 +552,7 @@ func TestDeleteClientIntegration(t *testing.T) {
 		Email:     "juan.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       34,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 	result := config.DB.Create(&client)
 	if result.Error != nil {
@@ -564,7 +577,7 @@ func TestDeleteClientIntegration(t *testing.T) {
 		assert.Error(t, result.Error, "Se esperaba que el cliente fuera eliminado")
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestGetAllIntegration(t *testing.T) {
@@ -577,7 +590,7 @@ func TestGetAllIntegration(t *testing.T) {
 			Email:     "john.doe@example.com",
 			BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 			Age:       33,
-			Telephone: "123456789",
+			Telephone: "600123456",
 		},
 		{
 			Name:      "Jane",
@@ -593,7 +606,7 @@ func TestGetAllIntegration(t *testing.T) {
 			Email:     "alice.johnson@example.com",
 			BirthDay:  time.Date(1995, time.March, 30, 0, 0, 0, 0, time.UTC),
 			Age:       29,
-			Telephone: "555555555",
+			Telephone: "655555555",
 		},
 	}
 
@@ -611,12 +624,12 @@ func TestGetAllIntegration(t *testing.T) {
 	if assert.NoError(t, err) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 
-		var returnedClients []models.Client
-		json.Unmarshal(rec.Body.Bytes(), &returnedClients)
-		assert.Greater(t, len(returnedClients), 1, "Se esperaba que el número de clientes devueltos sea mayor a 1")
+		var page ClientPage
+		json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Greater(t, len(page.Data), 1, "Se esperaba que el número de clientes devueltos sea mayor a 1")
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestGetClientIntegration(t *testing.T) {
@@ -629,7 +642,7 @@ func TestGetClientIntegration(t *testing.T) {
 		Email:     "john.doe@example.com",
 		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
 		Age:       33,
-		Telephone: "123456789",
+		Telephone: "600123456",
 	}
 	config.DB.Create(&testClient)
 
@@ -652,13 +665,19 @@ func TestGetClientIntegration(t *testing.T) {
 			"last_name": "Doe",
 			"email": "john.doe@example.com",
 			"birth_day": "1990-01-01T00:00:00Z",
-			"age": 33,
-			"telephone": "123456789"
+			"age": 34,
+			"telephone": "+34600123456",
+			"telephone_type": "mobile",
+			"telephone_display": "600123456",
+			"deleted_at": null,
+			"version": 1,
+			"created_at": "2024-06-15T12:00:00Z",
+			"updated_at": "2024-06-15T12:00:00Z"
 		}`
 		assert.JSONEq(t, expectedResponse, rec.Body.String())
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 func TestGetClientNotFoundIntegration(t *testing.T) {
 	setupTestDB()
@@ -682,7 +701,7 @@ func TestGetClientNotFoundIntegration(t *testing.T) {
 		assert.Equal(t, "Client not found", jsonErr["error"])
 	}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
 }
 
 func TestCalculateClientKPIIntegration(t *testing.T) {
@@ -694,17 +713,15 @@ func TestCalculateClientKPIIntegration(t *testing.T) {
 			Name:      "John",
 			LastName:  "Doe",
 			Email:     "john.doe@example.com",
-			BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
-			Age:       20,
-			Telephone: "123456789",
+			BirthDay:  time.Date(2004, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Telephone: "600123456",
 		},
 		{
 			ID:        2,
 			Name:      "Jane",
 			LastName:  "Smith",
 			Email:     "jane.smith@example.com",
-			BirthDay:  time.Date(1985, time.February, 14, 0, 0, 0, 0, time.UTC),
-			Age:       30,
+			BirthDay:  time.Date(1994, time.June, 15, 0, 0, 0, 0, time.UTC),
 			Telephone: "987654321",
 		},
 		{
@@ -712,36 +729,164 @@ func TestCalculateClientKPIIntegration(t *testing.T) {
 			Name:      "Alice",
 			LastName:  "Johnson",
 			Email:     "alice.johnson@example.com",
-			BirthDay:  time.Date(1995, time.March, 30, 0, 0, 0, 0, time.UTC),
-			Age:       40,
-			Telephone: "555555555",
+			BirthDay:  time.Date(1984, time.June, 16, 0, 0, 0, 0, time.UTC),
+			Telephone: "655555555",
 		},
 	}
 
 	for _, client := range clients {
 		config.DB.Create(&client)
 	}
+	models.RebuildClientAgeStats(config.DB)
 
-	var ages []float64
-	config.DB.Model(&models.Client{}).Pluck("age", &ages)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/clients/kpi", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
 
-	var sum float64
-	for _, age := range ages {
-		sum += age
+	err := GetClientKPI(c)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Edades al 15/06/2024: 20, 30 (cumple ese día) y 39 (cumple al día siguiente)
+		var kpi ClientKPI
+		json.Unmarshal(rec.Body.Bytes(), &kpi)
+		assert.InDelta(t, float64(89)/3, *kpi.AverageAge, 0.000001)
+		assert.InDelta(t, 7.760297, *kpi.AgeStandardDeviation, 0.000001)
 	}
-	expectedAverage := sum / float64(len(ages))
 
-	var varianceSum float64
-	for _, age := range ages {
-		varianceSum += (age - expectedAverage) * (age - expectedAverage)
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+}
+
+func TestDeleteClientNotFound(t *testing.T) {
+	setupTestDB()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/clients/999", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("999")
+
+	if assert.NoError(t, DeleteClient(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
 	}
-	expectedStdDev := float64(0)
-	if len(ages) > 0 {
-		expectedStdDev = float64(varianceSum / float64(len(ages)))
+}
+
+func TestSoftDeleteAndRestoreClient(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	client := models.Client{
+		Name:      "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+		BirthDay:  time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Telephone: "600123456",
 	}
+	config.DB.Create(&client)
+	id := fmt.Sprintf("%d", client.ID)
 
-	assert.Equal(t, float64(30), expectedAverage)
-	assert.InDelta(t, float64(66.66666666666667), expectedStdDev, 0.000001)
+	call := func(method, target string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, target, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		assert.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(http.MethodPost, "/clients/"+id+"/restore", RestoreClient)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = call(http.MethodDelete, "/clients/"+id, DeleteClient)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	var deleted models.Client
+	assert.NoError(t, config.DB.Unscoped().First(&deleted, client.ID).Error, "El cliente debe conservarse eliminado lógicamente")
+	assert.True(t, deleted.DeletedAt.Valid)
+
+	rec = call(http.MethodGet, "/clients/"+id, GetClient)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = call(http.MethodGet, "/clients/"+id+"?include_deleted=true", GetClient)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, page := listClients(t, "")
+	assert.Empty(t, page.Data)
+	_, page = listClients(t, "include_deleted=true")
+	assert.Equal(t, []string{"John"}, clientNames(page))
+
+	rec = call(http.MethodDelete, "/clients/"+id, DeleteClient)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "Eliminar dos veces debe devolver 404")
+
+	rec = call(http.MethodPost, "/clients/"+id+"/restore", RestoreClient)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"deleted_at":null`)
+
+	rec = call(http.MethodGet, "/clients/"+id, GetClient)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestClientTelephoneNormalization(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	call := func(method, target, body string, handler echo.HandlerFunc, id string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if id != "" {
+			c.SetParamNames("id")
+			c.SetParamValues(id)
+		}
+		assert.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(http.MethodPost, "/clients", `{"name":"Ana","last_name":"Silva","email":"ana.silva@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"+351 912 345 678"}`, CreateClient, "")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var client models.Client
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &client))
+	assert.Equal(t, "+351912345678", client.Telephone)
+	assert.Equal(t, models.PhoneMobile, client.TelephoneType)
+	assert.Equal(t, "+351 912 345 678", client.TelephoneDisplay)
+	id := fmt.Sprintf("%d", client.ID)
+
+	// El mismo número escrito en E.164 no pierde la forma original
+	rec = call(http.MethodPut, "/clients/"+id, `{"name":"Ana","last_name":"Silva","email":"ana.silva@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"+351912345678"}`, UpdateClient, id)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"telephone_display":"+351 912 345 678"`)
+
+	rec = call(http.MethodPut, "/clients/"+id, `{"name":"Ana","last_name":"Silva","email":"ana.silva@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"0000000"}`, UpdateClient, id)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid phone number")
+}
+
+func TestCreateClientEmailCaseConflict(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	config.DB.Create(&models.Client{Name: "John", LastName: "Doe", Email: "john.doe@example.com", BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader(`{"name":"Johnny","last_name":"Doe","email":"John.Doe@Example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"600123457"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	assert.NoError(t, CreateClient(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Email is already in use")
+}
 
-	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Client{})
+func TestIsEmailTaken(t *testing.T) {
+	assert.True(t, isEmailTaken(errors.New("UNIQUE constraint failed: clients.email")))
+	assert.True(t, isEmailTaken(errors.New("UNIQUE constraint failed: clients.email_key")))
+	assert.False(t, isEmailTaken(errors.New("UNIQUE constraint failed: clients.email_backup")))
+	assert.False(t, isEmailTaken(errors.New("UNIQUE constraint failed: pets.microchip")))
+	assert.False(t, isEmailTaken(errors.New("NOT NULL constraint failed: clients.email")))
 }
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// currentActor devuelve el usuario autenticado que realiza la petición
+func currentActor(c echo.Context) string {
+	if username, ok := c.Get("username").(string); ok && username != "" {
+		return username
+	}
+	return "anonymous"
+}
+
+// recordClientChange registra dentro de tx la versión after del cliente con el diff respecto de before
+// y actualiza con el cambio los agregados de edades, compras y puntos del KPI
+func recordClientChange(c echo.Context, tx *gorm.DB, action string, before, after *models.Client) error {
+	version := models.ClientVersion{
+		ClientID: after.ID,
+		Version:  after.Version,
+		Action:   action,
+		Actor:    currentActor(c),
+		Changes:  models.DiffClients(before, after),
+		Client:   *after,
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return err
+	}
+	if err := models.ApplyClientAgeChange(tx, before, after); err != nil {
+		return err
+	}
+	return models.ApplyClientLoyaltyChange(tx, before, after)
+}
+
+// GetClientHistory lista las versiones de un cliente
+// @Summary Historial de un cliente
+// @Description Devuelve las versiones registradas de un cliente, con el usuario, la fecha y los cambios de cada una
+// @Tags Clientes
+// @Param id path int true "ID del Cliente"
+// @Produce json
+// @Success 200 {array} models.ClientVersion "Versiones del cliente"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/history [get]
+func GetClientHistory(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+
+	versions := []models.ClientVersion{}
+	if err := config.DB.Where("client_id = ?", id).Order("version").Find(&versions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if len(versions) == 0 {
+		var count int64
+		config.DB.Unscoped().Model(&models.Client{}).Where("id = ?", id).Count(&count)
+		if count == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+		}
+	}
+	return c.JSON(http.StatusOK, versions)
+}
+
+// RevertClient restaura los datos de un cliente a los de una versión anterior
+// @Summary Revertir cliente a una versión
+// @Description Vuelve los datos del cliente a los registrados en la versión indicada y registra una nueva versión. El estado de eliminación no se modifica
+// @Tags Clientes
+// @Param id path int true "ID del Cliente"
+// @Param version path int true "Número de versión"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Produce json
+// @Success 200 {object} models.Client "Cliente revertido"
+// @Failure 400 {object} ValidationErrorResponse "La versión ya no es válida"
+// @Failure 404 {object} map[string]string "Cliente o versión no encontrados"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 409 {object} map[string]string "Hay una petición en curso con la misma Idempotency-Key"
+// @Failure 422 {object} map[string]string "Idempotency-Key usada con otra petición"
+// @Router /api/v1/clients/{id}/revert/{version} [post]
+func RevertClient(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Version not found"})
+	}
+
+	var client models.Client
+	if err := config.DB.Unscoped().First(&client, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	var version models.ClientVersion
+	if err := config.DB.Where("client_id = ? AND version = ?", id, number).First(&version).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Version not found"})
+	}
+
+	reverted := version.Client
+	reverted.ID = client.ID
+	reverted.Age = 0
+	reverted.DeletedAt = client.DeletedAt
+	reverted.Version = client.Version + 1
+	reverted.CreatedAt, reverted.UpdatedAt = client.CreatedAt, client.UpdatedAt
+	if errs := models.ValidateClient(&reverted); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.Client{}, client.ID, client.Version); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Save(&reverted).Error; err != nil {
+			return err
+		}
+		return recordClientChange(c, tx, models.ActionRevert, &client, &reverted)
+	})
+	if err != nil {
+		return clientWriteFailed(c, err)
+	}
+	setETag(c, reverted.Version)
+	return c.JSON(http.StatusOK, reverted)
+}
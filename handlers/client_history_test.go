@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestClientHistoryAndRevert(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	call := func(method, target, body string, handler echo.HandlerFunc, names []string, values ...string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("username", "admin")
+		c.SetParamNames(names...)
+		c.SetParamValues(values...)
+		assert.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(http.MethodPost, "/clients", `{"name":"John","last_name":"Doe","email":"john.history@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"600123456"}`, CreateClient, nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var client models.Client
+	json.Unmarshal(rec.Body.Bytes(), &client)
+	id := fmt.Sprintf("%d", client.ID)
+
+	rec = call(http.MethodPut, "/clients/"+id, `{"name":"Johnny","last_name":"Doe","email":"john.history@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"600123456"}`, UpdateClient, []string{"id"}, id)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = call(http.MethodDelete, "/clients/"+id, "", DeleteClient, []string{"id"}, id)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = call(http.MethodGet, "/clients/"+id+"/history", "", GetClientHistory, []string{"id"}, id)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var versions []models.ClientVersion
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &versions))
+	if assert.Len(t, versions, 3) {
+		assert.Equal(t, []string{models.ActionCreate, models.ActionUpdate, models.ActionDelete},
+			[]string{versions[0].Action, versions[1].Action, versions[2].Action})
+		assert.Equal(t, "admin", versions[1].Actor)
+		assert.Equal(t, models.FieldChange{From: "John", To: "Johnny"}, versions[1].Changes["name"])
+		assert.Len(t, versions[1].Changes, 1)
+		assert.Contains(t, versions[2].Changes, "deleted_at")
+	}
+
+	// Revertir a la versión 1 devuelve el nombre original sin restaurar el cliente
+	rec = call(http.MethodPost, "/clients/"+id+"/revert/1", "", RevertClient, []string{"id", "version"}, id, "1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var reverted models.Client
+	config.DB.Unscoped().First(&reverted, client.ID)
+	assert.Equal(t, "John", reverted.Name)
+	assert.True(t, reverted.DeletedAt.Valid)
+
+	rec = call(http.MethodGet, "/clients/"+id+"/history", "", GetClientHistory, []string{"id"}, id)
+	json.Unmarshal(rec.Body.Bytes(), &versions)
+	if assert.Len(t, versions, 4) {
+		assert.Equal(t, models.ActionRevert, versions[3].Action)
+		assert.Equal(t, models.FieldChange{From: "Johnny", To: "John"}, versions[3].Changes["name"])
+	}
+
+	rec = call(http.MethodPost, "/clients/"+id+"/revert/99", "", RevertClient, []string{"id", "version"}, id, "99")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = call(http.MethodGet, "/clients/999/history", "", GetClientHistory, []string{"id"}, "999")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Las versiones registradas no se pueden modificar ni borrar
+	assert.ErrorIs(t, config.DB.Model(&versions[0]).Update("actor", "someone").Error, models.ErrImmutableVersion)
+	assert.ErrorIs(t, config.DB.Delete(&versions[0]).Error, models.ErrImmutableVersion)
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Modos de confirmación de una importación
+const (
+	importAllOrNothing = "all_or_nothing"
+	importPartial      = "partial"
+)
+
+// Resultado de cada fila importada
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportRejected  = "rejected"
+)
+
+// importFields son los campos de un cliente que se pueden importar
+var importFields = []string{"name", "last_name", "email", "birth_day", "age", "telephone"}
+
+var (
+	// errImportRejected revierte una importación all_or_nothing con filas rechazadas
+	errImportRejected = errors.New("import has rejected rows")
+	// errImportDryRun revierte una importación de prueba
+	errImportDryRun = errors.New("dry run")
+)
+
+// codeImportFailed indica una fila válida que no se pudo guardar
+const codeImportFailed = "write_failed"
+
+// ClientImportRow es el resultado de una fila de la importación
+type ClientImportRow struct {
+	Line     int                     `json:"line"`
+	Status   string                  `json:"status"`
+	ClientID int                     `json:"client_id,omitempty"`
+	Email    string                  `json:"email,omitempty"`
+	Errors   models.ValidationErrors `json:"errors,omitempty"`
+}
+
+// ClientImportSummary cuenta las filas por resultado
+type ClientImportSummary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Rejected  int `json:"rejected"`
+}
+
+// ClientImportReport es la respuesta de una importación de clientes
+type ClientImportReport struct {
+	Mode      string              `json:"mode"`
+	DryRun    bool                `json:"dry_run"`
+	Committed bool                `json:"committed"`
+	Summary   ClientImportSummary `json:"summary"`
+	Rows      []ClientImportRow   `json:"rows"`
+}
+
+// importRecord es una fila leída del archivo, con los valores por nombre de campo
+type importRecord struct {
+	Line   int
+	Fields map[string]string
+}
+
+// ImportClients da de alta o actualiza clientes en bloque
+// @Summary Importar clientes
+// @Description Importa clientes desde CSV (text/csv) o NDJSON (application/x-ndjson), enviados como cuerpo o subidos en el campo file de un formulario multipart; el formato del archivo subido sale de su tipo o de su extensión (.csv, .ndjson o .jsonl). Cada fila se valida igual que un alta; si el email ya existe se actualiza el cliente. En modo all_or_nothing una fila rechazada descarta toda la importación; en modo partial se guardan las filas válidas. Con dry_run=true se informa el resultado sin guardar nada
+// @Tags Clientes
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept multipart/form-data
+// @Produce json
+// @Param mode query string false "all_or_nothing (por defecto) o partial"
+// @Param dry_run query bool false "Validar sin guardar"
+// @Param columns query string false "Mapeo de columnas CSV como campo:cabecera separados por coma, p. ej. name:Nombre,last_name:Apellido"
+// @Param delimiter query string false "Separador de columnas CSV (por defecto ,)"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 200 {object} ClientImportReport "Resultado por fila"
+// @Failure 400 {object} map[string]string "Archivo o parámetros inválidos"
+// @Failure 415 {object} map[string]string "Formato no soportado"
+// @Failure 422 {object} ClientImportReport "Importación all_or_nothing descartada por filas rechazadas"
+// @Router /api/v1/clients/import [post]
+func ImportClients(c echo.Context) error {
+	mode := c.QueryParam("mode")
+	if mode == "" {
+		mode = importAllOrNothing
+	}
+	if mode != importAllOrNothing && mode != importPartial {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "mode must be all_or_nothing or partial"})
+	}
+	dryRun := false
+	if v := c.QueryParam("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "dry_run must be a boolean"})
+		}
+	}
+
+	var records []importRecord
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	body := io.Reader(c.Request().Body)
+	if mediaType == echo.MIMEMultipartForm {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "file is required"})
+		}
+		file, err := header.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		defer file.Close()
+		body, mediaType = file, importFileType(header)
+	}
+	switch mediaType {
+	case "text/csv":
+		records, err = readImportCSV(c, body)
+	case "application/x-ndjson", "application/ndjson":
+		records, err = readImportNDJSON(body)
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be text/csv, application/x-ndjson or multipart/form-data with a CSV or NDJSON file"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	report := ClientImportReport{Mode: mode, DryRun: dryRun, Rows: make([]ClientImportRow, 0, len(records))}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			row := importClient(c, tx, record, mode == importPartial)
+			report.Rows = append(report.Rows, row)
+			switch row.Status {
+			case ImportCreated:
+				report.Summary.Created++
+			case ImportUpdated:
+				report.Summary.Updated++
+			case ImportUnchanged:
+				report.Summary.Unchanged++
+			case ImportRejected:
+				report.Summary.Rejected++
+			}
+		}
+		if mode == importAllOrNothing && report.Summary.Rejected > 0 {
+			return errImportRejected
+		}
+		if dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errImportRejected):
+		if dryRun {
+			return c.JSON(http.StatusOK, report)
+		}
+		return c.JSON(http.StatusUnprocessableEntity, report)
+	case errors.Is(err, errImportDryRun):
+		return c.JSON(http.StatusOK, report)
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	report.Committed = true
+	return c.JSON(http.StatusOK, report)
+}
+
+// importClient valida una fila y crea o actualiza el cliente con su email.
+// En modo parcial cada fila usa su propio savepoint para que un error no arrastre a las demás
+func importClient(c echo.Context, tx *gorm.DB, record importRecord, partial bool) ClientImportRow {
+	row := ClientImportRow{Line: record.Line, Email: record.Fields["email"]}
+	client, errs := clientFromImport(record.Fields)
+	if len(errs) == 0 {
+		errs = models.ValidateClient(&client)
+	}
+	if len(errs) > 0 {
+		row.Status, row.Errors = ImportRejected, errs
+		return row
+	}
+
+	write := func(tx *gorm.DB) error {
+		var existing models.Client
+		err := tx.Unscoped().Where("email_key = ?", client.EmailKey).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&client).Error; err != nil {
+				return err
+			}
+			row.Status, row.ClientID = ImportCreated, client.ID
+			return recordClientChange(c, tx, models.ActionCreate, nil, &client)
+		case err != nil:
+			return err
+		case existing.DeletedAt.Valid:
+			row.Status = ImportRejected
+			row.Errors = models.ValidationErrors{{Field: "email", Code: models.CodeDeleted, Message: "Client with this email is deleted; restore it first"}}
+			return nil
+		}
+
+		row.ClientID = existing.ID
+		client.ID, client.Version, client.Age = existing.ID, existing.Version, 0
+		client.CreatedAt, client.UpdatedAt = existing.CreatedAt, existing.UpdatedAt
+		if len(models.DiffClients(&existing, &client)) == 0 {
+			row.Status = ImportUnchanged
+			return nil
+		}
+		if err := claimVersion(tx, &models.Client{}, existing.ID, existing.Version); err != nil {
+			return err
+		}
+		client.Version++
+		if err := tx.Save(&client).Error; err != nil {
+			return err
+		}
+		row.Status = ImportUpdated
+		return recordClientChange(c, tx, models.ActionUpdate, &existing, &client)
+	}
+
+	var err error
+	if partial {
+		err = tx.Transaction(write)
+	} else {
+		err = write(tx)
+	}
+	if err != nil {
+		row.Status, row.ClientID = ImportRejected, 0
+		row.Errors = models.ValidationErrors{{Field: "", Code: codeImportFailed, Message: err.Error()}}
+	}
+	return row
+}
+
+// clientFromImport arma un cliente con los valores de una fila; las fechas aceptan YYYY-MM-DD o RFC 3339
+func clientFromImport(fields map[string]string) (models.Client, models.ValidationErrors) {
+	var errs models.ValidationErrors
+	client := models.Client{
+		Name:      fields["name"],
+		LastName:  fields["last_name"],
+		Email:     fields["email"],
+		Telephone: fields["telephone"],
+	}
+	if raw := fields["birth_day"]; raw != "" {
+		bd, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			bd, err = time.Parse(time.RFC3339, raw)
+		}
+		if err != nil {
+			errs = append(errs, models.FieldError{Field: "birth_day", Code: models.CodeInvalidFormat, Message: "Birth Day must be a date in YYYY-MM-DD format"})
+		}
+		client.BirthDay = bd
+	}
+	if raw := fields["age"]; raw != "" {
+		age, err := strconv.Atoi(raw)
+		if err != nil {
+			errs = append(errs, models.FieldError{Field: "age", Code: models.CodeInvalidFormat, Message: "Age must be an integer"})
+		}
+		client.Age = age
+	}
+	return client, errs
+}
+
+// importFileType devuelve el formato de un archivo subido por su tipo o, si el navegador no lo indica, por su
+// extensión
+func importFileType(header *multipart.FileHeader) string {
+	if mediaType, _, err := mime.ParseMediaType(header.Header.Get(echo.HeaderContentType)); err == nil && mediaType != echo.MIMEOctetStream {
+		return mediaType
+	}
+	switch strings.ToLower(path.Ext(header.Filename)) {
+	case ".csv":
+		return "text/csv"
+	case ".ndjson", ".jsonl":
+		return "application/x-ndjson"
+	}
+	return ""
+}
+
+// readImportCSV lee un CSV con cabecera. Por defecto las columnas se llaman como los campos del cliente;
+// el parámetro columns permite mapear cada campo a otra cabecera
+func readImportCSV(c echo.Context, body io.Reader) ([]importRecord, error) {
+	headers := map[string]string{}
+	for _, field := range importFields {
+		headers[field] = field
+	}
+	if raw := c.QueryParam("columns"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			field, header, ok := strings.Cut(pair, ":")
+			field = strings.TrimSpace(field)
+			if _, known := headers[field]; !ok || !known {
+				return nil, fmt.Errorf("invalid column mapping %q", pair)
+			}
+			headers[field] = strings.TrimSpace(header)
+		}
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	if d := c.QueryParam("delimiter"); d != "" {
+		if len([]rune(d)) != 1 {
+			return nil, errors.New("delimiter must be a single character")
+		}
+		reader.Comma = []rune(d)[0]
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+	positions := map[string]int{}
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	columns := map[string]int{}
+	for field, name := range headers {
+		if i, ok := positions[strings.ToLower(name)]; ok {
+			columns[field] = i
+		} else if field != "age" {
+			return nil, fmt.Errorf("missing CSV column %q for %s", name, field)
+		}
+	}
+
+	var records []importRecord
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		fields := map[string]string{}
+		for field, i := range columns {
+			if i < len(values) {
+				fields[field] = strings.TrimSpace(values[i])
+			}
+		}
+		records = append(records, importRecord{Line: line, Fields: fields})
+	}
+}
+
+// readImportNDJSON lee un objeto JSON por línea, ignorando las líneas vacías
+func readImportNDJSON(body io.Reader) ([]importRecord, error) {
+	var records []importRecord
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var doc map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %v", line, err)
+		}
+		fields := map[string]string{}
+		for _, field := range importFields {
+			switch v := doc[field].(type) {
+			case nil:
+			case string:
+				fields[field] = strings.TrimSpace(v)
+			default:
+				fields[field] = fmt.Sprint(v)
+			}
+		}
+		records = append(records, importRecord{Line: line, Fields: fields})
+	}
+	return records, scanner.Err()
+}
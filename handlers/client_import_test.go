@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/middlewares"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func importClients(t *testing.T, query, contentType, body string) (*httptest.ResponseRecorder, ClientImportReport) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/import?"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var report ClientImportReport
+	if assert.NoError(t, ImportClients(c)) && rec.Code != http.StatusBadRequest && rec.Code != http.StatusUnsupportedMediaType {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	}
+	return rec, report
+}
+
+func importStatuses(report ClientImportReport) []string {
+	statuses := []string{}
+	for _, row := range report.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	return statuses
+}
+
+func TestImportClientsCSV(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	config.DB.Create(&models.Client{Name: "John", LastName: "Doe", Email: "john.doe@example.com", BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"})
+
+	csv := "Nombre;Apellido;Correo;Nacimiento;telephone\n" +
+		"Johnny;Doe;john.doe@example.com;1990-01-01;600123456\n" +
+		"Jane;Smith;jane@example.com;1985-02-14;911234567\n" +
+		"Bad;;not-an-email;1985-13-01;12\n"
+	query := "mode=partial&delimiter=%3B&columns=name:Nombre,last_name:Apellido,email:Correo,birth_day:Nacimiento"
+
+	rec, report := importClients(t, query+"&dry_run=true", "text/csv", csv)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{ImportUpdated, ImportCreated, ImportRejected}, importStatuses(report))
+	assert.False(t, report.Committed)
+	var count int64
+	config.DB.Model(&models.Client{}).Count(&count)
+	assert.Equal(t, int64(1), count, "Un dry run no debe guardar nada")
+
+	rec, report = importClients(t, query, "text/csv", csv)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, report.Committed)
+	assert.Equal(t, ClientImportSummary{Created: 1, Updated: 1, Rejected: 1}, report.Summary)
+	assert.Equal(t, []int{2, 3, 4}, []int{report.Rows[0].Line, report.Rows[1].Line, report.Rows[2].Line})
+	assert.Equal(t, "birth_day", report.Rows[2].Errors[0].Field)
+
+	var john models.Client
+	config.DB.Where("email = ?", "john.doe@example.com").First(&john)
+	assert.Equal(t, "Johnny", john.Name)
+	assert.Equal(t, 2, john.Version)
+
+	// Reimportar los mismos datos no cambia nada
+	_, report = importClients(t, query, "text/csv", csv)
+	assert.Equal(t, []string{ImportUnchanged, ImportUnchanged, ImportRejected}, importStatuses(report))
+
+	rec, _ = importClients(t, "columns=name:Nombre", "text/csv", csv)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestImportClientsNDJSONAllOrNothing(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	ndjson := `{"name":"Ana","last_name":"Garcia","email":"ana@example.com","birth_day":"1992-05-10","telephone":"600000001"}
+
+{"name":"Luis","last_name":"Perez","email":"luis@example.com","birth_day":"1988-07-01T00:00:00Z","telephone":600000002,"age":99}
+`
+	rec, report := importClients(t, "", "application/x-ndjson", ndjson)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.False(t, report.Committed)
+	assert.Equal(t, []string{ImportCreated, ImportRejected}, importStatuses(report))
+	assert.Equal(t, 3, report.Rows[1].Line)
+	assert.Equal(t, models.CodeAgeMismatch, report.Rows[1].Errors[0].Code)
+
+	var count int64
+	config.DB.Model(&models.Client{}).Count(&count)
+	assert.Equal(t, int64(0), count, "Una fila rechazada descarta toda la importación")
+
+	rec, report = importClients(t, "", "application/x-ndjson", strings.Replace(ndjson, `,"age":99`, "", 1))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ClientImportSummary{Created: 2}, report.Summary)
+
+	var versions int64
+	config.DB.Model(&models.ClientVersion{}).Where("client_id = ?", report.Rows[0].ClientID).Count(&versions)
+	assert.Equal(t, int64(1), versions)
+
+	rec, _ = importClients(t, "", echo.MIMEApplicationJSON, ndjson)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestImportClientsMatchesEmailKey(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	config.DB.Create(&models.Client{Name: "John", LastName: "Doe", Email: "john.doe@example.com", BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"})
+
+	ndjson := `{"name":"Johnny","last_name":"Doe","email":"John.Doe@EXAMPLE.com","birth_day":"1990-01-01","telephone":"600123456"}
+{"name":"Temp","last_name":"User","email":"temp@mailinator.com","birth_day":"1990-01-01","telephone":"600123457"}
+`
+	rec, report := importClients(t, "mode=partial", "application/x-ndjson", ndjson)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{ImportUpdated, ImportRejected}, importStatuses(report))
+	assert.Equal(t, models.CodeDisposable, report.Rows[1].Errors[0].Code)
+
+	var clients []models.Client
+	config.DB.Find(&clients)
+	if assert.Len(t, clients, 1) {
+		assert.Equal(t, "Johnny", clients[0].Name)
+		assert.Equal(t, "John.Doe@example.com", clients[0].Email)
+	}
+}
+
+// importUpload arma un formulario multipart con el archivo filename en el campo file
+func importUpload(t *testing.T, filename, content string) (string, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	part.Write([]byte(content))
+	assert.NoError(t, form.Close())
+	return form.FormDataContentType(), body.String()
+}
+
+func TestImportClientsUpload(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	// El navegador sube el archivo como application/octet-stream: el formato sale de la extensión
+	contentType, body := importUpload(t, "clientes.csv", "name;last_name;email;birth_day;telephone\nJane;Smith;jane@example.com;1985-02-14;911234567\n")
+	rec, report := importClients(t, "delimiter=%3B", contentType, body)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, ClientImportSummary{Created: 1}, report.Summary)
+
+	contentType, body = importUpload(t, "clientes.xlsx", "PK")
+	rec, _ = importClients(t, "", contentType, body)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	rec, _ = importClients(t, "", echo.MIMEMultipartForm+"; boundary=x", "--x--\r\n")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestImportClientsUploadRetry(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.IdempotencyKey{})
+
+	e := echo.New()
+	e.POST("/api/v1/clients/import", ImportClients, middlewares.Idempotency)
+	upload := func(content string) *httptest.ResponseRecorder {
+		// Cada formulario lleva un separador aleatorio distinto
+		contentType, body := importUpload(t, "clientes.csv", content)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/import", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		req.Header.Set(middlewares.HeaderIdempotencyKey, "import-1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	csv := "name,last_name,email,birth_day,telephone\nJane,Smith,jane@example.com,1985-02-14,911234567\n"
+	first := upload(csv)
+	assert.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	retry := upload(csv)
+	assert.Equal(t, http.StatusOK, retry.Code, retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middlewares.HeaderIdempotentReplayed))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	other := upload(strings.Replace(csv, "Jane", "Janet", 1))
+	assert.Equal(t, http.StatusUnprocessableEntity, other.Code)
+	var count int64
+	config.DB.Model(&models.Client{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Modos de cálculo de la desviación estándar
+const (
+	stdPopulation = "population"
+	stdSample     = "sample"
+)
+
+// Límites de los parámetros del KPI para acotar el tamaño de la respuesta
+const (
+	maxKPIPercentiles = 20
+	maxKPIBuckets     = 50
+)
+
+var (
+	defaultKPIPercentiles = []float64{10, 25, 75, 90}
+	defaultKPIBuckets     = []float64{18, 25, 35, 45, 55, 65}
+)
+
+// kpiSegments son las expresiones SQL de cada segmentación del KPI
+var kpiSegments = map[string]string{
+	"birth_decade": "CAST(CAST(strftime('%Y', birth_day) AS INTEGER) / 10 * 10 AS TEXT)",
+	"birth_month":  "strftime('%m', birth_day)",
+	"email_domain": "LOWER(SUBSTR(email, INSTR(email, '@') + 1))",
+	"signup_month": "strftime('%Y-%m', created_at)",
+}
+
+// ClientKPI resume la distribución de edades de los clientes. Sin clientes, count es 0 y las
+// estadísticas son null; la desviación muestral también es null con menos de dos clientes
+type ClientKPI struct {
+	Count                int64                    `json:"count"`
+	AverageAge           *float64                 `json:"average_age"`
+	AgeStandardDeviation *float64                 `json:"age_standard_deviation"`
+	StandardDeviation    string                   `json:"standard_deviation" enums:"population,sample"`
+	MinAge               *float64                 `json:"min_age"`
+	MaxAge               *float64                 `json:"max_age"`
+	MedianAge            *float64                 `json:"median_age"`
+	Percentiles          map[string]*float64      `json:"percentiles"`
+	Histogram            []models.HistogramBucket `json:"histogram"`
+	*ClientLoyaltyKPI
+}
+
+// ClientLoyaltyKPI resume las compras y los puntos de los clientes: average_spend es el total medio por pedido
+// en euros, null sin pedidos, y points_outstanding la suma de los puntos vigentes
+type ClientLoyaltyKPI struct {
+	AverageSpend      *float64 `json:"average_spend"`
+	PointsOutstanding int64    `json:"points_outstanding"`
+}
+
+// ClientKPISegment son las estadísticas de un segmento; segment es null para los clientes sin valor
+type ClientKPISegment struct {
+	Segment *string `json:"segment"`
+	ClientKPI
+}
+
+// ClientKPIGroups son las estadísticas de edad por segmento
+type ClientKPIGroups struct {
+	GroupBy  string             `json:"group_by"`
+	Segments []ClientKPISegment `json:"segments"`
+}
+
+// kpiOptions son los parámetros con los que se calcula el KPI
+type kpiOptions struct {
+	Percentiles []float64
+	Buckets     []float64
+	Sample      bool
+}
+
+// GetClientKPI calcula las estadísticas de edad de los clientes, en total o por segmento
+// @Summary KPI de clientes
+// @Description Calcula la cantidad de clientes y la media, desviación estándar, mínimo, máximo, mediana, percentiles e histograma de sus edades, el total medio de sus pedidos y sus puntos de fidelidad vigentes; si ya venció alguno, primero se registran los vencimientos pendientes. Los percentiles interpolan linealmente entre las dos edades más cercanas. Acepta los mismos filtros que el listado. Con group_by la respuesta es {"group_by": ..., "segments": [...]} con las estadísticas de cada segmento
+// @Tags Clientes
+// @Produce json
+// @Param group_by query string false "Segmentar por década o mes de nacimiento, dominio del email o mes de alta" Enums(birth_decade, birth_month, email_domain, signup_month)
+// @Param percentiles query string false "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)"
+// @Param std query string false "Desviación estándar poblacional o muestral" Enums(population, sample) default(population)
+// @Param buckets query string false "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)"
+// @Param last_name query string false "Prefijo del apellido"
+// @Param email_domain query string false "Dominio del email"
+// @Param telephone_prefix query string false "Prefijo del teléfono, nacional (600) o internacional (+34600)"
+// @Param age_min query int false "Edad mínima"
+// @Param age_max query int false "Edad máxima"
+// @Param birth_day_from query string false "Fecha de nacimiento desde (YYYY-MM-DD)"
+// @Param birth_day_to query string false "Fecha de nacimiento hasta (YYYY-MM-DD)"
+// @Param include_deleted query bool false "Incluir clientes eliminados"
+// @Success 200 {object} ClientKPI "KPI de clientes calculado"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/kpi [get]
+func GetClientKPI(c echo.Context) error {
+	opts, err := parseKPIOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter, err := parseClientFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	groupBy := c.QueryParam("group_by")
+	segmentSQL, ok := kpiSegments[groupBy]
+	if groupBy != "" && !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "group_by must be one of birth_decade, birth_month, email_domain, signup_month"})
+	}
+
+	// Sin filtros ni segmentos se responde con los agregados que se mantienen con cada cambio
+	if includeDeleted, _ := strconv.ParseBool(c.QueryParam("include_deleted")); groupBy == "" && filter == (clientFilter{}) && !includeDeleted {
+		stats, ages, err := models.LoadClientAgeStats(config.DB)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve clients"})
+		}
+		loyalty, err := models.LoadClientLoyaltyStats(config.DB)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve clients"})
+		}
+		kpi := computeClientKPI(ages, opts)
+		kpi.Count = stats.Count
+		kpi.AverageAge = optional(stats.MeanValue())
+		kpi.AgeStandardDeviation = optional(stats.StdDev(opts.Sample))
+		kpi.ClientLoyaltyKPI = loyaltyKPI(loyalty.Orders, loyalty.SpendCents, loyalty.Points)
+		return c.JSON(http.StatusOK, kpi)
+	}
+
+	if err := models.ExpireDueLoyaltyPoints(config.DB); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve clients"})
+	}
+	scope := applyClientFilter(clientScope(c), filter)
+	loyalty, err := clientLoyaltyKPIs(scope.Session(&gorm.Session{}), segmentSQL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve clients"})
+	}
+	segments, err := clientAgeDistributions(scope, segmentSQL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve clients"})
+	}
+
+	if groupBy == "" {
+		var all models.Distribution
+		if len(segments) > 0 {
+			all = segments[0].Ages
+		}
+		kpi := computeClientKPI(all, opts)
+		kpi.ClientLoyaltyKPI = loyalty.get(nil)
+		return c.JSON(http.StatusOK, kpi)
+	}
+	groups := ClientKPIGroups{GroupBy: groupBy, Segments: make([]ClientKPISegment, len(segments))}
+	for i, s := range segments {
+		groups.Segments[i] = ClientKPISegment{Segment: s.Key, ClientKPI: computeClientKPI(s.Ages, opts)}
+		groups.Segments[i].ClientLoyaltyKPI = loyalty.get(s.Key)
+	}
+	return c.JSON(http.StatusOK, groups)
+}
+
+// loyaltyBySegment son las compras y los puntos de cada segmento; la clave nil es la de los clientes sin segmento
+type loyaltyBySegment map[string]*ClientLoyaltyKPI
+
+// get devuelve los valores del segmento, o los de un segmento sin pedidos ni puntos
+func (l loyaltyBySegment) get(segment *string) *ClientLoyaltyKPI {
+	if kpi, ok := l[loyaltySegmentKey(segment)]; ok {
+		return kpi
+	}
+	return loyaltyKPI(0, 0, 0)
+}
+
+func loyaltySegmentKey(segment *string) string {
+	if segment == nil {
+		return "null"
+	}
+	return "=" + *segment
+}
+
+// clientLoyaltyKPIs calcula en SQL el total medio por pedido y los puntos vigentes de los clientes de db en
+// cada segmento. Los puntos vigentes son la suma del libro, en el que ya deben estar registrados los vencimientos
+// pendientes. Recorre los clientes, así que solo se usa cuando los filtros o los segmentos ya obligan a hacerlo
+func clientLoyaltyKPIs(db *gorm.DB, segmentSQL string) (loyaltyBySegment, error) {
+	if segmentSQL == "" {
+		segmentSQL = "NULL"
+	}
+	var rows []struct {
+		Segment *string
+		Orders  int64
+		Spend   int64
+		Points  int64
+	}
+	err := db.Select(segmentSQL + ` AS segment,
+			SUM((SELECT COUNT(*) FROM orders WHERE orders.client_id = clients.id)) AS orders,
+			SUM((SELECT COALESCE(SUM(total_cents), 0) FROM orders WHERE orders.client_id = clients.id)) AS spend,
+			SUM((SELECT COALESCE(SUM(points), 0) FROM loyalty_entries WHERE loyalty_entries.client_id = clients.id)) AS points`).
+		Group("segment").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(loyaltyBySegment, len(rows))
+	for _, row := range rows {
+		result[loyaltySegmentKey(row.Segment)] = loyaltyKPI(row.Orders, row.Spend, row.Points)
+	}
+	return result, nil
+}
+
+// loyaltyKPI calcula el total medio por pedido en euros a partir de la cantidad y el total en céntimos de
+// los pedidos
+func loyaltyKPI(orders, spendCents, points int64) *ClientLoyaltyKPI {
+	kpi := ClientLoyaltyKPI{PointsOutstanding: points}
+	if orders > 0 {
+		kpi.AverageSpend = optional(math.Round(float64(spendCents)/float64(orders))/100, true)
+	}
+	return &kpi
+}
+
+// ageSegment es la distribución de edades de los clientes de un segmento
+type ageSegment struct {
+	Key  *string
+	Ages models.Distribution
+}
+
+// clientAgeDistributions calcula en SQL cuántos clientes de cada segmento tienen cada edad. Sin
+// segmentSQL todos los clientes forman un único segmento. Los segmentos se devuelven ordenados
+// por clave, con los clientes sin segmento al final
+func clientAgeDistributions(db *gorm.DB, segmentSQL string) ([]ageSegment, error) {
+	if segmentSQL == "" {
+		segmentSQL = "NULL"
+	}
+	today := models.Now().UTC().Format("2006-01-02")
+
+	var rows []struct {
+		Segment *string
+		Age     float64
+		Count   int64
+	}
+	err := db.Select(segmentSQL+" AS segment, "+models.ClientAgeSQL+" AS age, COUNT(*) AS count", today, today).
+		Group("segment, age").
+		Order("segment IS NULL, segment, age").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []ageSegment
+	for _, row := range rows {
+		if n := len(segments); n == 0 || !sameSegment(segments[n-1].Key, row.Segment) {
+			segments = append(segments, ageSegment{Key: row.Segment})
+		}
+		last := &segments[len(segments)-1]
+		last.Ages = append(last.Ages, models.ValueCount{Value: row.Age, Count: row.Count})
+	}
+	return segments, nil
+}
+
+func sameSegment(a, b *string) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// parseKPIOptions lee los percentiles, los bordes del histograma y el modo de la desviación estándar
+func parseKPIOptions(c echo.Context) (kpiOptions, error) {
+	opts := kpiOptions{Percentiles: defaultKPIPercentiles, Buckets: defaultKPIBuckets}
+
+	var err error
+	if opts.Sample, err = parseStdMode(c); err != nil {
+		return opts, err
+	}
+	if v := c.QueryParam("percentiles"); v != "" {
+		if opts.Percentiles, err = parseFloatList(v, maxKPIPercentiles); err != nil {
+			return opts, errors.New("percentiles " + err.Error())
+		}
+		for _, p := range opts.Percentiles {
+			if p < 0 || p > 100 {
+				return opts, errors.New("percentiles must be between 0 and 100")
+			}
+		}
+	}
+	if v := c.QueryParam("buckets"); v != "" {
+		if opts.Buckets, err = parseFloatList(v, maxKPIBuckets); err != nil {
+			return opts, errors.New("buckets " + err.Error())
+		}
+		for i := 1; i < len(opts.Buckets); i++ {
+			if opts.Buckets[i] <= opts.Buckets[i-1] {
+				return opts, errors.New("buckets must be in increasing order")
+			}
+		}
+	}
+	return opts, nil
+}
+
+// parseStdMode indica si se pide la desviación estándar muestral en lugar de la poblacional
+func parseStdMode(c echo.Context) (bool, error) {
+	switch c.QueryParam("std") {
+	case "", stdPopulation:
+		return false, nil
+	case stdSample:
+		return true, nil
+	}
+	return false, errors.New("std must be population or sample")
+}
+
+// parseFloatList interpreta una lista de números separados por coma con a lo sumo max elementos
+func parseFloatList(raw string, max int) ([]float64, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) > max {
+		return nil, errors.New("accepts at most " + strconv.Itoa(max) + " values")
+	}
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("must be a comma-separated list of numbers")
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// computeClientKPI calcula el KPI de una distribución de edades
+func computeClientKPI(ages models.Distribution, opts kpiOptions) ClientKPI {
+	kpi := ClientKPI{
+		Count:             ages.Count(),
+		StandardDeviation: stdPopulation,
+		Percentiles:       make(map[string]*float64, len(opts.Percentiles)),
+		Histogram:         ages.Histogram(opts.Buckets),
+	}
+	if opts.Sample {
+		kpi.StandardDeviation = stdSample
+	}
+	kpi.AverageAge = optional(ages.Mean())
+	kpi.AgeStandardDeviation = optional(ages.StdDev(opts.Sample))
+	kpi.MinAge = optional(ages.Min())
+	kpi.MaxAge = optional(ages.Max())
+	kpi.MedianAge = optional(ages.Quantile(50))
+	for _, p := range opts.Percentiles {
+		kpi.Percentiles[percentileKey(p)] = optional(ages.Quantile(p))
+	}
+	return kpi
+}
+
+// percentileKey nombra un percentil en la respuesta: p10, p2.5
+func percentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// optional convierte un resultado que puede no estar definido en un valor JSON o null
+func optional(v float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &v
+}
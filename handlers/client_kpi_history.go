@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golangApp/config"
+	"golangApp/jobs"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Intervalos de la serie histórica de KPI y de las cohortes de alta
+const (
+	intervalDay     = "day"
+	intervalWeek    = "week"
+	intervalMonth   = "month"
+	intervalQuarter = "quarter"
+)
+
+// maxKPIHistoryPoints limita la cantidad de períodos de una serie histórica
+const maxKPIHistoryPoints = 1000
+
+// Modos de completar los períodos sin foto
+const (
+	fillPrevious = "previous"
+	fillNone     = "none"
+)
+
+// ClientKPISnapshot es una foto de los KPI de clientes
+type ClientKPISnapshot struct {
+	ID           int       `json:"id"`
+	TakenAt      time.Time `json:"taken_at"`
+	DeletedCount int64     `json:"deleted_count"`
+	KPI          ClientKPI `json:"kpi"`
+}
+
+// ClientKPIPoint es el KPI de un período de la serie histórica. Se usa la última foto del período;
+// filled indica que el período no tenía fotos y se repite la anterior. Sin foto, kpi es null
+type ClientKPIPoint struct {
+	Period       string     `json:"period"`
+	TakenAt      *time.Time `json:"taken_at"`
+	Filled       bool       `json:"filled"`
+	DeletedCount *int64     `json:"deleted_count"`
+	KPI          *ClientKPI `json:"kpi"`
+}
+
+// ClientKPIHistory es la serie histórica de los KPI de clientes
+type ClientKPIHistory struct {
+	Interval string           `json:"interval"`
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	Points   []ClientKPIPoint `json:"points"`
+}
+
+// CreateClientKPISnapshot guarda una foto de los KPI de clientes
+// @Summary Guardar foto de KPI
+// @Description Guarda en el momento una foto de los KPI de clientes, además de las que se guardan periódicamente
+// @Tags Clientes
+// @Produce json
+// @Param percentiles query string false "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)"
+// @Param std query string false "Desviación estándar poblacional o muestral" Enums(population, sample) default(population)
+// @Param buckets query string false "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)"
+// @Success 201 {object} ClientKPISnapshot "Foto guardada"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/kpi/snapshots [post]
+func CreateClientKPISnapshot(c echo.Context) error {
+	opts, err := parseKPIOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	snapshot, err := jobs.TakeKPISnapshot(config.DB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, ClientKPISnapshot{
+		ID:           snapshot.ID,
+		TakenAt:      snapshot.TakenAt,
+		DeletedCount: snapshot.DeletedCount,
+		KPI:          computeClientKPI(snapshot.Ages, opts),
+	})
+}
+
+// GetClientKPIHistory devuelve la serie histórica de los KPI de clientes
+// @Summary Historial de KPI
+// @Description Devuelve un punto por día, semana (de lunes a domingo) o mes entre from y to con la última foto de KPI de cada período. Los períodos sin foto repiten la anterior salvo con fill=none
+// @Tags Clientes
+// @Produce json
+// @Param from query string false "Fecha inicial (YYYY-MM-DD); por defecto 30 días, 12 semanas o 12 meses antes de to"
+// @Param to query string false "Fecha final (YYYY-MM-DD); por defecto hoy"
+// @Param interval query string false "Tamaño de cada período" Enums(day, week, month) default(day)
+// @Param fill query string false "Cómo completar los períodos sin foto" Enums(previous, none) default(previous)
+// @Param percentiles query string false "Percentiles separados por coma, entre 0 y 100 (por defecto 10,25,75,90)"
+// @Param std query string false "Desviación estándar poblacional o muestral" Enums(population, sample) default(population)
+// @Param buckets query string false "Bordes crecientes de los intervalos del histograma (por defecto 18,25,35,45,55,65)"
+// @Success 200 {object} ClientKPIHistory "Serie histórica"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/clients/kpi/history [get]
+func GetClientKPIHistory(c echo.Context) error {
+	opts, err := parseKPIOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	interval := c.QueryParam("interval")
+	if interval == "" {
+		interval = intervalDay
+	}
+	if interval != intervalDay && interval != intervalWeek && interval != intervalMonth {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "interval must be day, week or month"})
+	}
+	fill := c.QueryParam("fill")
+	if fill == "" {
+		fill = fillPrevious
+	}
+	if fill != fillPrevious && fill != fillNone {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "fill must be previous or none"})
+	}
+
+	to, err := parseOptionalDate(c, "to")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if to == nil {
+		today := models.Now().UTC().Truncate(24 * time.Hour)
+		to = &today
+	}
+	from, err := parseOptionalDate(c, "from")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if from == nil {
+		start := defaultHistoryFrom(*to, interval)
+		from = &start
+	}
+	if from.After(*to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+	}
+
+	periods, err := historyPeriods(*from, *to, interval)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	end := nextPeriod(periods[len(periods)-1], interval)
+
+	var snapshots []models.KPISnapshot
+	if err := config.DB.Where("taken_at >= ? AND taken_at < ?", periods[0], end).Order("taken_at").Find(&snapshots).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	// La última foto anterior al rango completa los primeros períodos
+	var previous *models.KPISnapshot
+	if fill == fillPrevious {
+		var before models.KPISnapshot
+		if err := config.DB.Where("taken_at < ?", periods[0]).Order("taken_at DESC").Limit(1).Find(&before).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		if before.ID != 0 {
+			previous = &before
+		}
+	}
+
+	history := ClientKPIHistory{
+		Interval: interval,
+		From:     periods[0].Format(dateLayout),
+		To:       periods[len(periods)-1].Format(dateLayout),
+		Points:   make([]ClientKPIPoint, len(periods)),
+	}
+	next := 0
+	for i, start := range periods {
+		periodEnd := nextPeriod(start, interval)
+		var latest *models.KPISnapshot
+		for next < len(snapshots) && snapshots[next].TakenAt.Before(periodEnd) {
+			latest = &snapshots[next]
+			next++
+		}
+
+		point := ClientKPIPoint{Period: periodLabel(start, interval)}
+		switch {
+		case latest != nil:
+			previous = latest
+		case fill == fillPrevious && previous != nil:
+			point.Filled = true
+		default:
+			history.Points[i] = point
+			continue
+		}
+		kpi := computeClientKPI(previous.Ages, opts)
+		point.TakenAt, point.DeletedCount, point.KPI = &previous.TakenAt, &previous.DeletedCount, &kpi
+		history.Points[i] = point
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+// dateLayout es el formato de las fechas de la serie histórica
+const dateLayout = "2006-01-02"
+
+// historyPeriods devuelve el comienzo de cada período entre from y to
+func historyPeriods(from, to time.Time, interval string) ([]time.Time, error) {
+	var periods []time.Time
+	for start := periodStart(from, interval); !start.After(to); start = nextPeriod(start, interval) {
+		if len(periods) == maxKPIHistoryPoints {
+			return nil, errors.New("the range has too many periods, use a larger interval")
+		}
+		periods = append(periods, start)
+	}
+	return periods, nil
+}
+
+// periodStart devuelve el comienzo del período que contiene t: el día, el lunes o el primero del mes
+// o del trimestre
+func periodStart(t time.Time, interval string) time.Time {
+	y, m, d := t.UTC().Date()
+	switch interval {
+	case intervalWeek:
+		weekday := (int(t.UTC().Weekday()) + 6) % 7
+		return time.Date(y, m, d-weekday, 0, 0, 0, 0, time.UTC)
+	case intervalMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case intervalQuarter:
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// nextPeriod devuelve el comienzo del período siguiente a start
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case intervalWeek:
+		return start.AddDate(0, 0, 7)
+	case intervalMonth:
+		return start.AddDate(0, 1, 0)
+	case intervalQuarter:
+		return start.AddDate(0, 3, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// periodLabel nombra un período: la fecha del día o del lunes, el mes (YYYY-MM) o el trimestre (YYYY-Q1)
+func periodLabel(start time.Time, interval string) string {
+	switch interval {
+	case intervalMonth:
+		return start.Format("2006-01")
+	case intervalQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())+2)/3)
+	}
+	return start.Format(dateLayout)
+}
+
+// defaultHistoryFrom devuelve el comienzo por defecto de la serie que termina en to
+func defaultHistoryFrom(to time.Time, interval string) time.Time {
+	switch interval {
+	case intervalWeek:
+		return to.AddDate(0, 0, -7*11)
+	case intervalMonth:
+		return to.AddDate(0, -11, 0)
+	}
+	return to.AddDate(0, 0, -29)
+}
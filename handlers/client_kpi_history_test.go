@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func clientKPIHistory(t *testing.T, query string) (*httptest.ResponseRecorder, ClientKPIHistory) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/kpi/history?"+query, nil)
+	rec := httptest.NewRecorder()
+
+	var history ClientKPIHistory
+	if assert.NoError(t, GetClientKPIHistory(e.NewContext(req, rec))) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	}
+	return rec, history
+}
+
+// seedKPISnapshots guarda fotos con count clientes de 30 años en cada fecha
+func seedKPISnapshots(t *testing.T, snapshots map[string]int64) {
+	for takenAt, count := range snapshots {
+		at, err := time.Parse(time.RFC3339, takenAt)
+		assert.NoError(t, err)
+		config.DB.Create(&models.KPISnapshot{TakenAt: at, Count: count, Ages: models.Distribution{{Value: 30, Count: count}}})
+	}
+}
+
+// pointCounts resume una serie: la cantidad de clientes de cada punto, -1 sin foto, y si se completó
+func pointCounts(history ClientKPIHistory) ([]string, []int64, []bool) {
+	var periods []string
+	var counts []int64
+	var filled []bool
+	for _, p := range history.Points {
+		periods = append(periods, p.Period)
+		filled = append(filled, p.Filled)
+		if p.KPI == nil {
+			counts = append(counts, -1)
+			continue
+		}
+		counts = append(counts, p.KPI.Count)
+	}
+	return periods, counts, filled
+}
+
+func TestCreateClientKPISnapshot(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.KPISnapshot{})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/kpi/snapshots?std=sample", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, CreateClientKPISnapshot(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var snapshot ClientKPISnapshot
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snapshot))
+	assert.NotZero(t, snapshot.ID)
+	assert.True(t, testNow.Equal(snapshot.TakenAt))
+	assert.Equal(t, int64(5), snapshot.KPI.Count)
+	assert.Equal(t, "sample", snapshot.KPI.StandardDeviation)
+
+	_, history := clientKPIHistory(t, "from=2024-06-15")
+	if assert.Len(t, history.Points, 1) {
+		assert.Equal(t, int64(5), history.Points[0].KPI.Count)
+		assert.False(t, history.Points[0].Filled)
+	}
+}
+
+func TestGetClientKPIHistoryFillsGaps(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.KPISnapshot{})
+	seedKPISnapshots(t, map[string]int64{
+		"2024-06-08T10:00:00Z": 1,
+		"2024-06-11T09:00:00Z": 2,
+		"2024-06-11T18:00:00Z": 3,
+		"2024-06-13T23:59:59Z": 4,
+	})
+
+	rec, history := clientKPIHistory(t, "from=2024-06-10&to=2024-06-14")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "day", history.Interval)
+	periods, counts, filled := pointCounts(history)
+	assert.Equal(t, []string{"2024-06-10", "2024-06-11", "2024-06-12", "2024-06-13", "2024-06-14"}, periods)
+	assert.Equal(t, []int64{1, 3, 3, 4, 4}, counts, "Cada día usa su última foto o la anterior")
+	assert.Equal(t, []bool{true, false, true, false, true}, filled)
+	assert.Equal(t, "2024-06-11T18:00:00Z", history.Points[2].TakenAt.Format(time.RFC3339))
+
+	_, history = clientKPIHistory(t, "from=2024-06-10&to=2024-06-14&fill=none")
+	_, counts, filled = pointCounts(history)
+	assert.Equal(t, []int64{-1, 3, -1, 4, -1}, counts)
+	assert.Equal(t, []bool{false, false, false, false, false}, filled)
+	assert.Nil(t, history.Points[0].TakenAt)
+
+	_, history = clientKPIHistory(t, "from=2024-06-01&to=2024-06-09")
+	_, counts, _ = pointCounts(history)
+	assert.Equal(t, []int64{-1, -1, -1, -1, -1, -1, -1, 1, 1}, counts, "Antes de la primera foto no hay KPI")
+}
+
+func TestGetClientKPIHistoryIntervals(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.KPISnapshot{})
+	seedKPISnapshots(t, map[string]int64{
+		"2024-04-20T10:00:00Z": 1,
+		"2024-06-03T10:00:00Z": 2,
+		"2024-06-09T23:00:00Z": 3,
+		"2024-06-12T10:00:00Z": 4,
+	})
+
+	// Las semanas empiezan el lunes: el 2024-06-09 es domingo
+	_, history := clientKPIHistory(t, "interval=week&from=2024-05-29&to=2024-06-14")
+	periods, counts, filled := pointCounts(history)
+	assert.Equal(t, "2024-05-27", history.From)
+	assert.Equal(t, []string{"2024-05-27", "2024-06-03", "2024-06-10"}, periods)
+	assert.Equal(t, []int64{1, 3, 4}, counts)
+	assert.Equal(t, []bool{true, false, false}, filled)
+
+	_, history = clientKPIHistory(t, "interval=month&from=2024-03-15")
+	periods, counts, _ = pointCounts(history)
+	assert.Equal(t, []string{"2024-03", "2024-04", "2024-05", "2024-06"}, periods)
+	assert.Equal(t, []int64{-1, 1, 1, 4}, counts)
+
+	// Por defecto la serie termina hoy: 30 días, 12 semanas o 12 meses
+	_, history = clientKPIHistory(t, "")
+	assert.Len(t, history.Points, 30)
+	assert.Equal(t, "2024-06-15", history.To)
+	_, history = clientKPIHistory(t, "interval=week")
+	assert.Len(t, history.Points, 12)
+	_, history = clientKPIHistory(t, "interval=month")
+	assert.Len(t, history.Points, 12)
+	assert.Equal(t, "2023-07", history.Points[0].Period)
+}
+
+func TestGetClientKPIHistoryInvalidParams(t *testing.T) {
+	setupTestDB()
+
+	for _, query := range []string{
+		"interval=year",
+		"fill=zero",
+		"from=2024-06-15&to=2024-06-01",
+		"from=15/06/2024",
+		"from=2000-01-01&to=2024-06-15",
+		"std=unbiased",
+	} {
+		rec, _ := clientKPIHistory(t, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	rec, _ := clientKPIHistory(t, "interval=month&from=2000-01-01&to=2024-06-15")
+	assert.Equal(t, http.StatusOK, rec.Code, "El límite es de períodos, no de días")
+}
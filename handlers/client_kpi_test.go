@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func clientKPI(t *testing.T, query string) (*httptest.ResponseRecorder, ClientKPI) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/kpi?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var kpi ClientKPI
+	if assert.NoError(t, GetClientKPI(c)) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &kpi))
+	}
+	return rec, kpi
+}
+
+func TestGetClientKPIEmpty(t *testing.T) {
+	setupTestDB()
+
+	rec, _ := clientKPI(t, "std=sample")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"count": 0,
+		"average_age": null,
+		"age_standard_deviation": null,
+		"standard_deviation": "sample",
+		"min_age": null,
+		"max_age": null,
+		"median_age": null,
+		"percentiles": {"p10": null, "p25": null, "p75": null, "p90": null},
+		"histogram": [
+			{"from": null, "to": 18, "count": 0},
+			{"from": 18, "to": 25, "count": 0},
+			{"from": 25, "to": 35, "count": 0},
+			{"from": 35, "to": 45, "count": 0},
+			{"from": 45, "to": 55, "count": 0},
+			{"from": 55, "to": 65, "count": 0},
+			{"from": 65, "to": null, "count": 0}
+		],
+		"average_spend": null,
+		"points_outstanding": 0
+	}`, rec.Body.String())
+}
+
+func TestGetClientKPIStatistics(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	// Edades al 15/06/2024: 15, 20, 35, 40 y 50
+	for i, year := range []int{2009, 2004, 1989, 1984, 1974} {
+		config.DB.Create(&models.Client{Name: "C", LastName: "L", Email: string(rune('a'+i)) + "@example.com",
+			BirthDay: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"})
+	}
+	models.RebuildClientAgeStats(config.DB)
+
+	rec, kpi := clientKPI(t, "percentiles=2.5,50,90&buckets=18,40")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(5), kpi.Count)
+	assert.Equal(t, 32.0, *kpi.AverageAge)
+	assert.InDelta(t, 12.884099, *kpi.AgeStandardDeviation, 0.000001)
+	assert.Equal(t, "population", kpi.StandardDeviation)
+	assert.Equal(t, 15.0, *kpi.MinAge)
+	assert.Equal(t, 50.0, *kpi.MaxAge)
+	assert.Equal(t, 35.0, *kpi.MedianAge)
+	assert.Equal(t, []string{"p2.5", "p50", "p90"}, sortedKeys(kpi.Percentiles))
+	assert.Equal(t, 15.5, *kpi.Percentiles["p2.5"])
+	assert.Equal(t, 46.0, *kpi.Percentiles["p90"])
+	assert.Equal(t, []int64{1, 2, 2}, []int64{kpi.Histogram[0].Count, kpi.Histogram[1].Count, kpi.Histogram[2].Count})
+
+	_, kpi = clientKPI(t, "std=sample")
+	assert.InDelta(t, 14.404860, *kpi.AgeStandardDeviation, 0.000001)
+}
+
+func TestGetClientKPIInvalidParams(t *testing.T) {
+	setupTestDB()
+
+	for _, query := range []string{"std=unbiased", "percentiles=101", "percentiles=a,b", "percentiles=NaN", "buckets=30,20", "buckets=18,18"} {
+		rec, _ := clientKPI(t, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestGetClientKPIGroupBy(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	groupBy := func(query string) ClientKPIGroups {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/kpi?"+query, nil)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, GetClientKPI(e.NewContext(req, rec)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var groups ClientKPIGroups
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &groups))
+		return groups
+	}
+	summary := func(groups ClientKPIGroups) map[string]int64 {
+		counts := map[string]int64{}
+		for _, s := range groups.Segments {
+			key := "null"
+			if s.Segment != nil {
+				key = *s.Segment
+			}
+			counts[key] = s.Count
+		}
+		return counts
+	}
+
+	groups := groupBy("group_by=birth_decade")
+	assert.Equal(t, "birth_decade", groups.GroupBy)
+	assert.Equal(t, map[string]int64{"1970": 1, "1980": 1, "1990": 2, "2000": 1}, summary(groups))
+	if assert.Len(t, groups.Segments, 4) {
+		assert.Equal(t, "1970", *groups.Segments[0].Segment, "Los segmentos se ordenan por clave")
+		// John (1990-01-01) tiene 34 y Alice (1995-03-30) 29
+		assert.Equal(t, 31.5, *groups.Segments[2].AverageAge)
+		assert.Equal(t, 29.0, *groups.Segments[2].MinAge)
+	}
+
+	assert.Equal(t, map[string]int64{"01": 1, "02": 1, "03": 1, "07": 1, "12": 1}, summary(groupBy("group_by=birth_month")))
+	assert.Equal(t, map[string]int64{"example.com": 3, "corp.com": 2}, summary(groupBy("group_by=email_domain")))
+	assert.Equal(t, map[string]int64{"example.com": 1}, summary(groupBy("group_by=email_domain&last_name=smi&age_max=30")), "Acepta los filtros del listado")
+
+	// Los clientes se dieron de alta el día de los tests; los anteriores a las fechas de alta no tienen mes de alta
+	assert.Equal(t, map[string]int64{"2024-06": 5}, summary(groupBy("group_by=signup_month")))
+	config.DB.Exec("UPDATE clients SET created_at = ? WHERE name = ?", time.Date(2024, time.March, 3, 10, 0, 0, 0, time.UTC), "John")
+	config.DB.Exec("UPDATE clients SET created_at = NULL WHERE name = ?", "Alice")
+	assert.Equal(t, map[string]int64{"2024-03": 1, "2024-06": 3, "null": 1}, summary(groupBy("group_by=signup_month")))
+
+	rec, _ := clientKPI(t, "group_by=zodiac")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = clientKPI(t, "age_min=x")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetClientKPIFilters(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	_, kpi := clientKPI(t, "email_domain=corp.com")
+	assert.Equal(t, int64(2), kpi.Count)
+
+	var bob models.Client
+	config.DB.Where("name = ?", "Bob").First(&bob)
+	config.DB.Delete(&bob)
+	_, kpi = clientKPI(t, "email_domain=corp.com")
+	assert.Equal(t, int64(1), kpi.Count)
+	_, kpi = clientKPI(t, "email_domain=corp.com&include_deleted=true")
+	assert.Equal(t, int64(2), kpi.Count)
+}
+
+func TestClientAgeSQLMatchesAgeAt(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer func() { models.Now = func() time.Time { return testNow } }()
+
+	birthDays := []time.Time{
+		time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(1999, time.February, 28, 0, 0, 0, 0, time.UTC),
+		time.Date(1999, time.March, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1990, time.December, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for i, birthDay := range birthDays {
+		config.DB.Create(&models.Client{Name: "C", LastName: "L", Email: string(rune('a'+i)) + "@example.com", BirthDay: birthDay, Telephone: "600123456"})
+	}
+
+	for _, today := range []time.Time{
+		time.Date(2023, time.February, 28, 12, 0, 0, 0, time.UTC),
+		time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.February, 28, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, time.December, 31, 12, 0, 0, 0, time.UTC),
+	} {
+		models.Now = func() time.Time { return today }
+		var expected []float64
+		for _, birthDay := range birthDays {
+			expected = append(expected, float64(models.AgeAt(birthDay, today)))
+		}
+
+		segments, err := clientAgeDistributions(config.DB.Model(&models.Client{}), "")
+		if assert.NoError(t, err) && assert.Len(t, segments, 1) {
+			assert.Equal(t, models.NewDistribution(expected), segments[0].Ages, today.Format("2006-01-02"))
+		}
+	}
+}
+
+func sortedKeys(m map[string]*float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestClientKPIAggregatesFollowChanges(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer func() { models.Now = func() time.Time { return testNow } }()
+
+	call := func(method, target, body string, handler echo.HandlerFunc, id int) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if id != 0 {
+			c.SetParamNames("id")
+			c.SetParamValues(strconv.Itoa(id))
+		}
+		assert.NoError(t, handler(c))
+		return rec
+	}
+	create := func(email, birthDay string) int {
+		rec := call(http.MethodPost, "/api/v1/clients", `{"name": "C", "last_name": "L", "email": "`+email+`", "birth_day": "`+birthDay+`T00:00:00Z", "telephone": "600123456"}`, CreateClient, 0)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var client models.Client
+		json.Unmarshal(rec.Body.Bytes(), &client)
+		return client.ID
+	}
+	// Los agregados deben coincidir con el cálculo sobre la tabla, que se fuerza con un filtro que no excluye a nadie
+	assertAggregates := func(step string, count int64) {
+		_, fast := clientKPI(t, "std=sample")
+		_, scan := clientKPI(t, "std=sample&age_min=0")
+		assert.Equal(t, count, fast.Count, step)
+		assert.Equal(t, scan.Count, fast.Count, step)
+		if scan.AverageAge != nil && assert.NotNil(t, fast.AverageAge, step) {
+			assert.InDelta(t, *scan.AverageAge, *fast.AverageAge, 0.000001, step)
+		}
+		if scan.AgeStandardDeviation != nil && assert.NotNil(t, fast.AgeStandardDeviation, step) {
+			assert.InDelta(t, *scan.AgeStandardDeviation, *fast.AgeStandardDeviation, 0.000001, step)
+		}
+		assert.Equal(t, scan.MedianAge, fast.MedianAge, step)
+		assert.Equal(t, scan.Histogram, fast.Histogram, step)
+		diffs, err := models.VerifyClientAgeStats(config.DB)
+		assert.NoError(t, err)
+		assert.Empty(t, diffs, step)
+	}
+
+	first := create("a@example.com", "1990-01-01")
+	create("b@example.com", "1994-06-16")
+	third := create("c@example.com", "1970-12-31")
+	assertAggregates("create", 3)
+
+	rec := call(http.MethodPut, "/api/v1/clients/"+strconv.Itoa(first), `{"birth_day": "2000-01-01T00:00:00Z"}`, UpdateClient, first)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assertAggregates("update", 3)
+
+	call(http.MethodDelete, "/api/v1/clients/"+strconv.Itoa(third), "", DeleteClient, third)
+	assertAggregates("delete", 2)
+	call(http.MethodPost, "/api/v1/clients/"+strconv.Itoa(third)+"/restore", "", RestoreClient, third)
+	assertAggregates("restore", 3)
+
+	rec = call(http.MethodPost, "/api/v1/clients/merge", `{"survivor_id": `+strconv.Itoa(first)+`, "merged_ids": [`+strconv.Itoa(third)+`]}`, MergeClients, 0)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assertAggregates("merge", 2)
+
+	// Al día siguiente el segundo cliente cumple 30 años
+	models.Now = func() time.Time { return testNow.AddDate(0, 0, 1) }
+	_, kpi := clientKPI(t, "")
+	assert.Equal(t, 30.0, *kpi.MaxAge)
+	assertAggregates("rollover", 2)
+
+	// Un cambio varios días después aplica la diferencia y pasa las edades al día nuevo
+	models.Now = func() time.Time { return time.Date(2025, time.January, 2, 12, 0, 0, 0, time.UTC) }
+	create("d@example.com", "2000-01-02")
+	assertAggregates("rollover on change", 3)
+	_, kpi = clientKPI(t, "")
+	assert.Equal(t, []float64{25, 25, 30}, []float64{*kpi.MinAge, *kpi.MedianAge, *kpi.MaxAge})
+}
+
+func TestVerifyAndRebuildClientAgeStats(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	diffs, err := models.VerifyClientAgeStats(config.DB)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// Un cliente insertado sin pasar por los handlers no actualiza los agregados
+	config.DB.Create(&models.Client{Name: "D", LastName: "L", Email: "d@example.com", BirthDay: time.Date(1990, time.January, 2, 0, 0, 0, 0, time.UTC), Telephone: "600123456"})
+	diffs, _ = models.VerifyClientAgeStats(config.DB)
+	assert.Contains(t, diffs, "count is 5, expected 6")
+	assert.Contains(t, diffs, "age 34 has 1 clients, expected 2")
+
+	assert.NoError(t, models.RebuildClientAgeStats(config.DB))
+	diffs, _ = models.VerifyClientAgeStats(config.DB)
+	assert.Empty(t, diffs)
+	_, kpi := clientKPI(t, "")
+	assert.Equal(t, int64(6), kpi.Count)
+}
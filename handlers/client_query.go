@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// ClientPage es el sobre de respuesta del listado paginado de clientes
+type ClientPage struct {
+	Data       []models.Client `json:"data"`
+	Total      int64           `json:"total"`
+	Limit      int             `json:"limit"`
+	Offset     int             `json:"offset"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
+
+// clientFilter contiene los filtros aceptados por el listado de clientes
+type clientFilter struct {
+	LastName        string
+	EmailDomain     string
+	AgeMin          *int
+	AgeMax          *int
+	BirthDayFrom    *time.Time
+	BirthDayTo      *time.Time
+	TelephonePrefix string
+}
+
+// clientSort es un criterio de orden sobre una columna de clients
+type clientSort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// clientCursor identifica la posición de un registro dentro de un orden dado
+type clientCursor struct {
+	Values   []string `json:"v"`
+	ID       int      `json:"id"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// clientListQuery agrupa filtros, orden y paginación de una petición de listado
+type clientListQuery struct {
+	Filter clientFilter
+	Sort   []clientSort
+	Limit  int
+	Offset int
+	Cursor *clientCursor
+}
+
+// sortableClientFields mapea los campos públicos a su columna y si invierten el orden
+var sortableClientFields = map[string]struct {
+	Column string
+	Invert bool
+}{
+	"id":        {"id", false},
+	"name":      {"name", false},
+	"last_name": {"last_name", false},
+	"email":     {"email", false},
+	"birth_day": {"birth_day", false},
+	"age":       {"birth_day", true},
+}
+
+// parseClientFilter lee los filtros del listado de clientes desde la query string
+func parseClientFilter(c echo.Context) (clientFilter, error) {
+	var f clientFilter
+	f.LastName = strings.TrimSpace(c.QueryParam("last_name"))
+	f.EmailDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.QueryParam("email_domain")), "@"))
+	// Los teléfonos se guardan en E.164; un prefijo sin + se toma del país por defecto
+	if prefix := strings.TrimSpace(c.QueryParam("telephone_prefix")); prefix != "" {
+		if f.TelephonePrefix = models.PhonePrefix(prefix, models.DefaultPhoneRegion); f.TelephonePrefix == "" {
+			return f, errors.New("telephone_prefix must contain digits")
+		}
+	}
+
+	var err error
+	if f.AgeMin, err = parseOptionalInt(c, "age_min"); err != nil {
+		return f, err
+	}
+	if f.AgeMax, err = parseOptionalInt(c, "age_max"); err != nil {
+		return f, err
+	}
+	if f.AgeMin != nil && f.AgeMax != nil && *f.AgeMin > *f.AgeMax {
+		return f, errors.New("age_min must be less than or equal to age_max")
+	}
+	if f.BirthDayFrom, err = parseOptionalDate(c, "birth_day_from"); err != nil {
+		return f, err
+	}
+	if f.BirthDayTo, err = parseOptionalDate(c, "birth_day_to"); err != nil {
+		return f, err
+	}
+	if f.BirthDayFrom != nil && f.BirthDayTo != nil && f.BirthDayFrom.After(*f.BirthDayTo) {
+		return f, errors.New("birth_day_from must be before birth_day_to")
+	}
+	return f, nil
+}
+
+// parseClientListQuery lee filtros, orden y paginación del listado de clientes
+func parseClientListQuery(c echo.Context) (clientListQuery, error) {
+	var q clientListQuery
+	var err error
+	if q.Filter, err = parseClientFilter(c); err != nil {
+		return q, err
+	}
+	if q.Sort, err = parseClientSort(c.QueryParam("sort")); err != nil {
+		return q, err
+	}
+
+	q.Limit = defaultPageLimit
+	if v := c.QueryParam("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			return q, errors.New("limit must be a positive integer")
+		}
+		if q.Limit > maxPageLimit {
+			q.Limit = maxPageLimit
+		}
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil || q.Offset < 0 {
+			return q, errors.New("offset must be a non-negative integer")
+		}
+	}
+	if v := c.QueryParam("cursor"); v != "" {
+		if q.Cursor, err = decodeClientCursor(v, len(q.Sort)); err != nil {
+			return q, err
+		}
+		q.Offset = 0
+	}
+	return q, nil
+}
+
+// parseClientSort interpreta una lista como "last_name,-birth_day"; "-" indica orden descendente
+func parseClientSort(raw string) ([]clientSort, error) {
+	var sorts []clientSort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := false
+		if strings.HasPrefix(part, "-") {
+			desc = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+		field, ok := sortableClientFields[part]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by %q", part)
+		}
+		if seen[field.Column] {
+			continue
+		}
+		seen[field.Column] = true
+		sorts = append(sorts, clientSort{Field: part, Column: field.Column, Desc: desc != field.Invert})
+	}
+	// El id desempata siempre para que el orden sea total y el cursor estable
+	if !seen["id"] {
+		sorts = append(sorts, clientSort{Field: "id", Column: "id"})
+	}
+	return sorts, nil
+}
+
+// applyClientFilter agrega al query las condiciones del filtro
+func applyClientFilter(db *gorm.DB, f clientFilter) *gorm.DB {
+	if f.LastName != "" {
+		db = db.Where(`last_name LIKE ? ESCAPE '\'`, escapeLike(f.LastName)+"%")
+	}
+	if f.EmailDomain != "" {
+		db = db.Where(`LOWER(email) LIKE ? ESCAPE '\'`, "%@"+escapeLike(f.EmailDomain))
+	}
+	if f.TelephonePrefix != "" {
+		db = db.Where(`telephone LIKE ? ESCAPE '\'`, escapeLike(f.TelephonePrefix)+"%")
+	}
+	today := models.Now()
+	if f.AgeMin != nil {
+		db = db.Where("birth_day <= ?", models.LatestBirthDay(*f.AgeMin, today))
+	}
+	if f.AgeMax != nil {
+		db = db.Where("birth_day > ?", models.LatestBirthDay(*f.AgeMax+1, today))
+	}
+	if f.BirthDayFrom != nil {
+		db = db.Where("birth_day >= ?", *f.BirthDayFrom)
+	}
+	if f.BirthDayTo != nil {
+		db = db.Where("birth_day < ?", f.BirthDayTo.AddDate(0, 0, 1))
+	}
+	return db
+}
+
+// applyClientSort ordena el query; invertido recorre el orden al revés (página anterior)
+func applyClientSort(db *gorm.DB, sorts []clientSort, reversed bool) *gorm.DB {
+	for _, s := range sorts {
+		dir := "ASC"
+		if s.Desc != reversed {
+			dir = "DESC"
+		}
+		db = db.Order(s.Column + " " + dir)
+	}
+	return db
+}
+
+// applyClientCursor restringe el query a los registros posteriores (o anteriores) al cursor
+func applyClientCursor(db *gorm.DB, sorts []clientSort, cur *clientCursor) (*gorm.DB, error) {
+	values := make([]interface{}, len(sorts))
+	for i, s := range sorts {
+		if s.Column == "id" {
+			values[i] = cur.ID
+			continue
+		}
+		if s.Column == "birth_day" {
+			t, err := time.Parse(time.RFC3339Nano, cur.Values[i])
+			if err != nil {
+				return nil, errors.New("invalid cursor")
+			}
+			values[i] = t.UTC()
+			continue
+		}
+		values[i] = cur.Values[i]
+	}
+
+	var clauses []string
+	var args []interface{}
+	for i, s := range sorts {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, sorts[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if s.Desc != cur.Backward {
+			op = "<"
+		}
+		parts = append(parts, s.Column+" "+op+" ?")
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return db.Where("("+strings.Join(clauses, " OR ")+")", args...), nil
+}
+
+// encodeClientCursor serializa la posición de un cliente según el orden aplicado
+func encodeClientCursor(sorts []clientSort, client models.Client, backward bool) string {
+	raw, _ := json.Marshal(newClientCursor(sorts, client, backward))
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// newClientCursor arma el cursor que apunta a client dentro del orden sorts
+func newClientCursor(sorts []clientSort, client models.Client, backward bool) *clientCursor {
+	cur := clientCursor{ID: client.ID, Backward: backward}
+	for _, s := range sorts {
+		var v string
+		switch s.Column {
+		case "name":
+			v = client.Name
+		case "last_name":
+			v = client.LastName
+		case "email":
+			v = client.Email
+		case "birth_day":
+			v = client.BirthDay.UTC().Format(time.RFC3339Nano)
+		}
+		cur.Values = append(cur.Values, v)
+	}
+	return &cur
+}
+
+func decodeClientCursor(raw string, sortCount int) (*clientCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cur clientCursor
+	if err := json.Unmarshal(data, &cur); err != nil || len(cur.Values) != sortCount {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// setLinkHeader publica los enlaces de paginación según RFC 8288
+func setLinkHeader(c echo.Context, links map[string]url.Values) {
+	base := *c.Request().URL
+	if c.Request().Host != "" {
+		base.Scheme = c.Scheme()
+		base.Host = c.Request().Host
+	}
+
+	var parts []string
+	for _, rel := range []string{"first", "prev", "next"} {
+		params, ok := links[rel]
+		if !ok {
+			continue
+		}
+		u := base
+		u.RawQuery = params.Encode()
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+	}
+	if len(parts) > 0 {
+		c.Response().Header().Set("Link", strings.Join(parts, ", "))
+	}
+}
+
+// withQuery copia los parámetros de la petición reemplazando los indicados ("" los elimina)
+func withQuery(c echo.Context, overrides map[string]string) url.Values {
+	params := url.Values{}
+	for k, v := range c.QueryParams() {
+		params[k] = append([]string(nil), v...)
+	}
+	for k, v := range overrides {
+		if v == "" {
+			params.Del(k)
+		} else {
+			params.Set(k, v)
+		}
+	}
+	return params
+}
+
+func parseOptionalInt(c echo.Context, name string) (*int, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return &v, nil
+}
+
+func parseOptionalDate(c echo.Context, name string) (*time.Time, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+	}
+	return &t, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
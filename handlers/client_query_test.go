@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func seedListClients(t *testing.T) {
+	clients := []models.Client{
+		{Name: "John", LastName: "Doe", Email: "john.doe@example.com", BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Age: 33, Telephone: "600123456"},
+		{Name: "Jane", LastName: "Smith", Email: "jane.smith@corp.com", BirthDay: time.Date(1985, time.February, 14, 0, 0, 0, 0, time.UTC), Age: 39, Telephone: "911234567"},
+		{Name: "Alice", LastName: "Johnson", Email: "alice@example.com", BirthDay: time.Date(1995, time.March, 30, 0, 0, 0, 0, time.UTC), Age: 29, Telephone: "600987654"},
+		{Name: "Bob", LastName: "Dorian", Email: "bob@corp.com", BirthDay: time.Date(1970, time.July, 7, 0, 0, 0, 0, time.UTC), Age: 54, Telephone: "933334444"},
+		{Name: "Carol", LastName: "Smith", Email: "carol@example.com", BirthDay: time.Date(2000, time.December, 24, 0, 0, 0, 0, time.UTC), Age: 23, Telephone: "655555555"},
+	}
+	for _, client := range clients {
+		if err := config.DB.Create(&client).Error; err != nil {
+			t.Fatalf("Error al insertar el cliente: %v", err)
+		}
+	}
+	models.RebuildClientAgeStats(config.DB)
+}
+
+func listClients(t *testing.T, query string) (*httptest.ResponseRecorder, ClientPage) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var page ClientPage
+	if assert.NoError(t, GetAll(c)) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	}
+	return rec, page
+}
+
+func clientNames(page ClientPage) []string {
+	names := []string{}
+	for _, client := range page.Data {
+		names = append(names, client.Name)
+	}
+	return names
+}
+
+func TestGetAllFilters(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"Last name prefix", "last_name=smi", []string{"Jane", "Carol"}},
+		{"Email domain", "email_domain=corp.com", []string{"Jane", "Bob"}},
+		{"Telephone prefix", "telephone_prefix=600", []string{"John", "Alice"}},
+		{"International telephone prefix", "telephone_prefix=%2B34%20600", []string{"John", "Alice"}},
+		{"Other country telephone prefix", "telephone_prefix=0039", []string{}},
+		{"Birth day range", "birth_day_from=1985-02-14&birth_day_to=1990-01-01", []string{"John", "Jane"}},
+		{"Combined filters", "email_domain=example.com&telephone_prefix=6&sort=-birth_day", []string{"Carol", "Alice", "John"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, page := listClients(t, tt.query)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.expected, clientNames(page))
+			assert.Equal(t, int64(len(tt.expected)), page.Total)
+		})
+	}
+}
+
+func TestGetAllAgeFilter(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	today := models.Now()
+	birthday := func(years, days int) time.Time {
+		d := time.Date(today.Year()-years, today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+		return d.AddDate(0, 0, days)
+	}
+	config.DB.Create(&models.Client{Name: "Turns30Today", LastName: "A", Email: "a@example.com", BirthDay: birthday(30, 0), Telephone: "600000001"})
+	config.DB.Create(&models.Client{Name: "Turns30Tomorrow", LastName: "B", Email: "b@example.com", BirthDay: birthday(30, 1), Telephone: "600000002"})
+	config.DB.Create(&models.Client{Name: "Turns41Tomorrow", LastName: "C", Email: "c@example.com", BirthDay: birthday(41, 1), Telephone: "600000003"})
+
+	_, page := listClients(t, "age_min=30&age_max=40")
+	assert.Equal(t, []string{"Turns30Today", "Turns41Tomorrow"}, clientNames(page))
+}
+
+func TestGetAllSort(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	_, page := listClients(t, "sort=last_name,-name")
+	assert.Equal(t, []string{"John", "Bob", "Alice", "Jane", "Carol"}, clientNames(page))
+
+	_, page = listClients(t, "sort=-last_name,age")
+	assert.Equal(t, []string{"Carol", "Jane", "Alice", "Bob", "John"}, clientNames(page))
+
+	rec, _ := listClients(t, "sort=telephone")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, _ = listClients(t, "telephone_prefix=abc")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetAllOffsetPagination(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	rec, page := listClients(t, "limit=2&offset=2")
+	assert.Equal(t, []string{"Alice", "Bob"}, clientNames(page))
+	assert.Equal(t, int64(5), page.Total)
+	assert.Equal(t, 2, page.Limit)
+	assert.Equal(t, 2, page.Offset)
+
+	link := rec.Header().Get("Link")
+	assert.Contains(t, link, `offset=4>; rel="next"`)
+	assert.Contains(t, link, `offset=0>; rel="prev"`)
+	assert.Contains(t, link, `rel="first"`)
+
+	rec, page = listClients(t, "limit=2&offset=4")
+	assert.Equal(t, []string{"Carol"}, clientNames(page))
+	assert.NotContains(t, rec.Header().Get("Link"), `rel="next"`)
+}
+
+func TestGetAllCursorPagination(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	var seen []string
+	_, page := listClients(t, "limit=2&sort=-birth_day")
+	seen = append(seen, clientNames(page)...)
+	for page.NextCursor != "" {
+		rec, next := listClients(t, "limit=2&sort=-birth_day&cursor="+url.QueryEscape(page.NextCursor))
+		assert.Contains(t, rec.Header().Get("Link"), `rel="prev"`)
+		seen = append(seen, clientNames(next)...)
+		page = next
+	}
+	assert.Equal(t, []string{"Carol", "Alice", "John", "Jane", "Bob"}, seen)
+
+	rec, prev := listClients(t, "limit=2&sort=-birth_day&cursor="+url.QueryEscape(page.PrevCursor))
+	assert.Equal(t, []string{"John", "Jane"}, clientNames(prev))
+	assert.True(t, strings.Contains(rec.Header().Get("Link"), `rel="next"`))
+
+	rec, _ = listClients(t, "cursor=not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
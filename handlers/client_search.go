@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Pesos de bm25 por columna del índice: name, last_name, email, telephone
+const clientSearchRank = "bm25(clients_fts, 10.0, 10.0, 5.0, 2.0)"
+
+// SearchClients busca clientes por texto libre
+// @Summary Buscar clientes
+// @Description Busca clientes por nombre, apellido, email o teléfono. Cada término se compara por prefijo y los resultados se ordenan por relevancia
+// @Tags Clientes
+// @Produce json
+// @Param q query string true "Texto a buscar"
+// @Param limit query int false "Cantidad máxima de resultados (por defecto 50, máximo 500)"
+// @Param offset query int false "Cantidad de resultados a saltar"
+// @Success 200 {object} ClientPage "Clientes encontrados"
+// @Router /api/v1/clients/search [get]
+func SearchClients(c echo.Context) error {
+	terms := searchTerms(c.QueryParam("q"))
+	if len(terms) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Query parameter q is required"})
+	}
+
+	limit, offset := defaultPageLimit, 0
+	var err error
+	if v := c.QueryParam("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+		}
+		limit = min(limit, maxPageLimit)
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "offset must be a non-negative integer"})
+		}
+	}
+
+	var base, ranked *gorm.DB
+	if config.ClientSearchEnabled {
+		base = config.DB.Model(&models.Client{}).
+			Joins("JOIN clients_fts ON clients_fts.rowid = clients.id").
+			Where("clients_fts MATCH ?", ftsQuery(terms)).
+			Session(&gorm.Session{})
+		ranked = base.Order(clientSearchRank).Order("clients.id")
+	} else {
+		base = likeSearch(config.DB.Model(&models.Client{}), terms).Session(&gorm.Session{})
+		ranked = base.Order("clients.last_name").Order("clients.name").Order("clients.id")
+	}
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	clients := []models.Client{}
+	if err := ranked.Select("clients.*").Offset(offset).Limit(limit).Find(&clients).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, ClientPage{Data: clients, Total: total, Limit: limit, Offset: offset})
+}
+
+// searchTerms separa el texto de búsqueda en términos, descartando signos sueltos
+func searchTerms(q string) []string {
+	var terms []string
+	for _, field := range strings.Fields(q) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// ftsQuery arma una consulta FTS5 en la que cada término es una frase con búsqueda por prefijo.
+// Un término numérico también busca el teléfono con el código del país por defecto
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+		if phone, ok := nationalPhonePrefix(term); ok {
+			quoted[i] = "(" + quoted[i] + ` OR "` + strings.TrimPrefix(phone, "+") + `"*)`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// nationalPhonePrefix devuelve el comienzo en E.164 de un término formado solo por dígitos
+func nationalPhonePrefix(term string) (string, bool) {
+	if strings.IndexFunc(term, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return "", false
+	}
+	return models.PhonePrefix(term, models.DefaultPhoneRegion), true
+}
+
+// likeSearch es la alternativa sin FTS5: cada término debe ser prefijo de alguna columna o palabra del email
+func likeSearch(db *gorm.DB, terms []string) *gorm.DB {
+	for _, term := range terms {
+		prefix := escapeLike(term) + "%"
+		word := "%." + prefix
+		phone := prefix
+		if national, ok := nationalPhonePrefix(term); ok {
+			phone = escapeLike(national) + "%"
+		}
+		db = db.Where(`(clients.name LIKE ? ESCAPE '\' OR clients.last_name LIKE ? ESCAPE '\' OR clients.email LIKE ? ESCAPE '\' OR clients.email LIKE ? ESCAPE '\' OR clients.email LIKE ? ESCAPE '\' OR clients.telephone LIKE ? ESCAPE '\' OR clients.telephone LIKE ? ESCAPE '\')`,
+			prefix, prefix, prefix, word, "%@"+prefix, prefix, phone)
+	}
+	return db
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func searchClients(t *testing.T, q string) (*httptest.ResponseRecorder, ClientPage) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/search?q="+url.QueryEscape(q), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var page ClientPage
+	if assert.NoError(t, SearchClients(c)) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	}
+	return rec, page
+}
+
+func TestSearchClients(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"Name prefix", "joh", []string{"John", "Alice"}},
+		{"Several terms", "jo do", []string{"John"}},
+		{"Email domain", "corp", []string{"Jane", "Bob"}},
+		{"Telephone prefix", "6009", []string{"Alice"}},
+		{"International telephone prefix", "+346009", []string{"Alice"}},
+		{"No match", "zzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, page := searchClients(t, tt.query)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.ElementsMatch(t, tt.expected, clientNames(page))
+			assert.Equal(t, int64(len(tt.expected)), page.Total)
+		})
+	}
+
+	rec, _ := searchClients(t, "  ")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSearchClientsFollowsChanges(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	var client models.Client
+	config.DB.Where("email = ?", "bob@corp.com").First(&client)
+	client.LastName = "Marley"
+	config.DB.Save(&client)
+
+	_, page := searchClients(t, "dorian")
+	assert.Empty(t, page.Data)
+	_, page = searchClients(t, "marl")
+	assert.Equal(t, []string{"Bob"}, clientNames(page))
+
+	config.DB.Delete(&client)
+	_, page = searchClients(t, "marl")
+	assert.Empty(t, page.Data)
+}
+
+func TestSearchClientsRanking(t *testing.T) {
+	if !config.ClientSearchEnabled {
+		t.Skip("FTS5 is not available, run with -tags sqlite_fts5")
+	}
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	config.DB.Create(&models.Client{Name: "Ana", LastName: "Garcia", Email: "martin@example.com", Telephone: "600000001"})
+	config.DB.Create(&models.Client{Name: "Martin", LastName: "Martinez", Email: "mm@example.com", Telephone: "600000002"})
+
+	_, page := searchClients(t, "martin")
+	assert.Equal(t, []string{"Martin", "Ana"}, clientNames(page))
+}
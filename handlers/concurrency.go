@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golangApp/config"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	// errStaleVersion indica que el recurso cambió desde la versión que conoce el cliente
+	errStaleVersion = errors.New("resource has been modified by another request, reload it and retry")
+	// errIfMatchRequired indica que falta la cabecera If-Match en modo estricto
+	errIfMatchRequired = errors.New("If-Match header is required")
+)
+
+// versionETag es el ETag que representa una versión de un recurso
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag envía la versión del recurso en la cabecera ETag
+func setETag(c echo.Context, version int) {
+	c.Response().Header().Set("ETag", versionETag(version))
+}
+
+// checkIfMatch compara la cabecera If-Match con la versión actual del recurso.
+// Devuelve el código HTTP con el que responder cuando la precondición no se cumple
+func checkIfMatch(c echo.Context, version int) (int, error) {
+	header := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if header == "" {
+		if config.Settings.RequireIfMatch {
+			return http.StatusPreconditionRequired, errIfMatchRequired
+		}
+		return 0, nil
+	}
+	current := versionETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == current {
+			return 0, nil
+		}
+	}
+	return http.StatusPreconditionFailed, errStaleVersion
+}
+
+// claimVersion incrementa la versión de la fila id solo si sigue siendo expected.
+// Si otra petición la modificó desde que se leyó devuelve errStaleVersion
+func claimVersion(tx *gorm.DB, model interface{}, id, expected int) error {
+	res := tx.Unscoped().Model(model).Where("id = ? AND version = ?", id, expected).UpdateColumn("version", expected+1)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errStaleVersion
+	}
+	return nil
+}
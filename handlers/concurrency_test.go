@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestClientOptimisticConcurrency(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	client := models.Client{Name: "John", LastName: "Doe", Email: "john.etag@example.com", BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"}
+	config.DB.Create(&client)
+	id := fmt.Sprintf("%d", client.ID)
+
+	call := func(method, body, ifMatch string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/clients/"+id, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		assert.NoError(t, handler(c))
+		return rec
+	}
+	body := `{"name":"%s","last_name":"Doe","email":"john.etag@example.com","birth_day":"1990-01-01T00:00:00Z","telephone":"600123456"}`
+
+	rec := call(http.MethodGet, "", "", GetClient)
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// El primer agente guarda con la versión que leyó; el segundo queda desactualizado
+	rec = call(http.MethodPut, fmt.Sprintf(body, "First"), etag, UpdateClient)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	rec = call(http.MethodPut, fmt.Sprintf(body, "Second"), etag, UpdateClient)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = call(http.MethodDelete, "", etag, DeleteClient)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	var stored models.Client
+	config.DB.First(&stored, client.ID)
+	assert.Equal(t, "First", stored.Name)
+	assert.Equal(t, 2, stored.Version)
+
+	// Sin modo estricto la cabecera es opcional; con él se responde 428
+	rec = call(http.MethodPut, fmt.Sprintf(body, "Third"), "", UpdateClient)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	config.Settings.RequireIfMatch = true
+	defer func() { config.Settings.RequireIfMatch = false }()
+	rec = call(http.MethodPatch, `{"name":"Fourth"}`, "", PatchClient)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	rec = call(http.MethodDelete, "", "", DeleteClient)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	rec = call(http.MethodDelete, "", `"1", "3"`, DeleteClient)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestUserOptimisticConcurrency(t *testing.T) {
+	setupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.User{})
+
+	user := models.User{Username: "etag", Email: "etag@example.com", Password: "secret", Version: 7}
+	config.DB.Create(&user)
+	assert.Equal(t, 1, user.Version, "La versión de un alta siempre empieza en 1")
+	id := fmt.Sprintf("%d", user.ID)
+
+	call := func(method, target, ifMatch string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, target, nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		assert.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(http.MethodGet, "/users/"+id, "", GetUser)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	rec = call(http.MethodPut, "/users/"+id+"/disable", `"1"`, DisableUser)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = call(http.MethodPut, "/users/"+id+"/enable", `"1"`, EnableUser)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	rec = call(http.MethodDelete, "/users/"+id, `*`, DeleteUser)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -52,7 +52,10 @@ func GetAllGroups(c echo.Context) error {
 // @Description Crea un nuevo grupo en la base de datos
 // @Tags Group
 // @Param group body models.Group true "Datos del grupo"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
 // @Success 201 {object} models.Group
+// @Failure 409 {object} map[string]string "Hay una petición en curso con la misma Idempotency-Key"
+// @Failure 422 {object} map[string]string "Idempotency-Key usada con otra petición"
 // @Router /groups [post]
 func CreateGroup(c echo.Context) error {
 	var group models.Group
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalMaxLineOctets es el largo máximo de una línea de iCalendar sin contar el CRLF (RFC 5545 3.1)
+const icalMaxLineOctets = 75
+
+// icalEvent es un evento de día completo el día Date o, si tiene End, un evento de Date a End que ocupa
+// tiempo en la agenda. Sequence cuenta las modificaciones del evento, para que los calendarios las apliquen
+type icalEvent struct {
+	UID         string
+	Date        time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Sequence    int
+	Cancelled   bool
+}
+
+// icalWriter escribe un calendario iCalendar (RFC 5545) evento a evento sobre w
+type icalWriter struct {
+	w     *bufio.Writer
+	stamp string
+}
+
+func newICalWriter(w io.Writer, name string, stamp time.Time) *icalWriter {
+	x := &icalWriter{w: bufio.NewWriter(w), stamp: stamp.UTC().Format("20060102T150405Z")}
+	x.line("BEGIN:VCALENDAR")
+	x.line("VERSION:2.0")
+	x.line("PRODID:-//golangApp//Client calendar//EN")
+	x.line("CALSCALE:GREGORIAN")
+	x.line("METHOD:PUBLISH")
+	x.line("X-WR-CALNAME:" + icalText(name))
+	x.line("X-PUBLISHED-TTL:PT12H")
+	return x
+}
+
+// WriteEvent agrega un evento. Los de día completo no ocupan tiempo en la agenda
+func (x *icalWriter) WriteEvent(e icalEvent) error {
+	x.line("BEGIN:VEVENT")
+	x.line("UID:" + e.UID)
+	x.line("DTSTAMP:" + x.stamp)
+	if e.End.IsZero() {
+		x.line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		x.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+	} else {
+		x.line("DTSTART:" + e.Date.UTC().Format("20060102T150405Z"))
+		x.line("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+	}
+	if e.Sequence > 0 {
+		x.line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+	}
+	x.line("SUMMARY:" + icalText(e.Summary))
+	if e.Description != "" {
+		x.line("DESCRIPTION:" + icalText(e.Description))
+	}
+	if e.Location != "" {
+		x.line("LOCATION:" + icalText(e.Location))
+	}
+	if e.Cancelled {
+		x.line("STATUS:CANCELLED")
+	}
+	if e.End.IsZero() {
+		x.line("TRANSP:TRANSPARENT")
+	} else {
+		x.line("TRANSP:OPAQUE")
+	}
+	return x.line("END:VEVENT")
+}
+
+// Flush envía lo escrito hasta el momento
+func (x *icalWriter) Flush() error {
+	return x.w.Flush()
+}
+
+// Close cierra el calendario
+func (x *icalWriter) Close() error {
+	x.line("END:VCALENDAR")
+	return x.w.Flush()
+}
+
+// line escribe una línea terminada en CRLF, partiéndola en líneas de continuación que empiezan con un
+// espacio si supera los 75 octetos, sin cortar caracteres UTF-8
+func (x *icalWriter) line(s string) error {
+	limit := icalMaxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		x.w.WriteString(s[:cut])
+		x.w.WriteString("\r\n ")
+		s = s[cut:]
+		// El espacio inicial cuenta dentro del largo de la línea de continuación
+		limit = icalMaxLineOctets - 1
+	}
+	x.w.WriteString(s)
+	_, err := x.w.WriteString("\r\n")
+	return err
+}
+
+// icalText escapa un valor de texto de iCalendar
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	// errPatchTestFailed indica que una operación test de JSON Patch no se cumplió
+	errPatchTestFailed = errors.New("test operation failed")
+	// errPatchInvalid indica un documento de patch mal formado
+	errPatchInvalid = errors.New("invalid patch document")
+)
+
+// patchOperation es una operación de JSON Patch (RFC 6902)
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyMergePatch aplica un JSON Merge Patch (RFC 7396) sobre doc
+func applyMergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", errPatchInvalid, err)
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergePatch(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// applyJSONPatch aplica una lista de operaciones JSON Patch (RFC 6902) sobre doc.
+// Si alguna operación falla no se aplica ninguna
+func applyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", errPatchInvalid, err)
+	}
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if target, err = applyPatchOperation(target, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyPatchOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", errPatchInvalid)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		// Un value null llega como el literal null; solo una operación sin value queda vacía
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("%w: missing value", errPatchInvalid)
+		}
+		var v interface{}
+		if err := json.Unmarshal(op.Value, &v); err != nil {
+			return nil, fmt.Errorf("%w: %v", errPatchInvalid, err)
+		}
+		return v, nil
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", errPatchInvalid)
+		}
+		return parsePointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "remove":
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "move":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(src) && reflect.DeepEqual(path[:len(src)], src) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, v, err := pointerRemove(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "copy":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopy(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errPatchTestFailed, err)
+		}
+		if !reflect.DeepEqual(current, v) {
+			return nil, fmt.Errorf("%w: value at %s differs", errPatchTestFailed, *op.Path)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", errPatchInvalid, op.Op)
+	}
+}
+
+// parsePointer separa un JSON Pointer (RFC 6901) en sus tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", errPatchInvalid, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			current = v
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+	}
+	return current, nil
+}
+
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		i := len(node)
+		if last != "-" {
+			if i, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return replaceAt(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("cannot add to %q", strings.Join(path, "/"))
+	}
+}
+
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q not found", last)
+		}
+		delete(node, last)
+		return doc, v, nil
+	case []interface{}:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		doc, err = replaceAt(doc, path[:len(path)-1], node)
+		return doc, v, err
+	default:
+		return nil, nil, fmt.Errorf("path member %q not found", last)
+	}
+}
+
+// replaceAt reemplaza el valor en path; necesario porque append puede realocar los arrays
+func replaceAt(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = value
+	}
+	return doc, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max {
+		return 0, fmt.Errorf("array index %q out of bounds", token)
+	}
+	return i, nil
+}
+
+func deepCopy(v interface{}) interface{} {
+	raw, _ := json.Marshal(v)
+	var out interface{}
+	json.Unmarshal(raw, &out)
+	return out
+}
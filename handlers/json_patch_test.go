@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"Replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"Add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"Remove member with null", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"Nested object", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"Arrays are replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"Non object patch replaces document", `{"a":"b"}`, `["c"]`, `["c"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := applyMergePatch([]byte(tt.doc), []byte(tt.patch))
+			if assert.NoError(t, err) {
+				assert.JSONEq(t, tt.expected, string(result))
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		err      error
+	}{
+		{"Add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`, nil},
+		{"Add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"Append array element", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`, nil},
+		{"Remove member", `{"foo":"bar","baz":"qux"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"Remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"Replace member", `{"foo":"bar"}`, `[{"op":"replace","path":"/foo","value":"qux"}]`, `{"foo":"qux"}`, nil},
+		{"Replace member with null", `{"foo":"bar","baz":"qux"}`, `[{"op":"replace","path":"/foo","value":null}]`, `{"foo":null,"baz":"qux"}`, nil},
+		{"Add null member", `{}`, `[{"op":"add","path":"/foo","value":null},{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`, nil},
+		{"Move member", `{"foo":{"bar":"baz"},"qux":{}}`, `[{"op":"move","from":"/foo/bar","path":"/qux/thud"}]`, `{"foo":{},"qux":{"thud":"baz"}}`, nil},
+		{"Copy member", `{"foo":{"bar":"baz"}}`, `[{"op":"copy","from":"/foo","path":"/qux"}]`, `{"foo":{"bar":"baz"},"qux":{"bar":"baz"}}`, nil},
+		{"Escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`, nil},
+		{"Test succeeds", `{"foo":"bar","n":1}`, `[{"op":"test","path":"/foo","value":"bar"},{"op":"test","path":"/n","value":1.0}]`, `{"foo":"bar","n":1}`, nil},
+		{"Test fails", `{"foo":"bar"}`, `[{"op":"test","path":"/foo","value":"qux"}]`, "", errPatchTestFailed},
+		{"Test on missing path fails", `{"foo":"bar"}`, `[{"op":"test","path":"/baz","value":"qux"}]`, "", errPatchTestFailed},
+		{"Unknown operation", `{}`, `[{"op":"frobnicate","path":"/a"}]`, "", errPatchInvalid},
+		{"Missing value", `{}`, `[{"op":"add","path":"/a"}]`, "", errPatchInvalid},
+		{"Malformed document", `{}`, `{"op":"add"}`, "", errPatchInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := applyJSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "unexpected error: %v", err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.JSONEq(t, tt.expected, string(result))
+			}
+		})
+	}
+
+	_, err := applyJSONPatch([]byte(`{"foo":"bar"}`), []byte(`[{"op":"remove","path":"/missing"}]`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var errNotEnoughPoints = errors.New("Not enough points")
+
+// LoyaltyBalance es el saldo de puntos de un cliente. earned, redeemed y expired son los totales históricos
+// acumulados, canjeados y vencidos, y transferred los puntos recibidos por fusiones; lots son los puntos
+// vigentes de cada acumulación o traspaso recibido
+type LoyaltyBalance struct {
+	ClientID    int                 `json:"client_id"`
+	Points      int64               `json:"points"`
+	Earned      int64               `json:"earned"`
+	Redeemed    int64               `json:"redeemed"`
+	Expired     int64               `json:"expired"`
+	Transferred int64               `json:"transferred"`
+	Lots        []models.LoyaltyLot `json:"lots"`
+}
+
+// LoyaltyStatementEntry es un movimiento del extracto con el saldo que dejó
+type LoyaltyStatementEntry struct {
+	models.LoyaltyEntry
+	Balance int64 `json:"balance"`
+}
+
+// LoyaltyStatement es el extracto de puntos de un cliente entre dos días, con el saldo al comienzo y al final
+type LoyaltyStatement struct {
+	ClientID       int                     `json:"client_id"`
+	OpeningBalance int64                   `json:"opening_balance"`
+	ClosingBalance int64                   `json:"closing_balance"`
+	Entries        []LoyaltyStatementEntry `json:"entries"`
+}
+
+// LoyaltyRedemptionRequest es un canje de puntos, opcionalmente aplicado a un pedido del cliente
+type LoyaltyRedemptionRequest struct {
+	Points      int64  `json:"points" example:"500"`
+	Description string `json:"description" example:"Descuento de 5 € en caja"`
+	OrderID     *int   `json:"order_id"`
+}
+
+// GetLoyaltyRules lista las reglas de acumulación de puntos
+// @Summary Listar reglas de fidelidad
+// @Description Devuelve las reglas con las que los pedidos suman puntos de fidelidad
+// @Tags Fidelidad
+// @Produce json
+// @Success 200 {array} models.LoyaltyRule "Reglas de fidelidad"
+// @Router /api/v1/loyalty/rules [get]
+func GetLoyaltyRules(c echo.Context) error {
+	rules := []models.LoyaltyRule{}
+	if err := config.DB.Order("name").Find(&rules).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, rules)
+}
+
+// CreateLoyaltyRule crea una regla de acumulación de puntos
+// @Summary Crear regla de fidelidad
+// @Description Crea una regla de acumulación. Un pedido suma, por cada regla que cumple, points_per_euro puntos por euro del total, redondeando hacia abajo, más bonus_points. channel, store_id, min_total_cents, starts_on y ends_on son condiciones opcionales
+// @Tags Fidelidad
+// @Accept json
+// @Produce json
+// @Param rule body models.LoyaltyRule true "Información de la Regla"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.LoyaltyRule "Regla creada"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 409 {object} map[string]string "Ya existe una regla con ese nombre"
+// @Router /api/v1/loyalty/rules [post]
+func CreateLoyaltyRule(c echo.Context) error {
+	var rule models.LoyaltyRule
+	if err := c.Bind(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	rule.ID = 0
+	if errs := checkLoyaltyRule(&rule); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Create(&rule).Error; err != nil {
+		return uniqueNameFailed(c, err, "loyalty_rules", "A loyalty rule with that name already exists")
+	}
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateLoyaltyRule actualiza una regla de acumulación de puntos
+// @Summary Actualizar regla de fidelidad
+// @Description Actualiza una regla de acumulación; para retirarla se le pone ends_on. Los cambios solo se aplican a los pedidos que se registren después
+// @Tags Fidelidad
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la Regla"
+// @Param rule body models.LoyaltyRule true "Información actualizada de la Regla"
+// @Success 200 {object} models.LoyaltyRule "Regla actualizada"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Regla no encontrada"
+// @Failure 409 {object} map[string]string "Ya existe una regla con ese nombre"
+// @Router /api/v1/loyalty/rules/{id} [put]
+func UpdateLoyaltyRule(c echo.Context) error {
+	var rule models.LoyaltyRule
+	if err := config.DB.First(&rule, c.Param("id")).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Loyalty rule not found"})
+	}
+	before := rule
+	if err := c.Bind(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	rule.ID, rule.CreatedAt = before.ID, before.CreatedAt
+	if errs := checkLoyaltyRule(&rule); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Save(&rule).Error; err != nil {
+		return uniqueNameFailed(c, err, "loyalty_rules", "A loyalty rule with that name already exists")
+	}
+	return c.JSON(http.StatusOK, rule)
+}
+
+// GetClientLoyaltyBalance devuelve el saldo de puntos de un cliente
+// @Summary Saldo de puntos
+// @Description Devuelve los puntos vigentes del cliente, los totales acumulados, canjeados y vencidos, y cuándo vence cada parte del saldo. Antes de calcularlo registra los vencimientos pendientes
+// @Tags Fidelidad
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Success 200 {object} LoyaltyBalance "Saldo de puntos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/loyalty [get]
+func GetClientLoyaltyBalance(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	entries, err := settledLoyaltyEntries(client.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	balance := LoyaltyBalance{ClientID: client.ID, Lots: []models.LoyaltyLot{}}
+	for _, e := range entries {
+		balance.Points += e.Points
+		switch e.Kind {
+		case models.LoyaltyAccrual:
+			balance.Earned += e.Points
+		case models.LoyaltyRedemption:
+			balance.Redeemed -= e.Points
+		case models.LoyaltyExpiry:
+			balance.Expired -= e.Points
+		case models.LoyaltyTransfer:
+			balance.Transferred += e.Points
+		}
+	}
+	lots, _ := models.LoyaltyLots(entries)
+	for _, lot := range lots {
+		if lot.Points > 0 && lot.ValidAt(models.Now()) {
+			balance.Lots = append(balance.Lots, lot)
+		}
+	}
+	return c.JSON(http.StatusOK, balance)
+}
+
+// GetClientLoyaltyStatement devuelve el extracto de puntos de un cliente
+// @Summary Extracto de puntos
+// @Description Devuelve los movimientos de puntos del cliente en orden cronológico, cada uno con el saldo que dejó, y el saldo al comienzo y al final del período. from y to filtran por el día del movimiento en UTC; sin ellos el extracto abarca todo el historial. Los vencimientos figuran en la fecha en que vencieron los puntos
+// @Tags Fidelidad
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param from query string false "Movimientos desde este día (YYYY-MM-DD)"
+// @Param to query string false "Movimientos hasta este día inclusive (YYYY-MM-DD)"
+// @Success 200 {object} LoyaltyStatement "Extracto de puntos"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/loyalty/statement [get]
+func GetClientLoyaltyStatement(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	from, err := parseOptionalDate(c, "from")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	to, err := parseOptionalDate(c, "to")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+	}
+	entries, err := settledLoyaltyEntries(client.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	statement := LoyaltyStatement{ClientID: client.ID, Entries: []LoyaltyStatementEntry{}}
+	var balance int64
+	for _, e := range entries {
+		day := e.CreatedAt.UTC().Format("2006-01-02")
+		if to != nil && day > to.Format("2006-01-02") {
+			break
+		}
+		balance += e.Points
+		switch {
+		case from != nil && day < from.Format("2006-01-02"):
+			statement.OpeningBalance = balance
+		case e.Points != 0:
+			// Las acumulaciones canjeadas por completo se cierran con vencimientos de 0 puntos que no se listan
+			statement.Entries = append(statement.Entries, LoyaltyStatementEntry{LoyaltyEntry: e, Balance: balance})
+		}
+	}
+	statement.ClosingBalance = balance
+	return c.JSON(http.StatusOK, statement)
+}
+
+// RedeemClientPoints canjea puntos de un cliente
+// @Summary Canjear puntos
+// @Description Descuenta puntos del saldo del cliente, empezando por los que vencen antes. order_id es opcional y vincula el canje a un pedido del cliente. Si el saldo vigente no alcanza no se canjea nada
+// @Tags Fidelidad
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param redemption body LoyaltyRedemptionRequest true "Puntos a canjear"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.LoyaltyEntry "Canje registrado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Failure 409 {object} map[string]string "El saldo no alcanza"
+// @Router /api/v1/clients/{id}/loyalty/redemptions [post]
+func RedeemClientPoints(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	var req LoyaltyRedemptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	var errs models.ValidationErrors
+	if req.Points <= 0 {
+		errs = append(errs, models.FieldError{Field: "points", Code: models.CodeOutOfRange, Message: "Points must be positive"})
+	}
+	if req.OrderID != nil {
+		if err := config.DB.Where("client_id = ?", client.ID).Select("id").First(&models.Order{}, *req.OrderID).Error; err != nil {
+			errs = append(errs, models.FieldError{Field: "order_id", Code: models.CodeNotFound, Message: "Order not found among the client's orders"})
+		}
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	entry := models.LoyaltyEntry{
+		ClientID:    client.ID,
+		Kind:        models.LoyaltyRedemption,
+		Points:      -req.Points,
+		OrderID:     req.OrderID,
+		Description: strings.TrimSpace(req.Description),
+		Actor:       currentActor(c),
+	}
+	if entry.Description == "" {
+		entry.Description = "Points redeemed"
+	}
+	// El canje se registra antes de comprobar el saldo para que dos canjes simultáneos no gasten los mismos puntos
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := models.ExpireLoyaltyPoints(tx, client.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		entries, err := models.LoadLoyaltyEntries(tx, client.ID)
+		if err != nil {
+			return err
+		}
+		if _, uncovered := models.LoyaltyLots(entries); uncovered > 0 {
+			return errNotEnoughPoints
+		}
+		return nil
+	})
+	if errors.Is(err, errNotEnoughPoints) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, entry)
+}
+
+// checkLoyaltyRule valida la regla y que exista su tienda
+func checkLoyaltyRule(rule *models.LoyaltyRule) models.ValidationErrors {
+	errs := models.ValidateLoyaltyRule(rule)
+	if rule.StoreID != nil {
+		if err := config.DB.Select("id").First(&models.Store{}, *rule.StoreID).Error; err != nil {
+			errs = append(errs, models.FieldError{Field: "store_id", Code: models.CodeNotFound, Message: "Store not found"})
+		}
+	}
+	return errs
+}
+
+// settledLoyaltyEntries registra los vencimientos pendientes del cliente y devuelve su libro de puntos
+func settledLoyaltyEntries(clientID int) ([]models.LoyaltyEntry, error) {
+	if _, err := models.ExpireLoyaltyPoints(config.DB, clientID); err != nil {
+		return nil, err
+	}
+	return models.LoadLoyaltyEntries(config.DB, clientID)
+}
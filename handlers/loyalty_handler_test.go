@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func loyaltyRequest(t *testing.T, handler echo.HandlerFunc, method, query, body string, ids ...int) *httptest.ResponseRecorder {
+	return petRouteRequest(t, handler, "", method, query, body, ids...)
+}
+
+func cleanupLoyalty() {
+	config.DB.Exec("DELETE FROM loyalty_entries")
+	for _, model := range []interface{}{&models.OrderLine{}, &models.Order{}, &models.LoyaltyRule{}, &models.Store{}} {
+		config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model)
+	}
+	cleanupPets()
+}
+
+// ledger carga los movimientos en el libro de puntos de client
+func ledger(t *testing.T, client models.Client, entries ...models.LoyaltyEntry) []models.LoyaltyEntry {
+	for i := range entries {
+		entries[i].ClientID = client.ID
+		if err := config.DB.Create(&entries[i]).Error; err != nil {
+			t.Fatalf("Error al cargar el libro de puntos: %v", err)
+		}
+	}
+	return entries
+}
+
+func loyaltyBalance(t *testing.T, client models.Client) LoyaltyBalance {
+	rec := loyaltyRequest(t, GetClientLoyaltyBalance, http.MethodGet, "", "", client.ID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var balance LoyaltyBalance
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &balance))
+	return balance
+}
+
+func TestCreateClientOrderAccruesPoints(t *testing.T) {
+	setupTestDB()
+	defer cleanupLoyalty()
+	owner := createTestOwner(t, "john.doe@example.com")
+	store := models.Store{Name: "Centro", TimeZone: "Europe/Madrid"}
+	config.DB.Create(&store)
+
+	for _, body := range []string{
+		`{"name": "Base", "points_per_euro": 1}`,
+		`{"name": "Online", "channel": "online", "points_per_euro": 1}`,
+		`{"name": "Cesta grande", "min_total_cents": 5000, "bonus_points": 100, "ends_on": "2024-06-30T00:00:00Z"}`,
+	} {
+		rec := loyaltyRequest(t, CreateLoyaltyRule, http.MethodPost, "", body)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	rec := loyaltyRequest(t, CreateLoyaltyRule, http.MethodPost, "", `{"name": "Base", "bonus_points": 5}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = loyaltyRequest(t, CreateLoyaltyRule, http.MethodPost, "", `{"name": "Nada", "store_id": 999}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var invalid ValidationErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invalid))
+	assert.Equal(t, []string{"points_per_euro", "store_id"}, []string{invalid.Errors[0].Field, invalid.Errors[1].Field})
+
+	rec = loyaltyRequest(t, CreateClientOrder, http.MethodPost, "", `{"channel": "in_store", "lines": []}`, owner.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invalid))
+	assert.Len(t, invalid.Errors, 2)
+
+	rec = loyaltyRequest(t, CreateClientOrder, http.MethodPost, "", `{
+		"channel": "online", "discount_cents": 348, "client_id": 999, "points_earned": 1000000,
+		"lines": [{"product": "Pienso", "quantity": 2, "unit_price_cents": 2499}, {"product": "Snack", "quantity": 1, "unit_price_cents": 350}]
+	}`, owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var online models.Order
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &online))
+	assert.Equal(t, owner.ID, online.ClientID)
+	assert.Equal(t, []int64{5348, 5000}, []int64{online.SubtotalCents, online.TotalCents})
+	assert.Equal(t, int64(50+50+100), online.PointsEarned)
+	assert.True(t, online.PlacedAt.Equal(testNow))
+
+	rec = loyaltyRequest(t, CreateClientOrder, http.MethodPost, "", fmt.Sprintf(`{
+		"channel": "in_store", "store_id": %d, "placed_at": "2024-06-10T18:00:00+02:00",
+		"lines": [{"product": "Collar", "quantity": 1, "unit_price_cents": 1999}]
+	}`, store.ID), owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// El historial va del pedido más reciente al más antiguo y se puede acotar por día
+	rec = loyaltyRequest(t, GetClientOrders, http.MethodGet, "", "", owner.ID)
+	var orders []models.Order
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &orders))
+	if assert.Len(t, orders, 2) {
+		assert.Equal(t, online.ID, orders[0].ID)
+		assert.Equal(t, []string{"Pienso", "Snack"}, []string{orders[0].Lines[0].Product, orders[0].Lines[1].Product})
+		assert.Equal(t, int64(19), orders[1].PointsEarned)
+	}
+	rec = loyaltyRequest(t, GetClientOrders, http.MethodGet, "to=2024-06-10", "", owner.ID)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &orders))
+	assert.Len(t, orders, 1)
+	rec = loyaltyRequest(t, GetClientOrders, http.MethodGet, "from=junio", "", owner.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Los puntos vencen LOYALTY_POINTS_EXPIRY_MONTHS meses después de acumularse
+	balance := loyaltyBalance(t, owner)
+	assert.Equal(t, int64(219), balance.Points)
+	if assert.Len(t, balance.Lots, 2) {
+		assert.Equal(t, "2025-06-15", balance.Lots[0].ExpiresAt.Format("2006-01-02"))
+	}
+
+	// Retirar una regla no cambia los puntos ya acumulados
+	var rule models.LoyaltyRule
+	config.DB.Where("name = ?", "Online").First(&rule)
+	rec = loyaltyRequest(t, UpdateLoyaltyRule, http.MethodPut, "", `{"ends_on": "2024-06-01T00:00:00Z"}`, rule.ID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	config.DB.First(&rule, rule.ID)
+	assert.Equal(t, 1.0, rule.PointsPerEuro)
+	assert.Equal(t, int64(219), loyaltyBalance(t, owner).Points)
+}
+
+func TestRedeemClientPointsExpiresOldestFirst(t *testing.T) {
+	setupTestDB()
+	defer cleanupLoyalty()
+	owner := createTestOwner(t, "john.doe@example.com")
+	other := createTestOwner(t, "jane.doe@example.com")
+	otherOrder := models.Order{ClientID: other.ID, Channel: models.ChannelOnline, PlacedAt: testNow, Lines: []models.OrderLine{{Product: "Pienso", Quantity: 1}}}
+	config.DB.Create(&otherOrder)
+
+	at := func(s string) time.Time {
+		return *day(s)
+	}
+	// Al 15/06/2024 el primer lote venció el 01/06 con 60 puntos sin canjear
+	entries := ledger(t, owner,
+		models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 100, CreatedAt: at("2023-06-01"), ExpiresAt: day("2024-06-01")},
+		models.LoyaltyEntry{Kind: models.LoyaltyRedemption, Points: -40, CreatedAt: at("2024-01-10")},
+		models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 50, CreatedAt: at("2024-03-01"), ExpiresAt: day("2025-03-01")},
+	)
+
+	rec := loyaltyRequest(t, RedeemClientPoints, http.MethodPost, "", `{"points": 0}`, owner.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = loyaltyRequest(t, RedeemClientPoints, http.MethodPost, "", fmt.Sprintf(`{"points": 10, "order_id": %d}`, otherOrder.ID), owner.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "El pedido tiene que ser del cliente")
+
+	rec = loyaltyRequest(t, RedeemClientPoints, http.MethodPost, "", `{"points": 80}`, owner.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error": "Not enough points"}`, rec.Body.String())
+
+	rec = loyaltyRequest(t, RedeemClientPoints, http.MethodPost, "", `{"points": 30, "description": "Descuento en caja"}`, owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	balance := loyaltyBalance(t, owner)
+	assert.Equal(t, []int64{20, 150, 70, 60}, []int64{balance.Points, balance.Earned, balance.Redeemed, balance.Expired})
+	if assert.Len(t, balance.Lots, 1) {
+		assert.Equal(t, entries[2].ID, balance.Lots[0].EntryID)
+		assert.Equal(t, int64(20), balance.Lots[0].Points)
+	}
+
+	// El vencimiento figura en su fecha aunque se registrara después
+	rec = loyaltyRequest(t, GetClientLoyaltyStatement, http.MethodGet, "from=2024-01-01&to=2024-06-14", "", owner.ID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var statement LoyaltyStatement
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statement))
+	assert.Equal(t, []int64{100, 50}, []int64{statement.OpeningBalance, statement.ClosingBalance})
+	kinds, balances := []string{}, []int64{}
+	for _, e := range statement.Entries {
+		kinds = append(kinds, e.Kind)
+		balances = append(balances, e.Balance)
+	}
+	assert.Equal(t, []string{models.LoyaltyRedemption, models.LoyaltyAccrual, models.LoyaltyExpiry}, kinds)
+	assert.Equal(t, []int64{60, 110, 50}, balances)
+	assert.Equal(t, "2024-06-01", statement.Entries[2].CreatedAt.Format("2006-01-02"))
+
+	rec = loyaltyRequest(t, GetClientLoyaltyStatement, http.MethodGet, "", "", owner.ID)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statement))
+	assert.Len(t, statement.Entries, 5)
+	assert.Equal(t, []int64{0, 20}, []int64{statement.OpeningBalance, statement.ClosingBalance})
+	rec = loyaltyRequest(t, GetClientLoyaltyStatement, http.MethodGet, "from=2024-06-02&to=2024-06-01", "", owner.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// El libro solo admite altas
+	assert.ErrorIs(t, config.DB.Model(&entries[0]).Update("points", 1000).Error, models.ErrImmutableLedger)
+	assert.ErrorIs(t, config.DB.Delete(&entries[0]).Error, models.ErrImmutableLedger)
+}
+
+func TestGetClientKPILoyalty(t *testing.T) {
+	setupTestDB()
+	defer cleanupLoyalty()
+	ana := createTestOwner(t, "ana@example.com")
+	ben := createTestOwner(t, "ben@example.org")
+	createTestOwner(t, "eva@example.org")
+	for _, order := range []struct {
+		client models.Client
+		total  int64
+	}{{ana, 5000}, {ana, 1000}, {ben, 2000}} {
+		config.DB.Create(&models.Order{ClientID: order.client.ID, Channel: models.ChannelOnline, PlacedAt: testNow, SubtotalCents: order.total, TotalCents: order.total})
+	}
+	ledger(t, ana, models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 60, CreatedAt: testNow.AddDate(-1, 0, 0), ExpiresAt: day("2024-06-01")})
+	ledger(t, ana, models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 40, CreatedAt: testNow})
+	ledger(t, ben, models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 20, CreatedAt: testNow})
+	models.RebuildClientAgeStats(config.DB)
+
+	// Los puntos de ana vencieron sin que la tarea periódica lo registrara: el KPI registra el vencimiento al leer
+	rec, kpi := clientKPI(t, "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 26.67, *kpi.AverageSpend)
+	assert.Equal(t, int64(60), kpi.PointsOutstanding)
+	var entries int64
+	config.DB.Model(&models.LoyaltyEntry{}).Where("kind = ?", models.LoyaltyExpiry).Count(&entries)
+	assert.Equal(t, int64(1), entries)
+
+	rec, kpi = clientKPI(t, "email_domain=example.org")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 20.0, *kpi.AverageSpend)
+	assert.Equal(t, int64(20), kpi.PointsOutstanding)
+
+	rec, _ = clientKPI(t, "group_by=email_domain")
+	var groups ClientKPIGroups
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &groups))
+	if assert.Len(t, groups.Segments, 2) {
+		assert.Equal(t, 30.0, *groups.Segments[0].AverageSpend)
+		assert.Equal(t, int64(40), groups.Segments[0].PointsOutstanding)
+		assert.Equal(t, int64(2), groups.Segments[1].Count)
+		assert.Equal(t, 20.0, *groups.Segments[1].AverageSpend)
+	}
+
+	// Los agregados sin filtros coinciden con el cálculo sobre la tabla al eliminar y restaurar clientes
+	assertLoyaltyAggregates := func(step string) {
+		_, fast := clientKPI(t, "")
+		_, scan := clientKPI(t, "age_min=0")
+		assert.Equal(t, scan.ClientLoyaltyKPI, fast.ClientLoyaltyKPI, step)
+		diffs, err := models.VerifyClientLoyaltyStats(config.DB)
+		assert.NoError(t, err)
+		assert.Empty(t, diffs, step)
+	}
+	rec = loyaltyRequest(t, DeleteClient, http.MethodDelete, "", "", ana.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	_, kpi = clientKPI(t, "")
+	assert.Equal(t, []float64{20, 20}, []float64{*kpi.AverageSpend, float64(kpi.PointsOutstanding)})
+	assertLoyaltyAggregates("delete")
+	rec = loyaltyRequest(t, CreateClientOrder, http.MethodPost, "", `{"channel": "online", "lines": [{"product": "Pienso", "quantity": 1, "unit_price_cents": 4000}]}`, ben.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assertLoyaltyAggregates("order")
+	loyaltyRequest(t, RestoreClient, http.MethodPost, "", "", ana.ID)
+	assertLoyaltyAggregates("restore")
+	rec = loyaltyRequest(t, MergeClients, http.MethodPost, "", fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d]}`, ben.ID, ana.ID))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assertLoyaltyAggregates("merge")
+	_, kpi = clientKPI(t, "")
+	assert.Equal(t, int64(60), kpi.PointsOutstanding)
+
+	// Los puntos se descuentan en cuanto vencen, sin esperar a la tarea periódica, también con filtros
+	ledger(t, ben, models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 15, CreatedAt: testNow, ExpiresAt: day("2024-07-01")})
+	_, kpi = clientKPI(t, "")
+	assert.Equal(t, int64(75), kpi.PointsOutstanding)
+	defer func(now func() time.Time) { models.Now = now }(models.Now)
+	models.Now = func() time.Time { return *day("2024-07-02") }
+	_, kpi = clientKPI(t, "email_domain=example.org")
+	assert.Equal(t, int64(60), kpi.PointsOutstanding)
+	_, kpi = clientKPI(t, "")
+	assert.Equal(t, int64(60), kpi.PointsOutstanding)
+	assertLoyaltyAggregates("expiry")
+}
+
+func TestMergeClientsTransfersPoints(t *testing.T) {
+	setupTestDB()
+	defer cleanupLoyalty()
+	ana := createTestOwner(t, "ana@example.com")
+	ben := createTestOwner(t, "ben@example.com")
+	at := func(s string) time.Time {
+		return *day(s)
+	}
+	ledger(t, ana,
+		models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 100, CreatedAt: at("2023-06-01"), ExpiresAt: day("2024-06-01")},
+		models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 50, CreatedAt: at("2023-09-01"), ExpiresAt: day("2024-09-01")},
+		models.LoyaltyEntry{Kind: models.LoyaltyRedemption, Points: -20, CreatedAt: at("2024-01-10")},
+	)
+	ledger(t, ben, models.LoyaltyEntry{Kind: models.LoyaltyAccrual, Points: 40, CreatedAt: at("2024-03-01"), ExpiresAt: day("2025-03-01")})
+
+	rec := loyaltyRequest(t, MergeClients, http.MethodPost, "", fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d]}`, ben.ID, ana.ID))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Solo pasa lo que no venció, con su fecha de vencimiento
+	balance := loyaltyBalance(t, ben)
+	assert.Equal(t, []int64{90, 40, 50}, []int64{balance.Points, balance.Earned, balance.Transferred})
+	if assert.Len(t, balance.Lots, 2) {
+		assert.Equal(t, int64(50), balance.Lots[1].Points)
+		assert.Equal(t, "2024-09-01", balance.Lots[1].ExpiresAt.Format("2006-01-02"))
+	}
+
+	// El libro del fusionado se borra con él
+	entries, err := models.LoadLoyaltyEntries(config.DB, ana.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	diffs, err := models.VerifyClientLoyaltyStats(config.DB)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// Los puntos traspasados se canjean antes porque vencen antes, y vencen en su fecha
+	rec = loyaltyRequest(t, RedeemClientPoints, http.MethodPost, "", `{"points": 30}`, ben.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	balance = loyaltyBalance(t, ben)
+	assert.Equal(t, []int64{40, 20}, []int64{balance.Lots[0].Points, balance.Lots[1].Points})
+	defer func(now func() time.Time) { models.Now = now }(models.Now)
+	models.Now = func() time.Time { return at("2024-10-01") }
+	expired, err := models.ExpireLoyaltyPoints(config.DB, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), expired)
+	assert.Equal(t, int64(40), loyaltyBalance(t, ben).Points)
+}
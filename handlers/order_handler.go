@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// GetClientOrders lista el historial de compras de un cliente
+// @Summary Pedidos de un cliente
+// @Description Devuelve los pedidos del cliente con sus líneas, del más reciente al más antiguo. from y to filtran por el día del pedido en UTC
+// @Tags Fidelidad
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param from query string false "Pedidos desde este día (YYYY-MM-DD)"
+// @Param to query string false "Pedidos hasta este día inclusive (YYYY-MM-DD)"
+// @Success 200 {array} models.Order "Pedidos del cliente"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/orders [get]
+func GetClientOrders(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	query := withOrderLines(config.DB).Where("client_id = ?", client.ID)
+	for _, bound := range []struct{ name, op string }{{"from", ">="}, {"to", "<="}} {
+		day, err := parseOptionalDate(c, bound.name)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if day != nil {
+			query = query.Where("date(placed_at) "+bound.op+" ?", day.Format("2006-01-02"))
+		}
+	}
+	orders := []models.Order{}
+	if err := query.Order("placed_at DESC, id DESC").Find(&orders).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, orders)
+}
+
+// CreateClientOrder registra una compra de un cliente y le suma los puntos de fidelidad
+// @Summary Registrar pedido
+// @Description Registra un pedido con sus líneas. Los importes van en céntimos; el subtotal y el total se calculan con las líneas y discount_cents. store_id es obligatorio en los pedidos en tienda. placed_at es por defecto el momento del registro. El pedido suma los puntos de las reglas de fidelidad que cumple, que vencen LOYALTY_POINTS_EXPIRY_MONTHS meses después
+// @Tags Fidelidad
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param order body models.Order true "Información del Pedido"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.Order "Pedido registrado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/orders [post]
+func CreateClientOrder(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	var order models.Order
+	if err := c.Bind(&order); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	order.ID, order.ClientID, order.PointsEarned = 0, client.ID, 0
+	for i := range order.Lines {
+		order.Lines[i].ID = 0
+	}
+	if order.PlacedAt.IsZero() {
+		order.PlacedAt = models.Now()
+	}
+	order.ComputeTotals()
+
+	errs := models.ValidateOrder(&order)
+	if order.StoreID != nil {
+		if err := config.DB.Select("id").First(&models.Store{}, *order.StoreID).Error; err != nil {
+			errs = append(errs, models.FieldError{Field: "store_id", Code: models.CodeNotFound, Message: "Store not found"})
+		}
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var rules []models.LoyaltyRule
+		if err := tx.Find(&rules).Error; err != nil {
+			return err
+		}
+		order.PointsEarned = models.OrderPoints(rules, &order)
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		if order.PointsEarned == 0 {
+			return nil
+		}
+		return tx.Create(&models.LoyaltyEntry{
+			ClientID:    client.ID,
+			Kind:        models.LoyaltyAccrual,
+			Points:      order.PointsEarned,
+			OrderID:     &order.ID,
+			Description: "Order #" + strconv.Itoa(order.ID),
+			Actor:       currentActor(c),
+			ExpiresAt:   models.LoyaltyExpiresAt(models.Now()),
+		}).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, order)
+}
+
+// withOrderLines carga las líneas de los pedidos en el orden en que se registraron
+func withOrderLines(db *gorm.DB) *gorm.DB {
+	return db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
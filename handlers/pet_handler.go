@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// GetClientPets lista las mascotas de un cliente
+// @Summary Mascotas de un cliente
+// @Description Devuelve las mascotas de un cliente con su historial de pesos
+// @Tags Mascotas
+// @Param id path int true "ID del Cliente"
+// @Param include_deleted query bool false "Incluir mascotas eliminadas"
+// @Produce json
+// @Success 200 {array} models.Pet "Mascotas del cliente"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/pets [get]
+func GetClientPets(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	pets := []models.Pet{}
+	if err := petScope(c).Where("client_id = ?", client.ID).Order("id").Find(&pets).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, pets)
+}
+
+// GetClientPet obtiene una mascota de un cliente
+// @Summary Obtener mascota
+// @Description Recupera una mascota de un cliente con su historial de pesos
+// @Tags Mascotas
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param include_deleted query bool false "Incluir mascotas eliminadas"
+// @Produce json
+// @Success 200 {object} models.Pet "Detalles de la mascota"
+// @Failure 404 {object} map[string]string "Cliente o mascota no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id} [get]
+func GetClientPet(c echo.Context) error {
+	pet, err := findOwnedPet(c, petScope(c))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, pet)
+}
+
+// CreateClientPet registra una mascota de un cliente
+// @Summary Crear mascota
+// @Description Registra una mascota del cliente. El microchip tiene 15 dígitos y el último es un dígito de control Luhn; se aceptan espacios y guiones. weights es opcional y carga el historial de pesos inicial
+// @Tags Mascotas
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param pet body models.Pet true "Información de la Mascota"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.Pet "Mascota creada"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Failure 409 {object} map[string]string "El microchip ya está registrado en otra mascota"
+// @Router /api/v1/clients/{id}/pets [post]
+func CreateClientPet(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	var pet models.Pet
+	if err := c.Bind(&pet); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	pet.ID, pet.ClientID, pet.DeletedAt = 0, client.ID, gorm.DeletedAt{}
+	for i := range pet.Weights {
+		pet.Weights[i].ID = 0
+	}
+
+	if errs := models.ValidatePet(&pet); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Create(&pet).Error; err != nil {
+		return petWriteFailed(c, err)
+	}
+	return c.JSON(http.StatusCreated, pet)
+}
+
+// UpdateClientPet actualiza una mascota de un cliente
+// @Summary Actualizar mascota
+// @Description Actualiza los datos de una mascota. El historial de pesos no se modifica aquí sino con /weights
+// @Tags Mascotas
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param pet body models.Pet true "Información actualizada de la Mascota"
+// @Success 200 {object} models.Pet "Mascota actualizada"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente o mascota no encontrados"
+// @Failure 409 {object} map[string]string "El microchip ya está registrado en otra mascota"
+// @Router /api/v1/clients/{id}/pets/{pet_id} [put]
+func UpdateClientPet(c echo.Context) error {
+	pet, err := findOwnedPet(c, withPetWeights(config.DB))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+
+	before := pet
+	if err := c.Bind(&pet); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	// El dueño, la eliminación y los pesos no se modifican con PUT
+	pet.ID, pet.ClientID, pet.DeletedAt = before.ID, before.ClientID, before.DeletedAt
+	pet.CreatedAt, pet.Weights = before.CreatedAt, before.Weights
+
+	if errs := models.ValidatePet(&pet); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Omit("Weights").Save(&pet).Error; err != nil {
+		return petWriteFailed(c, err)
+	}
+	return c.JSON(http.StatusOK, pet)
+}
+
+// DeleteClientPet elimina una mascota de un cliente
+// @Summary Eliminar mascota
+// @Description Elimina lógicamente una mascota; se purga junto con los clientes eliminados al vencer el período de retención
+// @Tags Mascotas
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Success 204 "Mascota eliminada"
+// @Failure 404 {object} map[string]string "Cliente o mascota no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id} [delete]
+func DeleteClientPet(c echo.Context) error {
+	pet, err := findOwnedPet(c, config.DB)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err := config.DB.Delete(&pet).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AddPetWeight agrega un peso al historial de una mascota
+// @Summary Registrar peso
+// @Description Agrega un peso en kilos, medido el día measured_on, al historial de la mascota
+// @Tags Mascotas
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param weight body models.PetWeight true "Peso de la Mascota"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.PetWeight "Peso registrado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente o mascota no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id}/weights [post]
+func AddPetWeight(c echo.Context) error {
+	pet, err := findOwnedPet(c, config.DB)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	var weight models.PetWeight
+	if err := c.Bind(&weight); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	weight.ID, weight.PetID = 0, pet.ID
+
+	if errs := models.ValidatePetWeight(&weight, ""); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Create(&weight).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, weight)
+}
+
+// DeletePetWeight elimina un peso del historial de una mascota
+// @Summary Eliminar peso
+// @Description Elimina definitivamente un peso cargado por error
+// @Tags Mascotas
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param weight_id path int true "ID del Peso"
+// @Success 204 "Peso eliminado"
+// @Failure 404 {object} map[string]string "Cliente, mascota o peso no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id}/weights/{weight_id} [delete]
+func DeletePetWeight(c echo.Context) error {
+	pet, err := findOwnedPet(c, config.DB)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	weightID, err := strconv.Atoi(c.Param("weight_id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Weight not found"})
+	}
+	res := config.DB.Where("pet_id = ?", pet.ID).Delete(&models.PetWeight{}, weightID)
+	if res.Error != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": res.Error.Error()})
+	}
+	if res.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Weight not found"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// routeClient devuelve el cliente activo del parámetro id, al que pertenecen las mascotas, pedidos o puntos de la ruta
+func routeClient(c echo.Context) (models.Client, error) {
+	var client models.Client
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return client, gorm.ErrRecordNotFound
+	}
+	return client, config.DB.First(&client, id).Error
+}
+
+// findOwnedPet busca con db la mascota pet_id del cliente activo id. El error es el mensaje con el que
+// responder un 404
+func findOwnedPet(c echo.Context, db *gorm.DB) (models.Pet, error) {
+	var pet models.Pet
+	client, err := routeClient(c)
+	if err != nil {
+		return pet, errors.New("Client not found")
+	}
+	petID, err := strconv.Atoi(c.Param("pet_id"))
+	if err != nil {
+		return pet, errors.New("Pet not found")
+	}
+	if err := db.Where("client_id = ?", client.ID).First(&pet, petID).Error; err != nil {
+		return pet, errors.New("Pet not found")
+	}
+	return pet, nil
+}
+
+// petScope devuelve el query base de mascotas con su historial de pesos, incluyendo las eliminadas si se
+// pide include_deleted=true
+func petScope(c echo.Context) *gorm.DB {
+	db := withPetWeights(config.DB.Model(&models.Pet{}))
+	if include, _ := strconv.ParseBool(c.QueryParam("include_deleted")); include {
+		db = db.Unscoped()
+	}
+	return db
+}
+
+// withPetWeights carga el historial de pesos de las mascotas, del más antiguo al más reciente
+func withPetWeights(db *gorm.DB) *gorm.DB {
+	return db.Preload("Weights", func(db *gorm.DB) *gorm.DB {
+		return db.Order("measured_on, id")
+	})
+}
+
+// petWriteFailed responde a un error al guardar una mascota; un microchip que ya tiene otra mascota es un 409
+func petWriteFailed(c echo.Context, err error) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed: pets.microchip") {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Microchip is already registered to another pet"})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// petRequest ejecuta handler con los parámetros de ruta id, pet_id y weight_id, en ese orden, que se pasen en ids
+func petRequest(t *testing.T, handler echo.HandlerFunc, method, query, body string, ids ...int) *httptest.ResponseRecorder {
+	return petRouteRequest(t, handler, "weight_id", method, query, body, ids...)
+}
+
+// petRouteRequest es petRequest con last como nombre del tercer parámetro de ruta
+func petRouteRequest(t *testing.T, handler echo.HandlerFunc, last, method, query, body string, ids ...int) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/api/v1/clients/pets?"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	names := []string{"id", "pet_id", last}[:len(ids)]
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	assert.NoError(t, handler(c))
+	return rec
+}
+
+func cleanupPets() {
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Notification{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.PetTreatment{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.PetWeight{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Pet{})
+	config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+}
+
+func createTestOwner(t *testing.T, email string) models.Client {
+	client := models.Client{Name: "John", LastName: "Doe", Email: email, BirthDay: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Telephone: "600123456"}
+	if err := config.DB.Create(&client).Error; err != nil {
+		t.Fatalf("Error al insertar el cliente: %v", err)
+	}
+	return client
+}
+
+func TestClientPetsCRUD(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	owner := createTestOwner(t, "john.doe@example.com")
+
+	rec := petRequest(t, CreateClientPet, http.MethodPost, "", `{
+		"name": "Luna", "species": "Cat", "breed": "Siamese", "sex": "female", "birth_day": "2020-04-01T00:00:00Z",
+		"neutered": true, "microchip": "985 102 000 123 458",
+		"weights": [{"kilograms": 3.9, "measured_on": "2024-05-01T00:00:00Z"}, {"kilograms": 3.5, "measured_on": "2023-05-01T00:00:00Z"}]
+	}`, owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var pet models.Pet
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pet))
+	assert.Equal(t, owner.ID, pet.ClientID)
+	assert.Equal(t, "cat", pet.Species)
+	assert.Equal(t, "985102000123458", *pet.Microchip)
+
+	rec = petRequest(t, GetClientPet, http.MethodGet, "", "", owner.ID, pet.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var found models.Pet
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &found))
+	if assert.Len(t, found.Weights, 2) {
+		assert.Equal(t, []float64{3.5, 3.9}, []float64{found.Weights[0].Kilograms, found.Weights[1].Kilograms}, "Los pesos van del más antiguo al más reciente")
+	}
+
+	// PUT no cambia el dueño ni el historial de pesos
+	rec = petRequest(t, UpdateClientPet, http.MethodPut, "", `{"name": "Luna", "species": "cat", "microchip": null, "client_id": 999, "weights": []}`, owner.ID, pet.ID)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var updated models.Pet
+	config.DB.Preload("Weights").First(&updated, pet.ID)
+	assert.Equal(t, owner.ID, updated.ClientID)
+	assert.Len(t, updated.Weights, 2)
+	assert.Nil(t, updated.Microchip)
+	assert.Equal(t, models.SexFemale, updated.Sex, "Los campos que no se envían no cambian")
+
+	rec = petRequest(t, AddPetWeight, http.MethodPost, "", `{"kilograms": 4.1, "measured_on": "2024-06-01T00:00:00Z"}`, owner.ID, pet.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var weight models.PetWeight
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &weight))
+	rec = petRequest(t, AddPetWeight, http.MethodPost, "", `{"kilograms": 0}`, owner.ID, pet.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = petRequest(t, DeletePetWeight, http.MethodDelete, "", "", owner.ID, pet.ID, weight.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = petRequest(t, DeletePetWeight, http.MethodDelete, "", "", owner.ID, pet.ID, weight.ID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Una mascota solo se encuentra a través de su dueño
+	other := createTestOwner(t, "jane.doe@example.com")
+	rec = petRequest(t, GetClientPet, http.MethodGet, "", "", other.ID, pet.ID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error": "Pet not found"}`, rec.Body.String())
+	rec = petRequest(t, GetClientPets, http.MethodGet, "", "", 999)
+	assert.JSONEq(t, `{"error": "Client not found"}`, rec.Body.String())
+
+	rec = petRequest(t, DeleteClientPet, http.MethodDelete, "", "", owner.ID, pet.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	var pets []models.Pet
+	rec = petRequest(t, GetClientPets, http.MethodGet, "", "", owner.ID)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pets))
+	assert.Empty(t, pets)
+	rec = petRequest(t, GetClientPets, http.MethodGet, "include_deleted=true", "", owner.ID)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pets))
+	assert.Len(t, pets, 1)
+}
+
+func TestCreateClientPetValidation(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	owner := createTestOwner(t, "john.doe@example.com")
+
+	rec := petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Rex", "species": "dog", "microchip": "985102000123459"}`, owner.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var response ValidationErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, models.ValidationErrors{{Field: "microchip", Code: models.CodeChecksum, Message: "Microchip check digit does not match, check the number"}}, response.Errors)
+
+	rec = petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Rex", "species": "dog", "microchip": "985102000123458"}`, owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Max", "species": "dog", "microchip": "985-102-000-123-458"}`, owner.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error": "Microchip is already registered to another pet"}`, rec.Body.String())
+}
+
+func TestClientPetsFollowTheirOwner(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	owner := createTestOwner(t, "john.doe@example.com")
+	luna := models.Pet{ClientID: owner.ID, Name: "Luna", Species: models.SpeciesCat}
+	rex := models.Pet{ClientID: owner.ID, Name: "Rex", Species: models.SpeciesDog}
+	config.DB.Create(&luna)
+	config.DB.Create(&rex)
+	config.DB.Delete(&rex)
+
+	rec := petRequest(t, DeleteClient, http.MethodDelete, "", "", owner.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	var active int64
+	config.DB.Model(&models.Pet{}).Count(&active)
+	assert.Zero(t, active, "Las mascotas se eliminan con su dueño")
+	rec = petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Kiwi", "species": "bird"}`, owner.ID)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = petRequest(t, RestoreClient, http.MethodPost, "", "", owner.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var names []string
+	config.DB.Model(&models.Pet{}).Order("id").Pluck("name", &names)
+	assert.Equal(t, []string{"Luna"}, names, "Solo vuelven las mascotas que se eliminaron con el cliente")
+
+	// Al fusionar clientes, las mascotas pasan al que sobrevive
+	survivor := createTestOwner(t, "johnny.doe@example.com")
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/merge", strings.NewReader(fmt.Sprintf(`{"survivor_id": %d, "merged_ids": [%d]}`, survivor.ID, owner.ID)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	merged := httptest.NewRecorder()
+	assert.NoError(t, MergeClients(e.NewContext(req, merged)))
+	assert.Equal(t, http.StatusOK, merged.Code, merged.Body.String())
+	var owners []int
+	config.DB.Unscoped().Model(&models.Pet{}).Order("id").Pluck("client_id", &owners)
+	assert.Equal(t, []int{survivor.ID, survivor.ID}, owners)
+}
+
+func TestMicrochipOfDeletedPets(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	owner := createTestOwner(t, "john.doe@example.com")
+	other := createTestOwner(t, "jane.doe@example.com")
+
+	rec := petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Rex", "species": "dog", "microchip": "985102000123458"}`, owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var rex models.Pet
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rex))
+	rec = petRequest(t, DeleteClientPet, http.MethodDelete, "", "", owner.ID, rex.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Max", "species": "dog", "microchip": "985102000123458"}`, other.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code, "El microchip de una mascota eliminada se puede registrar en otra")
+
+	// Una mascota eliminada con su dueño no vuelve si otra tomó su microchip mientras tanto
+	rec = petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Luna", "species": "cat", "microchip": "985102000123466"}`, owner.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = petRequest(t, DeleteClient, http.MethodDelete, "", "", owner.ID)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = petRequest(t, CreateClientPet, http.MethodPost, "", `{"name": "Kiwi", "species": "cat", "microchip": "985102000123466"}`, other.ID)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = petRequest(t, RestoreClient, http.MethodPost, "", "", owner.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error": "A pet of the client has a microchip that is already registered to another pet"}`, rec.Body.String())
+	var restored int64
+	config.DB.Model(&models.Client{}).Where("id = ?", owner.ID).Count(&restored)
+	assert.Zero(t, restored, "El cliente sigue eliminado")
+
+	config.DB.Model(&models.Pet{}).Where("name = ?", "Kiwi").Update("microchip", nil)
+	rec = petRequest(t, RestoreClient, http.MethodPost, "", "", owner.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var names []string
+	config.DB.Model(&models.Pet{}).Where("client_id = ?", owner.ID).Pluck("name", &names)
+	assert.Equal(t, []string{"Luna"}, names)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// PetKPI resume las mascotas de los clientes: cuántas hay de cada especie y raza, la edad por especie y
+// cuántas mascotas tiene cada cliente. Las proporciones van de 0 a 1 y son 0 si no hay nada que repartir
+type PetKPI struct {
+	Count             int64            `json:"count"`
+	StandardDeviation string           `json:"standard_deviation" enums:"population,sample"`
+	Species           []PetSpeciesKPI  `json:"species"`
+	Breeds            []PetBreedKPI    `json:"breeds"`
+	PetsPerClient     PetsPerClientKPI `json:"pets_per_client"`
+}
+
+// PetSpeciesKPI son las mascotas de una especie y su edad en años cumplidos. La edad solo considera las
+// mascotas con fecha de nacimiento, que son aged_count; sin ninguna, las estadísticas son null
+type PetSpeciesKPI struct {
+	Species              string   `json:"species"`
+	Count                int64    `json:"count"`
+	Share                float64  `json:"share"`
+	AgedCount            int64    `json:"aged_count"`
+	AverageAge           *float64 `json:"average_age"`
+	AgeStandardDeviation *float64 `json:"age_standard_deviation"`
+}
+
+// PetBreedKPI son las mascotas de una raza; share es la proporción dentro de su especie. breed es null
+// para las mascotas sin raza informada
+type PetBreedKPI struct {
+	Species string  `json:"species"`
+	Breed   *string `json:"breed"`
+	Count   int64   `json:"count"`
+	Share   float64 `json:"share"`
+}
+
+// PetsPerClientKPI es la cantidad de mascotas por cliente, contando a los clientes sin mascotas
+type PetsPerClientKPI struct {
+	Clients int64          `json:"clients"`
+	Mean    *float64       `json:"mean"`
+	Median  *float64       `json:"median"`
+	Max     *float64       `json:"max"`
+	None    PetOwnersShare `json:"none"`
+	One     PetOwnersShare `json:"one"`
+	Several PetOwnersShare `json:"several"`
+}
+
+// PetOwnersShare es la cantidad y la proporción de clientes con una cantidad de mascotas
+type PetOwnersShare struct {
+	Count int64   `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// GetPetKPI calcula las estadísticas de las mascotas de los clientes
+// @Summary KPI de mascotas
+// @Description Calcula la distribución de las mascotas por especie y raza, la edad media y su desviación estándar por especie, y la cantidad de mascotas por cliente (media, mediana, máximo y proporción de clientes sin mascotas, con una o con varias). Acepta los mismos filtros que el listado de clientes, que se aplican a los dueños
+// @Tags Mascotas
+// @Produce json
+// @Param std query string false "Desviación estándar poblacional o muestral" Enums(population, sample) default(population)
+// @Param last_name query string false "Prefijo del apellido del dueño"
+// @Param email_domain query string false "Dominio del email del dueño"
+// @Param telephone_prefix query string false "Prefijo del teléfono del dueño, nacional (600) o internacional (+34600)"
+// @Param age_min query int false "Edad mínima del dueño"
+// @Param age_max query int false "Edad máxima del dueño"
+// @Param birth_day_from query string false "Fecha de nacimiento del dueño desde (YYYY-MM-DD)"
+// @Param birth_day_to query string false "Fecha de nacimiento del dueño hasta (YYYY-MM-DD)"
+// @Param include_deleted query bool false "Incluir clientes y mascotas eliminados"
+// @Success 200 {object} PetKPI "KPI de mascotas calculado"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/pets/kpi [get]
+func GetPetKPI(c echo.Context) error {
+	sample, err := parseStdMode(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter, err := parseClientFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	includeDeleted, _ := strconv.ParseBool(c.QueryParam("include_deleted"))
+
+	owners := func() *gorm.DB { return applyClientFilter(clientScope(c), filter) }
+	pets := func() *gorm.DB {
+		db := config.DB.Model(&models.Pet{}).Where("client_id IN (?)", owners().Select("id"))
+		if includeDeleted {
+			db = db.Unscoped()
+		}
+		return db
+	}
+
+	kpi := PetKPI{StandardDeviation: stdPopulation, Species: []PetSpeciesKPI{}, Breeds: []PetBreedKPI{}}
+	if sample {
+		kpi.StandardDeviation = stdSample
+	}
+	if err := petSpeciesKPI(pets(), sample, &kpi); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve pets"})
+	}
+	if err := petBreedKPI(pets(), &kpi); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve pets"})
+	}
+	if err := petsPerClientKPI(owners(), includeDeleted, &kpi); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve pets"})
+	}
+	return c.JSON(http.StatusOK, kpi)
+}
+
+// petSpeciesKPI cuenta en SQL las mascotas de cada especie y edad, ordenadas por especie
+func petSpeciesKPI(db *gorm.DB, sample bool, kpi *PetKPI) error {
+	today := models.Now().UTC().Format("2006-01-02")
+	var rows []struct {
+		Species string
+		Age     *float64
+		Count   int64
+	}
+	err := db.Select("species, "+models.ClientAgeSQL+" AS age, COUNT(*) AS count", today, today).
+		Group("species, age").
+		Order("species, age").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	ages := map[string]models.Distribution{}
+	for _, row := range rows {
+		n := len(kpi.Species)
+		if n == 0 || kpi.Species[n-1].Species != row.Species {
+			kpi.Species = append(kpi.Species, PetSpeciesKPI{Species: row.Species})
+			n++
+		}
+		kpi.Species[n-1].Count += row.Count
+		kpi.Count += row.Count
+		if row.Age != nil {
+			ages[row.Species] = append(ages[row.Species], models.ValueCount{Value: *row.Age, Count: row.Count})
+		}
+	}
+	for i := range kpi.Species {
+		s := &kpi.Species[i]
+		s.Share = share(s.Count, kpi.Count)
+		s.AgedCount = ages[s.Species].Count()
+		s.AverageAge = optional(ages[s.Species].Mean())
+		s.AgeStandardDeviation = optional(ages[s.Species].StdDev(sample))
+	}
+	return nil
+}
+
+// petBreedKPI cuenta en SQL las mascotas de cada raza, de la más a la menos frecuente dentro de cada especie.
+// Las razas se comparan sin distinguir mayúsculas y se nombran con una de las formas en que se escribieron
+func petBreedKPI(db *gorm.DB, kpi *PetKPI) error {
+	var rows []struct {
+		Species string
+		Breed   *string
+		Count   int64
+	}
+	err := db.Select("species, MIN(NULLIF(TRIM(breed), '')) AS breed, COUNT(*) AS count").
+		Group("species, LOWER(TRIM(breed))").
+		Order("species, count DESC, breed IS NULL, breed").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	perSpecies := map[string]int64{}
+	for _, s := range kpi.Species {
+		perSpecies[s.Species] = s.Count
+	}
+	for _, row := range rows {
+		kpi.Breeds = append(kpi.Breeds, PetBreedKPI{Species: row.Species, Breed: row.Breed, Count: row.Count, Share: share(row.Count, perSpecies[row.Species])})
+	}
+	return nil
+}
+
+// petsPerClientKPI calcula en SQL cuántos clientes de owners tienen cada cantidad de mascotas
+func petsPerClientKPI(owners *gorm.DB, includeDeleted bool, kpi *PetKPI) error {
+	petCount := "(SELECT COUNT(*) FROM pets WHERE pets.client_id = clients.id AND pets.deleted_at IS NULL)"
+	if includeDeleted {
+		petCount = "(SELECT COUNT(*) FROM pets WHERE pets.client_id = clients.id)"
+	}
+	counts := models.Distribution{}
+	err := owners.Select(petCount + " AS value, COUNT(*) AS count").
+		Group("value").Order("value").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	p := &kpi.PetsPerClient
+	p.Clients = counts.Count()
+	p.Mean = optional(counts.Mean())
+	p.Median = optional(counts.Quantile(50))
+	p.Max = optional(counts.Max())
+	for _, vc := range counts {
+		switch {
+		case vc.Value == 0:
+			p.None.Count += vc.Count
+		case vc.Value == 1:
+			p.One.Count += vc.Count
+		default:
+			p.Several.Count += vc.Count
+		}
+	}
+	p.None.Share = share(p.None.Count, p.Clients)
+	p.One.Share = share(p.One.Count, p.Clients)
+	p.Several.Share = share(p.Several.Count, p.Clients)
+	return nil
+}
+
+// share es la proporción de part en total, o 0 si total es 0
+func share(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
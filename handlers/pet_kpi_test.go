@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func petKPI(t *testing.T, query string) (*httptest.ResponseRecorder, PetKPI) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/pets/kpi?"+query, nil)
+	rec := httptest.NewRecorder()
+
+	var kpi PetKPI
+	if assert.NoError(t, GetPetKPI(e.NewContext(req, rec))) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &kpi))
+	}
+	return rec, kpi
+}
+
+// seedPets da mascotas a los clientes de seedListClients: John tiene tres, Jane una y Bob una eliminada
+func seedPets(t *testing.T) {
+	ids := map[string]int{}
+	var clients []models.Client
+	config.DB.Find(&clients)
+	for _, client := range clients {
+		ids[client.Name] = client.ID
+	}
+	born := func(year int) *time.Time {
+		d := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	pets := []models.Pet{
+		{ClientID: ids["John"], Name: "Rex", Species: models.SpeciesDog, Breed: "Beagle", BirthDay: born(2020)},
+		{ClientID: ids["John"], Name: "Toby", Species: models.SpeciesDog, Breed: "beagle ", BirthDay: born(2016)},
+		{ClientID: ids["John"], Name: "Luna", Species: models.SpeciesCat},
+		{ClientID: ids["Jane"], Name: "Max", Species: models.SpeciesDog, Breed: "Boxer", BirthDay: born(2021)},
+		{ClientID: ids["Bob"], Name: "Kiwi", Species: models.SpeciesBird},
+	}
+	for i := range pets {
+		if err := config.DB.Create(&pets[i]).Error; err != nil {
+			t.Fatalf("Error al insertar la mascota: %v", err)
+		}
+	}
+	config.DB.Delete(&pets[4])
+}
+
+func TestGetPetKPI(t *testing.T) {
+	setupTestDB()
+	seedListClients(t)
+	seedPets(t)
+	defer cleanupPets()
+
+	rec, kpi := petKPI(t, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(4), kpi.Count)
+	if assert.Len(t, kpi.Species, 2) {
+		cat, dog := kpi.Species[0], kpi.Species[1]
+		assert.Equal(t, []interface{}{"cat", int64(1), 0.25, int64(0)}, []interface{}{cat.Species, cat.Count, cat.Share, cat.AgedCount})
+		assert.Nil(t, cat.AverageAge, "Sin fechas de nacimiento no hay edad")
+		// Los perros tienen 4, 8 y 3 años
+		assert.Equal(t, []interface{}{"dog", int64(3), 0.75, int64(3)}, []interface{}{dog.Species, dog.Count, dog.Share, dog.AgedCount})
+		assert.Equal(t, 5.0, *dog.AverageAge)
+		assert.InDelta(t, 2.1602, *dog.AgeStandardDeviation, 1e-4)
+	}
+
+	breeds := map[string]int64{}
+	for _, b := range kpi.Breeds {
+		name := "null"
+		if b.Breed != nil {
+			name = *b.Breed
+		}
+		breeds[b.Species+"/"+name] = b.Count
+	}
+	assert.Equal(t, map[string]int64{"cat/null": 1, "dog/Beagle": 2, "dog/Boxer": 1}, breeds, "Las razas se agrupan sin distinguir mayúsculas")
+	assert.Equal(t, 2.0/3, kpi.Breeds[1].Share)
+
+	// John tiene 3 mascotas, Jane 1 y Alice, Bob y Carol ninguna
+	p := kpi.PetsPerClient
+	assert.Equal(t, int64(5), p.Clients)
+	assert.Equal(t, []float64{0.8, 0, 3}, []float64{*p.Mean, *p.Median, *p.Max})
+	assert.Equal(t, PetOwnersShare{Count: 3, Share: 0.6}, p.None)
+	assert.Equal(t, PetOwnersShare{Count: 1, Share: 0.2}, p.One)
+	assert.Equal(t, PetOwnersShare{Count: 1, Share: 0.2}, p.Several)
+
+	// Los filtros se aplican a los dueños
+	_, kpi = petKPI(t, "email_domain=corp.com&std=sample")
+	assert.Equal(t, "sample", kpi.StandardDeviation)
+	assert.Equal(t, int64(1), kpi.Count)
+	assert.Nil(t, kpi.Species[0].AgeStandardDeviation, "La desviación muestral necesita dos mascotas")
+	assert.Equal(t, PetOwnersShare{Count: 1, Share: 0.5}, kpi.PetsPerClient.None)
+
+	_, kpi = petKPI(t, "include_deleted=true")
+	assert.Equal(t, int64(5), kpi.Count)
+	assert.Equal(t, int64(2), kpi.PetsPerClient.One.Count, "Bob cuenta con su mascota eliminada")
+
+	rec, _ = petKPI(t, "std=unbiased")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = petKPI(t, "age_min=x")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetPetKPIWithoutPets(t *testing.T) {
+	setupTestDB()
+
+	_, kpi := petKPI(t, "")
+	assert.Zero(t, kpi.Count)
+	assert.Empty(t, kpi.Species)
+	assert.Empty(t, kpi.Breeds)
+	assert.Nil(t, kpi.PetsPerClient.Mean)
+	assert.Zero(t, kpi.PetsPerClient.None.Share)
+}
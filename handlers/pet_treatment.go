@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultUpcomingTreatmentWindow es la ventana por defecto del listado de tratamientos próximos
+const defaultUpcomingTreatmentWindow = "30d"
+
+// DueTreatment es un registro sanitario pendiente con la mascota y el dueño a los que corresponde.
+// days_until son los días que faltan para next_due_on, negativos si ya venció
+type DueTreatment struct {
+	models.PetTreatment
+	PetName         string `json:"pet_name"`
+	PetSpecies      string `json:"pet_species"`
+	ClientID        int    `json:"client_id"`
+	ClientName      string `json:"client_name"`
+	ClientLastName  string `json:"client_last_name"`
+	ClientEmail     string `json:"client_email"`
+	ClientTelephone string `json:"client_telephone"`
+	DaysUntil       int    `json:"days_until"`
+}
+
+// GetPetTreatments lista el historial sanitario de una mascota
+// @Summary Historial sanitario de una mascota
+// @Description Devuelve las vacunas, desparasitaciones y tratamientos de la mascota, del más antiguo al más reciente
+// @Tags Mascotas
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Produce json
+// @Success 200 {array} models.PetTreatment "Historial sanitario"
+// @Failure 404 {object} map[string]string "Cliente o mascota no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id}/treatments [get]
+func GetPetTreatments(c echo.Context) error {
+	pet, err := findOwnedPet(c, config.DB)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	treatments := []models.PetTreatment{}
+	if err := config.DB.Where("pet_id = ?", pet.ID).Order("administered_on, id").Find(&treatments).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, treatments)
+}
+
+// CreatePetTreatment registra una vacuna, desparasitación o tratamiento de una mascota
+// @Summary Registrar tratamiento
+// @Description Registra una vacuna, desparasitación o tratamiento aplicado el día administered_on, con el producto y el lote. next_due_on es cuándo toca repetirlo; el nuevo registro da por cumplidos los anteriores del mismo tipo y producto
+// @Tags Mascotas
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param treatment body models.PetTreatment true "Registro sanitario"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.PetTreatment "Tratamiento registrado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente o mascota no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id}/treatments [post]
+func CreatePetTreatment(c echo.Context) error {
+	pet, err := findOwnedPet(c, config.DB)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	var treatment models.PetTreatment
+	if err := c.Bind(&treatment); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	treatment.ID, treatment.PetID = 0, pet.ID
+
+	if errs := models.ValidatePetTreatment(&treatment); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Create(&treatment).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, treatment)
+}
+
+// UpdatePetTreatment corrige un registro sanitario de una mascota
+// @Summary Actualizar tratamiento
+// @Description Corrige un registro sanitario; los campos que no se envían no cambian
+// @Tags Mascotas
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param treatment_id path int true "ID del Registro"
+// @Param treatment body models.PetTreatment true "Registro sanitario"
+// @Success 200 {object} models.PetTreatment "Tratamiento actualizado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Cliente, mascota o registro no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id}/treatments/{treatment_id} [put]
+func UpdatePetTreatment(c echo.Context) error {
+	treatment, err := findPetTreatment(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	before := treatment
+	if err := c.Bind(&treatment); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	treatment.ID, treatment.PetID, treatment.CreatedAt = before.ID, before.PetID, before.CreatedAt
+
+	if errs := models.ValidatePetTreatment(&treatment); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Save(&treatment).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, treatment)
+}
+
+// DeletePetTreatment elimina un registro sanitario de una mascota
+// @Summary Eliminar tratamiento
+// @Description Elimina definitivamente un registro cargado por error; si había dado por cumplido uno anterior, ese vuelve a estar pendiente
+// @Tags Mascotas
+// @Param id path int true "ID del Cliente"
+// @Param pet_id path int true "ID de la Mascota"
+// @Param treatment_id path int true "ID del Registro"
+// @Success 204 "Registro eliminado"
+// @Failure 404 {object} map[string]string "Cliente, mascota o registro no encontrados"
+// @Router /api/v1/clients/{id}/pets/{pet_id}/treatments/{treatment_id} [delete]
+func DeletePetTreatment(c echo.Context) error {
+	treatment, err := findPetTreatment(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err := config.DB.Delete(&treatment).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetOverdueTreatments lista los tratamientos vencidos de todos los clientes
+// @Summary Tratamientos vencidos
+// @Description Devuelve los registros sanitarios pendientes cuya fecha de repetición ya pasó, de las mascotas y clientes activos, del más atrasado al más reciente
+// @Tags Mascotas
+// @Produce json
+// @Param kind query string false "Tipo de registro" Enums(vaccination, deworming, treatment)
+// @Param tz query string false "Zona horaria IANA con la que se determina el día de hoy" default(UTC)
+// @Success 200 {array} DueTreatment "Tratamientos vencidos"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/treatments/overdue [get]
+func GetOverdueTreatments(c echo.Context) error {
+	today, err := requestToday(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return listDueTreatments(c, today, "date(pet_treatments.next_due_on) < ?", today.Format("2006-01-02"))
+}
+
+// GetUpcomingTreatments lista los tratamientos que vencen en los próximos días
+// @Summary Próximos tratamientos
+// @Description Devuelve los registros sanitarios pendientes que vencen desde hoy hasta antes de que pase within, de las mascotas y clientes activos, ordenados por fecha
+// @Tags Mascotas
+// @Produce json
+// @Param within query string false "Cantidad de días, incluido hoy (1d a 366d)" default(30d)
+// @Param kind query string false "Tipo de registro" Enums(vaccination, deworming, treatment)
+// @Param tz query string false "Zona horaria IANA con la que se determina el día de hoy" default(UTC)
+// @Success 200 {array} DueTreatment "Próximos tratamientos"
+// @Failure 400 {object} map[string]string "Parámetros inválidos"
+// @Router /api/v1/treatments/upcoming [get]
+func GetUpcomingTreatments(c echo.Context) error {
+	days, err := parseWithinDays(c, defaultUpcomingTreatmentWindow)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	today, err := requestToday(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return listDueTreatments(c, today, "date(pet_treatments.next_due_on) BETWEEN ? AND ?",
+		today.Format("2006-01-02"), today.AddDate(0, 0, days-1).Format("2006-01-02"))
+}
+
+// GetClientNotifications lista las notificaciones emitidas para un cliente
+// @Summary Notificaciones de un cliente
+// @Description Devuelve los avisos emitidos para el cliente, como los recordatorios de vacunas y tratamientos, del más reciente al más antiguo
+// @Tags Clientes
+// @Param id path int true "ID del Cliente"
+// @Produce json
+// @Success 200 {array} models.Notification "Notificaciones del cliente"
+// @Failure 404 {object} map[string]string "Cliente no encontrado"
+// @Router /api/v1/clients/{id}/notifications [get]
+func GetClientNotifications(c echo.Context) error {
+	client, err := routeClient(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Client not found"})
+	}
+	notifications := []models.Notification{}
+	if err := config.DB.Where("client_id = ?", client.ID).Order("created_at DESC, id DESC").Find(&notifications).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, notifications)
+}
+
+// listDueTreatments responde con los registros pendientes que cumplen la condición sobre next_due_on,
+// filtrados por el parámetro kind
+func listDueTreatments(c echo.Context, today time.Time, due string, args ...interface{}) error {
+	db := models.PendingTreatments(config.DB.Model(&models.PetTreatment{})).
+		Select(`pet_treatments.*, pets.name AS pet_name, pets.species AS pet_species, clients.id AS client_id,
+			clients.name AS client_name, clients.last_name AS client_last_name, clients.email AS client_email,
+			clients.telephone AS client_telephone`).
+		Joins("JOIN pets ON pets.id = pet_treatments.pet_id AND pets.deleted_at IS NULL").
+		Joins("JOIN clients ON clients.id = pets.client_id AND clients.deleted_at IS NULL").
+		Where(due, args...)
+	if kind := strings.ToLower(strings.TrimSpace(c.QueryParam("kind"))); kind != "" {
+		if !slices.Contains(models.TreatmentKinds, kind) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "kind must be one of " + strings.Join(models.TreatmentKinds, ", ")})
+		}
+		db = db.Where("pet_treatments.kind = ?", kind)
+	}
+
+	treatments := []DueTreatment{}
+	if err := db.Order("pet_treatments.next_due_on, clients.last_name, clients.name, pet_treatments.id").Scan(&treatments).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve treatments"})
+	}
+	for i := range treatments {
+		treatments[i].DaysUntil = int(treatments[i].NextDueOn.Sub(today).Hours() / 24)
+	}
+	return c.JSON(http.StatusOK, treatments)
+}
+
+// findPetTreatment busca el registro treatment_id de la mascota pet_id del cliente activo id. El error es
+// el mensaje con el que responder un 404
+func findPetTreatment(c echo.Context) (models.PetTreatment, error) {
+	var treatment models.PetTreatment
+	pet, err := findOwnedPet(c, config.DB)
+	if err != nil {
+		return treatment, err
+	}
+	id, err := strconv.Atoi(c.Param("treatment_id"))
+	if err != nil {
+		return treatment, errors.New("Treatment not found")
+	}
+	if err := config.DB.Where("pet_id = ?", pet.ID).First(&treatment, id).Error; err != nil {
+		return treatment, errors.New("Treatment not found")
+	}
+	return treatment, nil
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func treatmentRequest(t *testing.T, handler echo.HandlerFunc, method, body string, ids ...int) (int, string) {
+	rec := petRouteRequest(t, handler, "treatment_id", method, "", body, ids...)
+	return rec.Code, rec.Body.String()
+}
+
+// dueTreatments llama al listado handler y devuelve los productos de los tratamientos y sus días hasta el vencimiento
+func dueTreatments(t *testing.T, handler echo.HandlerFunc, query string) (int, []string, []int) {
+	rec := petRequest(t, handler, http.MethodGet, query, "")
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil, nil
+	}
+	var treatments []DueTreatment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &treatments))
+	products, days := []string{}, []int{}
+	for _, treatment := range treatments {
+		products = append(products, treatment.Product)
+		days = append(days, treatment.DaysUntil)
+	}
+	return rec.Code, products, days
+}
+
+func day(s string) *time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return &d
+}
+
+func TestPetTreatmentsCRUD(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	owner := createTestOwner(t, "john.doe@example.com")
+	luna := models.Pet{ClientID: owner.ID, Name: "Luna", Species: models.SpeciesCat}
+	config.DB.Create(&luna)
+
+	code, body := treatmentRequest(t, CreatePetTreatment, http.MethodPost, `{"kind": "surgery", "administered_on": "2024-06-01T00:00:00Z"}`, owner.ID, luna.ID)
+	assert.Equal(t, http.StatusBadRequest, code)
+	var invalid ValidationErrorResponse
+	assert.NoError(t, json.Unmarshal([]byte(body), &invalid))
+	assert.Len(t, invalid.Errors, 2)
+
+	// Las fechas se guardan como días, sin importar la zona horaria con la que se envían
+	code, body = treatmentRequest(t, CreatePetTreatment, http.MethodPost, `{
+		"kind": "Vaccination", "product": " Nobivac Rabies ", "batch": "A123",
+		"administered_on": "2024-06-01T22:30:00-03:00", "next_due_on": "2025-06-01T00:00:00Z"
+	}`, owner.ID, luna.ID)
+	assert.Equal(t, http.StatusCreated, code, body)
+	var rabies models.PetTreatment
+	assert.NoError(t, json.Unmarshal([]byte(body), &rabies))
+	assert.Equal(t, models.TreatmentVaccination, rabies.Kind)
+	assert.Equal(t, "Nobivac Rabies", rabies.Product)
+	assert.Equal(t, "2024-06-01T00:00:00Z", rabies.AdministeredOn.Format(time.RFC3339))
+
+	code, body = treatmentRequest(t, CreatePetTreatment, http.MethodPost, `{"kind": "deworming", "product": "Milbemax", "administered_on": "2024-03-20T00:00:00Z"}`, owner.ID, luna.ID)
+	assert.Equal(t, http.StatusCreated, code, body)
+
+	code, body = treatmentRequest(t, UpdatePetTreatment, http.MethodPut, `{"next_due_on": "2024-06-20T00:00:00Z", "pet_id": 999}`, owner.ID, luna.ID, rabies.ID)
+	assert.Equal(t, http.StatusOK, code, body)
+	var updated models.PetTreatment
+	config.DB.First(&updated, rabies.ID)
+	assert.Equal(t, luna.ID, updated.PetID)
+	assert.Equal(t, "A123", updated.Batch, "Los campos que no se envían no cambian")
+	assert.Equal(t, "2024-06-20", updated.NextDueOn.Format("2006-01-02"))
+
+	rec := petRequest(t, GetPetTreatments, http.MethodGet, "", "", owner.ID, luna.ID)
+	var treatments []models.PetTreatment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &treatments))
+	if assert.Len(t, treatments, 2) {
+		assert.Equal(t, []string{"Milbemax", "Nobivac Rabies"}, []string{treatments[0].Product, treatments[1].Product}, "Del más antiguo al más reciente")
+	}
+
+	// Un registro solo se encuentra a través de su mascota
+	rex := models.Pet{ClientID: owner.ID, Name: "Rex", Species: models.SpeciesDog}
+	config.DB.Create(&rex)
+	code, body = treatmentRequest(t, DeletePetTreatment, http.MethodDelete, "", owner.ID, rex.ID, rabies.ID)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.JSONEq(t, `{"error": "Treatment not found"}`, body)
+	code, _ = treatmentRequest(t, DeletePetTreatment, http.MethodDelete, "", owner.ID, luna.ID, rabies.ID)
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = treatmentRequest(t, DeletePetTreatment, http.MethodDelete, "", owner.ID, luna.ID, rabies.ID)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestOverdueAndUpcomingTreatments(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	john := createTestOwner(t, "john.doe@example.com")
+	jane := createTestOwner(t, "jane.doe@example.com")
+	luna := models.Pet{ClientID: john.ID, Name: "Luna", Species: models.SpeciesCat}
+	rex := models.Pet{ClientID: john.ID, Name: "Rex", Species: models.SpeciesDog}
+	kiwi := models.Pet{ClientID: jane.ID, Name: "Kiwi", Species: models.SpeciesBird}
+	config.DB.Create(&luna)
+	config.DB.Create(&rex)
+	config.DB.Create(&kiwi)
+
+	dhpp := models.PetTreatment{PetID: rex.ID, Kind: models.TreatmentVaccination, Product: "DHPP", AdministeredOn: *day("2023-07-01"), NextDueOn: day("2024-07-01")}
+	booster := models.PetTreatment{PetID: rex.ID, Kind: models.TreatmentVaccination, Product: "dhpp", AdministeredOn: *day("2024-06-10"), NextDueOn: day("2025-06-10")}
+	for _, treatment := range []*models.PetTreatment{
+		{PetID: luna.ID, Kind: models.TreatmentVaccination, Product: "Nobivac Rabies", AdministeredOn: *day("2023-06-01"), NextDueOn: day("2024-06-01")},
+		{PetID: luna.ID, Kind: models.TreatmentDeworming, Product: "Milbemax", AdministeredOn: *day("2024-03-20"), NextDueOn: day("2024-06-20")},
+		{PetID: luna.ID, Kind: models.TreatmentMedication, Product: "Metacam", AdministeredOn: *day("2024-06-01")},
+		&dhpp, &booster,
+		{PetID: kiwi.ID, Kind: models.TreatmentDeworming, Product: "Panacur", AdministeredOn: *day("2024-01-01"), NextDueOn: day("2024-06-01")},
+	} {
+		config.DB.Create(treatment)
+	}
+	config.DB.Delete(&kiwi)
+
+	_, products, days := dueTreatments(t, GetOverdueTreatments, "")
+	assert.Equal(t, []string{"Nobivac Rabies"}, products, "Las mascotas eliminadas no se listan")
+	assert.Equal(t, []int{-14}, days)
+
+	// El refuerzo del DHPP da por cumplida la dosis anterior
+	_, products, days = dueTreatments(t, GetUpcomingTreatments, "")
+	assert.Equal(t, []string{"Milbemax"}, products)
+	assert.Equal(t, []int{5}, days)
+	_, products, days = dueTreatments(t, GetUpcomingTreatments, "within=366d")
+	assert.Equal(t, []string{"Milbemax", "dhpp"}, products)
+	assert.Equal(t, []int{5, 360}, days)
+	_, products, _ = dueTreatments(t, GetUpcomingTreatments, "within=366d&kind=vaccination")
+	assert.Equal(t, []string{"dhpp"}, products)
+
+	config.DB.Delete(&booster)
+	_, products, days = dueTreatments(t, GetUpcomingTreatments, "")
+	assert.Equal(t, []string{"Milbemax", "DHPP"}, products, "Sin el refuerzo, la dosis anterior vuelve a estar pendiente")
+	assert.Equal(t, []int{5, 16}, days)
+
+	rec := petRequest(t, GetUpcomingTreatments, http.MethodGet, "", "")
+	var due []DueTreatment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &due))
+	if assert.Len(t, due, 2) {
+		assert.Equal(t, "Luna", due[0].PetName)
+		assert.Equal(t, john.ID, due[0].ClientID)
+		assert.Equal(t, "john.doe@example.com", due[0].ClientEmail)
+	}
+
+	for _, query := range []string{"within=0d", "within=367d", "kind=surgery", "tz=Mars/Olympus"} {
+		code, _, _ := dueTreatments(t, GetUpcomingTreatments, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+	code, _, _ := dueTreatments(t, GetOverdueTreatments, "kind=surgery")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetClientNotifications(t *testing.T) {
+	setupTestDB()
+	defer cleanupPets()
+	owner := createTestOwner(t, "john.doe@example.com")
+	for _, message := range []string{"First", "Second"} {
+		config.DB.Create(&models.Notification{ClientID: owner.ID, Kind: models.NotificationTreatmentDue, Message: message})
+	}
+
+	rec := petRequest(t, GetClientNotifications, http.MethodGet, "", "", owner.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var notifications []models.Notification
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notifications))
+	if assert.Len(t, notifications, 2) {
+		assert.Equal(t, "Second", notifications[0].Message, "Las más recientes primero")
+	}
+	rec = petRequest(t, GetClientNotifications, http.MethodGet, "", "", 999)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
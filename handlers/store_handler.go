@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetStores lista las tiendas
+// @Summary Listar tiendas
+// @Description Devuelve las tiendas con su zona horaria y su horario de apertura
+// @Tags Turnos
+// @Produce json
+// @Success 200 {array} models.Store "Tiendas"
+// @Router /api/v1/stores [get]
+func GetStores(c echo.Context) error {
+	stores := []models.Store{}
+	if err := config.DB.Order("name").Find(&stores).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, stores)
+}
+
+// CreateStore crea una tienda
+// @Summary Crear tienda
+// @Description Crea una tienda. opening_hours son los tramos en que abre cada día (monday a sunday) en la hora local de time_zone; un día puede tener varios tramos y los días sin tramos está cerrada
+// @Tags Turnos
+// @Accept json
+// @Produce json
+// @Param store body models.Store true "Información de la Tienda"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.Store "Tienda creada"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 409 {object} map[string]string "Ya existe una tienda con ese nombre"
+// @Router /api/v1/stores [post]
+func CreateStore(c echo.Context) error {
+	var store models.Store
+	if err := c.Bind(&store); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	store.ID = 0
+	if errs := models.ValidateStore(&store); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Create(&store).Error; err != nil {
+		return uniqueNameFailed(c, err, "stores", "A store with that name already exists")
+	}
+	return c.JSON(http.StatusCreated, store)
+}
+
+// UpdateStore actualiza una tienda
+// @Summary Actualizar tienda
+// @Description Actualiza el nombre, la zona horaria o el horario de una tienda. El nuevo horario solo se aplica a los turnos que se reserven o reprogramen después
+// @Tags Turnos
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la Tienda"
+// @Param store body models.Store true "Información actualizada de la Tienda"
+// @Success 200 {object} models.Store "Tienda actualizada"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 404 {object} map[string]string "Tienda no encontrada"
+// @Failure 409 {object} map[string]string "Ya existe una tienda con ese nombre"
+// @Router /api/v1/stores/{id} [put]
+func UpdateStore(c echo.Context) error {
+	var store models.Store
+	if err := config.DB.First(&store, c.Param("id")).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Store not found"})
+	}
+	before := store
+	if err := c.Bind(&store); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	store.ID, store.CreatedAt = before.ID, before.CreatedAt
+	if errs := models.ValidateStore(&store); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Save(&store).Error; err != nil {
+		return uniqueNameFailed(c, err, "stores", "A store with that name already exists")
+	}
+	return c.JSON(http.StatusOK, store)
+}
+
+// GetServiceTypes lista los servicios que se reservan con turnos
+// @Summary Listar servicios
+// @Description Devuelve los servicios que se pueden reservar, como baños o consultas veterinarias, con su duración
+// @Tags Turnos
+// @Produce json
+// @Success 200 {array} models.ServiceType "Servicios"
+// @Router /api/v1/service_types [get]
+func GetServiceTypes(c echo.Context) error {
+	services := []models.ServiceType{}
+	if err := config.DB.Order("name").Find(&services).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, services)
+}
+
+// CreateServiceType crea un servicio que se reserva con turnos
+// @Summary Crear servicio
+// @Description Crea un servicio con su duración en minutos, que determina cuánto ocupa cada turno
+// @Tags Turnos
+// @Accept json
+// @Produce json
+// @Param service body models.ServiceType true "Información del Servicio"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
+// @Success 201 {object} models.ServiceType "Servicio creado"
+// @Failure 400 {object} ValidationErrorResponse "Datos inválidos"
+// @Failure 409 {object} map[string]string "Ya existe un servicio con ese nombre"
+// @Router /api/v1/service_types [post]
+func CreateServiceType(c echo.Context) error {
+	var service models.ServiceType
+	if err := c.Bind(&service); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+	}
+	service.ID = 0
+	service.Name = strings.TrimSpace(service.Name)
+	if errs := models.ValidateServiceType(&service); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if err := config.DB.Create(&service).Error; err != nil {
+		return uniqueNameFailed(c, err, "service_types", "A service type with that name already exists")
+	}
+	return c.JSON(http.StatusCreated, service)
+}
+
+// uniqueNameFailed responde a un error al guardar en table; un nombre que ya existe es un 409 con message
+func uniqueNameFailed(c echo.Context, err error, table, message string) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed: "+table+".name") {
+		return c.JSON(http.StatusConflict, map[string]string{"error": message})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"golangApp/config"
 	"golangApp/models"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // GetUser obtiene un usuario por ID
@@ -16,6 +18,7 @@ import (
 // @Param id path int true "ID del Usuario"
 // @Produce json
 // @Success 200 {object} models.User "Detalles del usuario"
+// @Header 200 {string} ETag "Versión del recurso"
 // @Router /api/v1/users/{id} [get]
 func GetUser(c echo.Context) error {
 	id := c.Param("id")
@@ -27,6 +30,7 @@ func GetUser(c echo.Context) error {
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusOK, user)
 }
 
@@ -56,7 +60,10 @@ func GetAllUsers(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param user body models.User true "Información del Usuario"
+// @Param Idempotency-Key header string false "Clave para reintentar la petición sin repetir sus efectos"
 // @Success 201 {object} models.User "Usuario creado exitosamente"
+// @Failure 409 {object} map[string]string "Hay una petición en curso con la misma Idempotency-Key"
+// @Failure 422 {object} map[string]string "Idempotency-Key usada con otra petición"
 // @Router /api/v1/users [post]
 func CreateUser(c echo.Context) error {
 	var user models.User
@@ -81,6 +88,7 @@ func CreateUser(c echo.Context) error {
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusCreated, user)
 }
 
@@ -91,8 +99,12 @@ func CreateUser(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID del Usuario"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Param user body models.User true "Información del Usuario"
 // @Success 200 {object} models.User "Usuario actualizado exitosamente"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/users/{id} [put]
 func UpdateUser(c echo.Context) error {
 	id := c.Param("id")
@@ -103,19 +115,31 @@ func UpdateUser(c echo.Context) error {
 			"message": "User not found",
 		})
 	}
+	if status, err := checkIfMatch(c, user.Version); err != nil {
+		return c.JSON(status, echo.Map{
+			"message": err.Error(),
+		})
+	}
 
+	userID, version := user.ID, user.Version
 	if err := c.Bind(&user); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{
 			"message": "Invalid input",
 		})
 	}
+	user.ID, user.Version = userID, version
 
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := saveUser(&user); errors.Is(err, errStaleVersion) {
+		return c.JSON(http.StatusPreconditionFailed, echo.Map{
+			"message": err.Error(),
+		})
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to update user",
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusOK, user)
 }
 
@@ -124,8 +148,12 @@ func UpdateUser(c echo.Context) error {
 // @Description Cambia el estado de un usuario a habilitado
 // @Tags Usuarios
 // @Param id path int true "ID del Usuario"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Produce json
 // @Success 200 {object} models.User "Usuario habilitado"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/users/{id}/enable [put]
 func EnableUser(c echo.Context) error {
 	id := c.Param("id")
@@ -136,14 +164,24 @@ func EnableUser(c echo.Context) error {
 			"message": "User not found",
 		})
 	}
+	if status, err := checkIfMatch(c, user.Version); err != nil {
+		return c.JSON(status, echo.Map{
+			"message": err.Error(),
+		})
+	}
 
 	user.IsEnabled = true
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := saveUser(&user); errors.Is(err, errStaleVersion) {
+		return c.JSON(http.StatusPreconditionFailed, echo.Map{
+			"message": err.Error(),
+		})
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to enable user",
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusOK, user)
 }
 
@@ -152,8 +190,12 @@ func EnableUser(c echo.Context) error {
 // @Description Cambia el estado de un usuario a deshabilitado
 // @Tags Usuarios
 // @Param id path int true "ID del Usuario"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Produce json
 // @Success 200 {object} models.User "Usuario deshabilitado"
+// @Header 200 {string} ETag "Versión del recurso"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/users/{id}/disable [put]
 func DisableUser(c echo.Context) error {
 	id := c.Param("id")
@@ -164,14 +206,24 @@ func DisableUser(c echo.Context) error {
 			"message": "User not found",
 		})
 	}
+	if status, err := checkIfMatch(c, user.Version); err != nil {
+		return c.JSON(status, echo.Map{
+			"message": err.Error(),
+		})
+	}
 
 	user.IsEnabled = false
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := saveUser(&user); errors.Is(err, errStaleVersion) {
+		return c.JSON(http.StatusPreconditionFailed, echo.Map{
+			"message": err.Error(),
+		})
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to disable user",
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusOK, user)
 }
 
@@ -180,7 +232,10 @@ func DisableUser(c echo.Context) error {
 // @Description Elimina un usuario específico usando su ID
 // @Tags Usuarios
 // @Param id path int true "ID del Usuario"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Success 204 "Usuario eliminado exitosamente"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/users/{id} [delete]
 func DeleteUser(c echo.Context) error {
 	id := c.Param("id")
@@ -191,8 +246,26 @@ func DeleteUser(c echo.Context) error {
 			"message": "User not found",
 		})
 	}
+	if status, err := checkIfMatch(c, user.Version); err != nil {
+		return c.JSON(status, echo.Map{
+			"message": err.Error(),
+		})
+	}
 
-	if err := config.DB.Delete(&user).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.User{}, user.ID, user.Version); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.CalendarToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if errors.Is(err, errStaleVersion) {
+		return c.JSON(http.StatusPreconditionFailed, echo.Map{
+			"message": err.Error(),
+		})
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to delete user",
 		})
@@ -206,8 +279,11 @@ func DeleteUser(c echo.Context) error {
 // @Description Cambia la contraseña de un usuario a una nueva
 // @Tags Usuarios
 // @Param id path int true "ID del Usuario"
+// @Param If-Match header string false "ETag de la versión que se modifica"
 // @Param new_password body string true "Nueva contraseña"
 // @Success 200 "Contraseña restablecida exitosamente"
+// @Failure 412 {object} map[string]string "La versión no coincide con la actual"
+// @Failure 428 {object} map[string]string "Falta la cabecera If-Match (modo estricto)"
 // @Router /api/v1/users/{id}/reset_password [put]
 func ResetPassword(c echo.Context) error {
 	id := c.Param("id")
@@ -218,6 +294,11 @@ func ResetPassword(c echo.Context) error {
 			"message": "User not found",
 		})
 	}
+	if status, err := checkIfMatch(c, user.Version); err != nil {
+		return c.JSON(status, echo.Map{
+			"message": err.Error(),
+		})
+	}
 
 	type ResetPasswordRequest struct {
 		NewPassword string `json:"new_password"`
@@ -238,11 +319,27 @@ func ResetPassword(c echo.Context) error {
 	}
 
 	user.Password = string(hashedPassword)
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := saveUser(&user); errors.Is(err, errStaleVersion) {
+		return c.JSON(http.StatusPreconditionFailed, echo.Map{
+			"message": err.Error(),
+		})
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"message": "Failed to reset password",
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.NoContent(http.StatusOK)
 }
+
+// saveUser guarda el usuario incrementando su versión, siempre que nadie lo haya modificado desde que se leyó
+func saveUser(user *models.User) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, &models.User{}, user.ID, user.Version); err != nil {
+			return err
+		}
+		user.Version++
+		return tx.Save(user).Error
+	})
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"time"
+
+	"golangApp/models"
+
+	"gorm.io/gorm"
+)
+
+// PurgeDeletedClients elimina definitivamente los clientes borrados hace más de retention. También elimina
+// las mascotas borradas en ese período, entre ellas las de esos clientes, con su historial de pesos y
+// sanitario, y el historial de versiones, las notificaciones, los turnos, los pedidos y el libro de puntos de
+// los clientes purgados.
+// Los turnos de otros clientes con una mascota purgada se conservan sin la mascota
+func PurgeDeletedClients(db *gorm.DB, retention time.Duration) (int64, error) {
+	cutoff := models.Now().Add(-retention)
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		pets := tx.Unscoped().Model(&models.Pet{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		if err := tx.Where("pet_id IN (?)", pets).Delete(&models.PetWeight{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("pet_id IN (?)", pets).Delete(&models.PetTreatment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Appointment{}).Where("pet_id IN (?)", pets).UpdateColumn("pet_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Pet{}).Error; err != nil {
+			return err
+		}
+		clients := tx.Unscoped().Model(&models.Client{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		if err := tx.Where("client_id IN (?)", clients).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		appointments := tx.Model(&models.Appointment{}).Select("id").Where("client_id IN (?)", clients)
+		if err := tx.Where("appointment_id IN (?)", appointments).Delete(&models.AppointmentChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id IN (?)", clients).Delete(&models.Appointment{}).Error; err != nil {
+			return err
+		}
+		orders := tx.Model(&models.Order{}).Select("id").Where("client_id IN (?)", clients)
+		if err := tx.Where("order_id IN (?)", orders).Delete(&models.OrderLine{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id IN (?)", clients).Delete(&models.Order{}).Error; err != nil {
+			return err
+		}
+		if err := models.PurgeLoyaltyEntries(tx, clients); err != nil {
+			return err
+		}
+		if err := models.PurgeClientVersions(tx, clients); err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Client{})
+		purged = res.RowsAffected
+		return res.Error
+	})
+	return purged, err
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPurgeDeletedClients(t *testing.T) {
+	config.SetupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+
+	now := time.Now()
+	clients := []struct {
+		email     string
+		deletedAt *time.Time
+	}{
+		{"active@example.com", nil},
+		{"recent@example.com", ptr(now.Add(-24 * time.Hour))},
+		{"old@example.com", ptr(now.Add(-31 * 24 * time.Hour))},
+	}
+	for _, c := range clients {
+		client := models.Client{Name: "N", LastName: "L", Email: c.email, Telephone: "600123456"}
+		config.DB.Create(&client)
+		pet := models.Pet{ClientID: client.ID, Name: "P", Species: models.SpeciesDog, Weights: []models.PetWeight{{Kilograms: 10, MeasuredOn: now}}}
+		config.DB.Create(&pet)
+		config.DB.Create(&models.Appointment{ClientID: client.ID, PetID: &pet.ID, ServiceTypeID: 1, StoreID: 1, StaffID: 1, StartsAt: now, EndsAt: now})
+		order := models.Order{ClientID: client.ID, Channel: models.ChannelOnline, PlacedAt: now, Lines: []models.OrderLine{{Product: "Pienso", Quantity: 1}}}
+		config.DB.Create(&order)
+		config.DB.Create(&models.LoyaltyEntry{ClientID: client.ID, Kind: models.LoyaltyAccrual, Points: 10, OrderID: &order.ID})
+		config.DB.Create(&models.ClientVersion{ClientID: client.ID, Version: 1, Action: models.ActionCreate, Client: client})
+		if c.deletedAt != nil {
+			config.DB.Unscoped().Model(&client).Update("deleted_at", *c.deletedAt)
+			config.DB.Unscoped().Model(&pet).Update("deleted_at", *c.deletedAt)
+		}
+	}
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.PetWeight{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Appointment{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Pet{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.OrderLine{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Order{})
+	defer config.DB.Exec("DELETE FROM loyalty_entries")
+	defer config.DB.Exec("DELETE FROM client_versions")
+
+	purged, err := PurgeDeletedClients(config.DB, 30*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var remaining []string
+	config.DB.Unscoped().Model(&models.Client{}).Order("id").Pluck("email", &remaining)
+	assert.Equal(t, []string{"active@example.com", "recent@example.com"}, remaining)
+
+	// Las mascotas de los clientes purgados se eliminan con su historial de pesos
+	var pets, weights int64
+	config.DB.Unscoped().Model(&models.Pet{}).Count(&pets)
+	config.DB.Model(&models.PetWeight{}).Count(&weights)
+	assert.Equal(t, []int64{2, 2}, []int64{pets, weights})
+
+	// Los turnos de los clientes purgados también se eliminan
+	var appointments int64
+	config.DB.Model(&models.Appointment{}).Count(&appointments)
+	assert.Equal(t, int64(2), appointments)
+
+	// Y sus pedidos y su libro de puntos, aunque el libro no admita bajas
+	var orders, lines, entries int64
+	config.DB.Model(&models.Order{}).Count(&orders)
+	config.DB.Model(&models.OrderLine{}).Count(&lines)
+	config.DB.Model(&models.LoyaltyEntry{}).Count(&entries)
+	assert.Equal(t, []int64{2, 2, 2}, []int64{orders, lines, entries})
+
+	// Tampoco quedan copias de sus datos en el historial
+	var versions []string
+	config.DB.Model(&models.ClientVersion{}).Order("client_id").Pluck("json_extract(client, '$.email')", &versions)
+	assert.Equal(t, []string{"active@example.com", "recent@example.com"}, versions)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
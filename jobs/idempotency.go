@@ -0,0 +1,13 @@
+package jobs
+
+import (
+	"golangApp/models"
+
+	"gorm.io/gorm"
+)
+
+// PurgeIdempotencyKeys elimina las respuestas guardadas cuyas claves de idempotencia ya vencieron
+func PurgeIdempotencyKeys(db *gorm.DB) (int64, error) {
+	res := db.Where("expires_at <= ?", models.Now()).Delete(&models.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPurgeIdempotencyKeys(t *testing.T) {
+	config.SetupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.IdempotencyKey{})
+
+	now := time.Now()
+	config.DB.Create(&models.IdempotencyKey{Key: "expired", Actor: "admin", Fingerprint: "f", ExpiresAt: now.Add(-time.Hour)})
+	config.DB.Create(&models.IdempotencyKey{Key: "valid", Actor: "admin", Fingerprint: "f", ExpiresAt: now.Add(time.Hour)})
+
+	purged, err := PurgeIdempotencyKeys(config.DB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var remaining []string
+	config.DB.Model(&models.IdempotencyKey{}).Pluck("key", &remaining)
+	assert.Equal(t, []string{"valid"}, remaining)
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+)
+
+// ageRolloverInterval es cada cuánto se comprueba si cambió el día para pasar a su nueva edad a quienes
+// cumplen años, de modo que el primer KPI del día no tenga que hacerlo
+const ageRolloverInterval = time.Hour
+
+// loyaltyExpiryInterval es cada cuánto se registran los vencimientos de puntos de fidelidad. Las consultas de
+// saldo y extracto también los registran; el KPI, que no escribe, descuenta los que ya registró esta tarea
+const loyaltyExpiryInterval = time.Hour
+
+// Start lanza en segundo plano las tareas periódicas configuradas hasta que se cancele ctx
+func Start(ctx context.Context) {
+	every(ctx, config.Settings.PurgeInterval, "purge deleted clients", func() error {
+		purged, err := PurgeDeletedClients(config.DB, config.Settings.ClientRetention)
+		if err == nil && purged > 0 {
+			log.Printf("Purged %d deleted clients", purged)
+		}
+		return err
+	})
+	every(ctx, config.Settings.PurgeInterval, "purge idempotency keys", func() error {
+		_, err := PurgeIdempotencyKeys(config.DB)
+		return err
+	})
+	every(ctx, config.Settings.PurgeInterval, "purge KPI snapshots", func() error {
+		_, err := PurgeKPISnapshots(config.DB, config.Settings.KPISnapshotRetention)
+		return err
+	})
+	every(ctx, ageRolloverInterval, "roll over client ages", func() error {
+		_, _, err := models.LoadClientAgeStats(config.DB)
+		return err
+	})
+	every(ctx, config.Settings.KPISnapshotInterval, "snapshot client KPIs", func() error {
+		_, err := TakeKPISnapshot(config.DB)
+		return err
+	})
+	every(ctx, config.Settings.ReminderInterval, "send treatment reminders", func() error {
+		sent, err := SendTreatmentReminders(config.DB, config.Settings.TreatmentReminderDays)
+		if err == nil && sent > 0 {
+			log.Printf("Sent %d treatment reminders", sent)
+		}
+		return err
+	})
+	every(ctx, loyaltyExpiryInterval, "expire loyalty points", func() error {
+		expired, err := models.ExpireLoyaltyPoints(config.DB, 0)
+		if err == nil && expired > 0 {
+			log.Printf("Expired %d loyalty points", expired)
+		}
+		return err
+	})
+}
+
+// every ejecuta task cada interval; un intervalo no positivo desactiva la tarea
+func every(ctx context.Context, interval time.Duration, name string, task func() error) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := task(); err != nil {
+					log.Printf("Job %q failed: %v", name, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"time"
+
+	"golangApp/models"
+
+	"gorm.io/gorm"
+)
+
+// TakeKPISnapshot guarda la distribución de edades de los clientes activos y la cantidad de eliminados
+func TakeKPISnapshot(db *gorm.DB) (models.KPISnapshot, error) {
+	now := models.Now()
+	snapshot := models.KPISnapshot{TakenAt: now}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if snapshot.Ages, err = models.ClientAgeDistribution(tx, now.UTC().Format("2006-01-02")); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Client{}).Unscoped().Where("deleted_at IS NOT NULL").Count(&snapshot.DeletedCount).Error; err != nil {
+			return err
+		}
+		snapshot.Count = snapshot.Ages.Count()
+		return tx.Create(&snapshot).Error
+	})
+	return snapshot, err
+}
+
+// PurgeKPISnapshots elimina las fotos de KPI más antiguas que retention; 0 las conserva todas
+func PurgeKPISnapshots(db *gorm.DB, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	res := db.Where("taken_at < ?", models.Now().Add(-retention)).Delete(&models.KPISnapshot{})
+	return res.RowsAffected, res.Error
+}
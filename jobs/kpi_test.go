@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTakeKPISnapshot(t *testing.T) {
+	config.SetupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.KPISnapshot{})
+
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	models.Now = func() time.Time { return now }
+	defer func() { models.Now = time.Now }()
+
+	births := []time.Time{
+		time.Date(1994, 6, 15, 0, 0, 0, 0, time.UTC), // 30
+		time.Date(1994, 6, 16, 0, 0, 0, 0, time.UTC), // 29
+		time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),  // 34
+		time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),  // 34, eliminado
+	}
+	for i, birth := range births {
+		client := models.Client{Name: "N", LastName: "L", Email: string(rune('a'+i)) + "@example.com", Telephone: "600123456", BirthDay: birth}
+		config.DB.Create(&client)
+		if i == len(births)-1 {
+			config.DB.Delete(&client)
+		}
+	}
+
+	snapshot, err := TakeKPISnapshot(config.DB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), snapshot.Count)
+	assert.Equal(t, int64(1), snapshot.DeletedCount)
+
+	var stored models.KPISnapshot
+	config.DB.First(&stored, snapshot.ID)
+	assert.True(t, now.Equal(stored.TakenAt))
+	assert.Equal(t, models.Distribution{{Value: 29, Count: 1}, {Value: 30, Count: 1}, {Value: 34, Count: 1}}, stored.Ages)
+}
+
+func TestPurgeKPISnapshots(t *testing.T) {
+	config.SetupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.KPISnapshot{})
+
+	now := time.Now()
+	for _, age := range []time.Duration{time.Hour, 40 * 24 * time.Hour} {
+		config.DB.Create(&models.KPISnapshot{TakenAt: now.Add(-age), Ages: models.Distribution{}})
+	}
+
+	purged, err := PurgeKPISnapshots(config.DB, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged, "Sin retención se conservan todas las fotos")
+
+	purged, err = PurgeKPISnapshots(config.DB, 30*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var remaining int64
+	config.DB.Model(&models.KPISnapshot{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+}
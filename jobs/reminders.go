@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"fmt"
+
+	"golangApp/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dueTreatment es un registro sanitario pendiente con la mascota y el cliente a los que se recuerda
+type dueTreatment struct {
+	models.PetTreatment
+	PetName  string
+	ClientID int
+}
+
+// SendTreatmentReminders emite una notificación al dueño de cada mascota activa con un tratamiento pendiente
+// que vence desde hoy hasta dentro de days días. Cada fecha de repetición se recuerda una sola vez, por lo
+// que se puede ejecutar tantas veces como se quiera; los que ya vencieron no se recuerdan
+func SendTreatmentReminders(db *gorm.DB, days int) (int64, error) {
+	today := models.Now().UTC().Format("2006-01-02")
+	until := models.Now().UTC().AddDate(0, 0, days).Format("2006-01-02")
+
+	var due []dueTreatment
+	err := models.PendingTreatments(db.Model(&models.PetTreatment{})).
+		Select("pet_treatments.*, pets.name AS pet_name, pets.client_id AS client_id").
+		Joins("JOIN pets ON pets.id = pet_treatments.pet_id AND pets.deleted_at IS NULL").
+		Joins("JOIN clients ON clients.id = pets.client_id AND clients.deleted_at IS NULL").
+		Where("date(pet_treatments.next_due_on) BETWEEN ? AND ?", today, until).
+		Where("NOT EXISTS (SELECT 1 FROM notifications WHERE notifications.treatment_id = pet_treatments.id AND notifications.due_on = pet_treatments.next_due_on)").
+		Order("pet_treatments.next_due_on, pet_treatments.id").
+		Scan(&due).Error
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+
+	notifications := make([]models.Notification, len(due))
+	for i, t := range due {
+		petID, treatmentID := t.PetID, t.ID
+		notifications[i] = models.Notification{
+			ClientID:    t.ClientID,
+			Kind:        models.NotificationTreatmentDue,
+			Message:     fmt.Sprintf("%s's %s %s is due on %s", t.PetName, t.Product, t.Kind, t.NextDueOn.Format("2006-01-02")),
+			PetID:       &petID,
+			TreatmentID: &treatmentID,
+			DueOn:       t.NextDueOn,
+		}
+	}
+	// Si otra instancia emitió el mismo recordatorio mientras tanto, el índice único lo descarta
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSendTreatmentReminders(t *testing.T) {
+	config.SetupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Client{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&models.Pet{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.PetTreatment{})
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Notification{})
+
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	models.Now = func() time.Time { return now }
+	defer func() { models.Now = time.Now }()
+	day := func(offset int) *time.Time {
+		d := time.Date(2024, 6, 15+offset, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	owner := models.Client{Name: "John", LastName: "Doe", Email: "john@example.com", Telephone: "600123456"}
+	gone := models.Client{Name: "Jane", LastName: "Doe", Email: "jane@example.com", Telephone: "600123457"}
+	config.DB.Create(&owner)
+	config.DB.Create(&gone)
+	luna := models.Pet{ClientID: owner.ID, Name: "Luna", Species: models.SpeciesCat}
+	kiwi := models.Pet{ClientID: gone.ID, Name: "Kiwi", Species: models.SpeciesBird}
+	config.DB.Create(&luna)
+	config.DB.Create(&kiwi)
+	config.DB.Delete(&gone)
+
+	rabies := models.PetTreatment{PetID: luna.ID, Kind: models.TreatmentVaccination, Product: "Nobivac Rabies", AdministeredOn: *day(-365), NextDueOn: day(3)}
+	for _, treatment := range []*models.PetTreatment{
+		&rabies,
+		{PetID: luna.ID, Kind: models.TreatmentDeworming, Product: "Milbemax", AdministeredOn: *day(-90), NextDueOn: day(20)},
+		{PetID: luna.ID, Kind: models.TreatmentDeworming, Product: "Drontal", AdministeredOn: *day(-90), NextDueOn: day(-1)},
+		{PetID: kiwi.ID, Kind: models.TreatmentDeworming, Product: "Panacur", AdministeredOn: *day(-90), NextDueOn: day(1)},
+	} {
+		config.DB.Create(treatment)
+	}
+
+	sent, err := SendTreatmentReminders(config.DB, 14)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), sent, "Solo se recuerdan los que vencen en la ventana, de clientes activos")
+	var notifications []models.Notification
+	config.DB.Find(&notifications)
+	if assert.Len(t, notifications, 1) {
+		n := notifications[0]
+		assert.Equal(t, owner.ID, n.ClientID)
+		assert.Equal(t, models.NotificationTreatmentDue, n.Kind)
+		assert.Equal(t, "Luna's Nobivac Rabies vaccination is due on 2024-06-18", n.Message)
+		assert.Equal(t, rabies.ID, *n.TreatmentID)
+	}
+
+	sent, err = SendTreatmentReminders(config.DB, 14)
+	assert.NoError(t, err)
+	assert.Zero(t, sent, "Cada fecha se recuerda una sola vez")
+
+	// Si se pospone la fecha, se recuerda la nueva
+	config.DB.Model(&rabies).Update("next_due_on", day(5))
+	sent, err = SendTreatmentReminders(config.DB, 14)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), sent)
+}
@@ -34,7 +34,7 @@ func main() {
 	e := echo.New()
 
 	// Middleware
-	e.Use(middleware.Logger())
+	e.Use(middlewares.Logger())
 	e.Use(middleware.Recover())
 
 	// Setup session middleware
@@ -42,19 +42,69 @@ func main() {
 
 	// Routes that don't require authentication
 	e.POST("/login", handlers.HandleLogin)
+	// Las aplicaciones de calendario se autentican con el token de calendario del usuario
+	e.GET("/calendar/:token/birthdays.ics", handlers.GetClientBirthdayCalendar)
+	e.GET("/calendar/:token/appointments.ics", handlers.GetStaffAppointmentCalendar)
 
 	// Group of routes that require authentication
 	auth := e.Group("/api/v1")
 
 	// Apply the Basic Auth Middleware only to specific routes
 	auth.Use(middleware.BasicAuth(middlewares.BasicAuthMiddleware))
+	auth.Use(middlewares.Idempotency)
 
 	auth.GET("/clients/:id", handlers.GetClient)
 	auth.GET("/clients", handlers.GetAll)
 	auth.GET("/clients/kpi", handlers.GetClientKPI)
+	auth.GET("/clients/kpi/history", handlers.GetClientKPIHistory)
+	auth.GET("/clients/search", handlers.SearchClients)
+	auth.GET("/clients/export", handlers.ExportClients)
+	auth.GET("/clients/duplicates", handlers.GetClientDuplicates)
+	auth.GET("/clients/birthdays", handlers.GetClientBirthdays)
+	auth.GET("/clients/cohorts", handlers.GetClientCohorts)
+	auth.GET("/pets/kpi", handlers.GetPetKPI)
+	auth.GET("/treatments/overdue", handlers.GetOverdueTreatments)
+	auth.GET("/treatments/upcoming", handlers.GetUpcomingTreatments)
 	auth.POST("/clients", handlers.CreateClient)
+	auth.POST("/clients/import", handlers.ImportClients)
+	auth.POST("/clients/merge", handlers.MergeClients)
+	auth.POST("/clients/kpi/snapshots", handlers.CreateClientKPISnapshot)
 	auth.PUT("/clients/:id", handlers.UpdateClient)
+	auth.PATCH("/clients/:id", handlers.PatchClient)
 	auth.DELETE("/clients/:id", handlers.DeleteClient)
+	auth.POST("/clients/:id/restore", handlers.RestoreClient)
+	auth.GET("/clients/:id/history", handlers.GetClientHistory)
+	auth.POST("/clients/:id/revert/:version", handlers.RevertClient)
+	auth.GET("/clients/:id/pets", handlers.GetClientPets)
+	auth.POST("/clients/:id/pets", handlers.CreateClientPet)
+	auth.GET("/clients/:id/pets/:pet_id", handlers.GetClientPet)
+	auth.PUT("/clients/:id/pets/:pet_id", handlers.UpdateClientPet)
+	auth.DELETE("/clients/:id/pets/:pet_id", handlers.DeleteClientPet)
+	auth.POST("/clients/:id/pets/:pet_id/weights", handlers.AddPetWeight)
+	auth.DELETE("/clients/:id/pets/:pet_id/weights/:weight_id", handlers.DeletePetWeight)
+	auth.GET("/clients/:id/pets/:pet_id/treatments", handlers.GetPetTreatments)
+	auth.POST("/clients/:id/pets/:pet_id/treatments", handlers.CreatePetTreatment)
+	auth.PUT("/clients/:id/pets/:pet_id/treatments/:treatment_id", handlers.UpdatePetTreatment)
+	auth.DELETE("/clients/:id/pets/:pet_id/treatments/:treatment_id", handlers.DeletePetTreatment)
+	auth.GET("/clients/:id/notifications", handlers.GetClientNotifications)
+	auth.GET("/stores", handlers.GetStores)
+	auth.POST("/stores", handlers.CreateStore)
+	auth.PUT("/stores/:id", handlers.UpdateStore)
+	auth.GET("/service_types", handlers.GetServiceTypes)
+	auth.POST("/service_types", handlers.CreateServiceType)
+	auth.GET("/appointments", handlers.GetAppointments)
+	auth.GET("/appointments/:id", handlers.GetAppointment)
+	auth.POST("/appointments", handlers.CreateAppointment)
+	auth.POST("/appointments/:id/reschedule", handlers.RescheduleAppointment)
+	auth.POST("/appointments/:id/cancel", handlers.CancelAppointment)
+	auth.GET("/clients/:id/orders", handlers.GetClientOrders)
+	auth.POST("/clients/:id/orders", handlers.CreateClientOrder)
+	auth.GET("/clients/:id/loyalty", handlers.GetClientLoyaltyBalance)
+	auth.GET("/clients/:id/loyalty/statement", handlers.GetClientLoyaltyStatement)
+	auth.POST("/clients/:id/loyalty/redemptions", handlers.RedeemClientPoints)
+	auth.GET("/loyalty/rules", handlers.GetLoyaltyRules)
+	auth.POST("/loyalty/rules", handlers.CreateLoyaltyRule)
+	auth.PUT("/loyalty/rules/:id", handlers.UpdateLoyaltyRule)
 	auth.GET("/users/:id", handlers.GetUser)
 	auth.GET("/users", handlers.GetAllUsers)
 	auth.GET("/groups/:id", handlers.GetGroup)
@@ -69,6 +119,8 @@ func main() {
 	auth.DELETE("/users/:id/groups/:group_id", handlers.RemoveAssignGroup)
 	auth.DELETE("/groups/:group_id", handlers.RemoveGroup)
 	auth.PUT("/users/:id/reset_password", handlers.ResetPassword)
+	auth.PUT("/users/:id/calendar_token", handlers.RegenerateCalendarToken)
+	auth.DELETE("/users/:id/calendar_token", handlers.RevokeCalendarToken)
 
 	// Swagger documentation endpoint
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
@@ -1,7 +1,7 @@
 #!/bin/bash
 
 # Compile main.go for Lambda (setting GOOS=linux because Lambda uses a Linux environment)
-GOOS=linux GOARCH=amd64 go build -o main .
+GOOS=linux GOARCH=amd64 go build -tags sqlite_fts5 -o main .
 
 # Install AWS SAM CLI if not already installed
 if ! command -v sam &> /dev/null
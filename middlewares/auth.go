@@ -11,6 +11,8 @@ import (
 func BasicAuthMiddleware(username, password string, c echo.Context) (bool, error) {
 	// Replace with your user validation logic
 	if username == "admin" && password == "admin" {
+		// El usuario autenticado queda disponible para auditar los cambios
+		c.Set("username", username)
 		return true, nil
 	} else {
 		return false, echo.NewHTTPError(http.StatusUnauthorized, "Invalid credentials")
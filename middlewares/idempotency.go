@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// HeaderIdempotencyKey es la cabecera con la que el cliente identifica un POST que puede reintentar
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marca las respuestas repetidas a partir de una petición anterior
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders son las cabeceras de la respuesta original que se guardan para repetirlas en los reintentos
+var replayedHeaders = []string{"ETag", echo.HeaderLocation}
+
+// Idempotency permite reintentar los POST que envían Idempotency-Key sin repetir sus efectos.
+// La primera respuesta se guarda durante IDEMPOTENCY_TTL y se devuelve tal cual a los reintentos, con su
+// ETag y su Location; reutilizar la clave con otra petición devuelve 422
+func Idempotency(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(HeaderIdempotencyKey)
+		if c.Request().Method != http.MethodPost || key == "" {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Idempotency-Key must be at most 255 characters long"})
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request data"})
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		actor, _ := c.Get("username").(string)
+		now := models.Now()
+		record := models.IdempotencyKey{
+			Key:         key,
+			Actor:       actor,
+			Fingerprint: requestFingerprint(c.Request(), body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(config.Settings.IdempotencyTTL),
+		}
+
+		// Una clave vencida se puede volver a usar
+		if err := config.DB.Where("actor = ? AND key = ? AND expires_at <= ?", actor, key, now).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		res := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if res.Error != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": res.Error.Error()})
+		}
+		if res.RowsAffected == 0 {
+			return replayResponse(c, record)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		err = next(c)
+
+		// Los errores del servidor no se guardan para que el cliente pueda reintentar
+		status := c.Response().Status
+		if err != nil || !c.Response().Committed || status >= http.StatusInternalServerError {
+			config.DB.Delete(&record)
+			return err
+		}
+		headers := map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := c.Response().Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		config.DB.Model(&record).Updates(models.IdempotencyKey{
+			Status:      status,
+			ContentType: c.Response().Header().Get(echo.HeaderContentType),
+			Body:        recorder.body.Bytes(),
+			Headers:     headers,
+		})
+		return nil
+	}
+}
+
+// replayResponse responde a un reintento con la respuesta guardada para la misma clave
+func replayResponse(c echo.Context, attempt models.IdempotencyKey) error {
+	var stored models.IdempotencyKey
+	if err := config.DB.Where("actor = ? AND key = ?", attempt.Actor, attempt.Key).First(&stored).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	switch {
+	case stored.Fingerprint != attempt.Fingerprint:
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Idempotency-Key was already used with a different request"})
+	case stored.Status == 0:
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A request with this Idempotency-Key is still in progress"})
+	}
+	for name, value := range stored.Headers {
+		c.Response().Header().Set(name, value)
+	}
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(stored.Status, stored.ContentType, stored.Body)
+}
+
+// requestFingerprint identifica una petición por su método, ruta y cuerpo.
+// Los cuerpos JSON se normalizan para que el orden de las claves o los espacios no cuenten, y de los
+// formularios multipart solo cuentan sus partes, porque el separador cambia en cada reintento
+func requestFingerprint(req *http.Request, body []byte) string {
+	var doc interface{}
+	if json.Unmarshal(body, &doc) == nil {
+		body, _ = json.Marshal(doc)
+	} else if parts, ok := multipartFingerprint(req, body); ok {
+		body = parts
+	}
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// multipartFingerprint resume un formulario multipart en el nombre de cada campo, el nombre de archivo y el
+// hash de su contenido, en el orden en que llegan. false si el cuerpo no es un formulario multipart válido
+func multipartFingerprint(req *http.Request, body []byte) ([]byte, bool) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if err != nil || mediaType != echo.MIMEMultipartForm || params["boundary"] == "" {
+		return nil, false
+	}
+	var parts bytes.Buffer
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts.Bytes(), true
+		}
+		if err != nil {
+			return nil, false
+		}
+		content := sha256.New()
+		if _, err := io.Copy(content, part); err != nil {
+			return nil, false
+		}
+		parts.WriteString(part.FormName() + "\x00" + part.FileName() + "\x00" + hex.EncodeToString(content.Sum(nil)) + "\n")
+	}
+}
+
+// responseRecorder copia el cuerpo de la respuesta mientras se envía
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
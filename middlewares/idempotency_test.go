@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golangApp/config"
+	"golangApp/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestIdempotency(t *testing.T) {
+	config.SetupTestDB()
+	defer config.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.IdempotencyKey{})
+
+	calls := 0
+	fail := false
+	e := echo.New()
+	e.POST("/clients", func(c echo.Context) error {
+		calls++
+		if fail {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "boom"})
+		}
+		c.Response().Header().Set("ETag", `"1"`)
+		c.Response().Header().Set(echo.HeaderLocation, "/clients/"+strconv.Itoa(calls))
+		return c.JSON(http.StatusCreated, map[string]int{"id": calls})
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("username", c.Request().Header.Get("X-User"))
+			return next(c)
+		}
+	}, Idempotency)
+
+	post := func(key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/clients", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-User", user)
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("k1", "admin", `{"name": "John", "email": "john@example.com"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// Un reintento con el mismo cuerpo, aunque cambie el orden de las claves, repite la respuesta
+	replay := post("k1", "admin", `{"email":"john@example.com","name":"John"}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, "true", replay.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, `"1"`, replay.Header().Get("ETag"))
+	assert.Equal(t, "/clients/1", replay.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, 1, calls)
+
+	rec := post("k1", "admin", `{"name": "Jane"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Las claves son de cada usuario
+	rec = post("k1", "other", `{"name": "John", "email": "john@example.com"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, 2, calls)
+
+	post("", "admin", `{}`)
+	post("", "admin", `{}`)
+	assert.Equal(t, 4, calls, "Sin Idempotency-Key no se guarda nada")
+
+	// Los errores del servidor no se guardan
+	fail = true
+	rec = post("k2", "admin", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	fail = false
+	rec = post("k2", "admin", `{}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+
+	// Una clave vencida se puede reutilizar
+	config.DB.Model(&models.IdempotencyKey{}).Where("key = ?", "k1").Update("expires_at", time.Now().Add(-time.Minute))
+	rec = post("k1", "admin", `{"name": "Jane"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
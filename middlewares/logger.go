@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// calendarToken reconoce el token de suscripción que llevan en la dirección las rutas de calendario
+var calendarToken = regexp.MustCompile(`^/calendar/[^/?]+`)
+
+// Logger registra cada petición con el formato por defecto de Echo, pero ocultando el token de las rutas de
+// calendario: quien lea los logs podría suscribirse con él a los calendarios del usuario
+func Logger() echo.MiddlewareFunc {
+	return middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: strings.Replace(middleware.DefaultLoggerConfig.Format, `"uri":"${uri}"`, `"uri":"${custom}"`, 1),
+		CustomTagFunc: func(c echo.Context, buf *bytes.Buffer) (int, error) {
+			return buf.WriteString(redactURI(c.Request().RequestURI))
+		},
+	})
+}
+
+// redactURI sustituye el token de calendario de uri por ***
+func redactURI(uri string) string {
+	return calendarToken.ReplaceAllLiteralString(uri, "/calendar/***")
+}
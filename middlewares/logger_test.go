@@ -0,0 +1,13 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactURI(t *testing.T) {
+	assert.Equal(t, "/calendar/***/birthdays.ics?days=30", redactURI("/calendar/s3cr3t/birthdays.ics?days=30"))
+	assert.Equal(t, "/calendar/***/appointments.ics", redactURI("/calendar/s3cr3t/appointments.ics"))
+	assert.Equal(t, "/api/v1/clients?q=calendar/x", redactURI("/api/v1/clients?q=calendar/x"))
+}
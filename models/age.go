@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Now es el reloj con el que se calculan las edades; los tests pueden reemplazarlo
+var Now = time.Now
+
+// AgeAt devuelve los años cumplidos en la fecha on por alguien nacido en birthDay.
+// Quien nació un 29 de febrero cumple años el 1 de marzo en los años no bisiestos
+func AgeAt(birthDay, on time.Time) int {
+	by, bm, bd := birthDay.Date()
+	oy, om, od := on.In(birthDay.Location()).Date()
+	age := oy - by
+	if om < bm || (om == bm && od < bd) {
+		age--
+	}
+	return age
+}
+
+// ClientAgeSQL calcula en SQLite la edad a partir de birth_day con el mismo criterio que AgeAt.
+// Recibe dos veces la fecha del día (YYYY-MM-DD). También sirve para las mascotas, que tienen la misma
+// columna; sin birth_day la edad es NULL
+const ClientAgeSQL = `(CAST(strftime('%Y', ?) AS INTEGER) - CAST(strftime('%Y', birth_day) AS INTEGER) - (strftime('%m-%d', ?) < strftime('%m-%d', birth_day)))`
+
+// LatestBirthDay devuelve la fecha de nacimiento más reciente con la que en on se tienen al menos age años
+func LatestBirthDay(age int, on time.Time) time.Time {
+	on = on.UTC()
+	year := on.Year() - age
+	day := on.Day()
+	// Si on es 29 de febrero y el año destino no es bisiesto, el último día válido es el 28
+	if last := time.Date(year, on.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, on.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// NextBirthday devuelve el próximo cumpleaños, el mismo día on o después, de alguien nacido en birthDay.
+// Se usa la fecha de on en su zona horaria; como en AgeAt, quien nació un 29 de febrero lo celebra el
+// 1 de marzo en los años no bisiestos
+func NextBirthday(birthDay, on time.Time) time.Time {
+	oy, om, od := on.Date()
+	today := time.Date(oy, om, od, 0, 0, 0, 0, time.UTC)
+	_, bm, bd := birthDay.Date()
+	// time.Date pasa el 29 de febrero de un año no bisiesto al 1 de marzo
+	next := time.Date(oy, bm, bd, 0, 0, 0, 0, time.UTC)
+	if next.Before(today) {
+		next = time.Date(oy+1, bm, bd, 0, 0, 0, 0, time.UTC)
+	}
+	return next
+}
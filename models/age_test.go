@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAgeAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		birthDay time.Time
+		on       time.Time
+		expected int
+	}{
+		{"Day before birthday", date(1990, time.June, 15), date(2024, time.June, 14), 33},
+		{"On birthday", date(1990, time.June, 15), date(2024, time.June, 15), 34},
+		{"Earlier month", date(1990, time.December, 1), date(2024, time.June, 15), 33},
+		{"Leap day in leap year", date(2000, time.February, 29), date(2024, time.February, 29), 24},
+		{"Leap day on Feb 28 of common year", date(2000, time.February, 29), date(2023, time.February, 28), 22},
+		{"Leap day on Mar 1 of common year", date(2000, time.February, 29), date(2023, time.March, 1), 23},
+		{"Newborn", date(2024, time.June, 15), date(2024, time.June, 15), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, AgeAt(tt.birthDay, tt.on))
+		})
+	}
+}
+
+func TestLatestBirthDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		age      int
+		on       time.Time
+		expected time.Time
+	}{
+		{"Regular day", 30, date(2024, time.June, 15), date(1994, time.June, 15)},
+		{"Leap day to common year", 1, date(2024, time.February, 29), date(2023, time.February, 28)},
+		{"Leap day to leap year", 4, date(2024, time.February, 29), date(2020, time.February, 29)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound := LatestBirthDay(tt.age, tt.on)
+			assert.Equal(t, tt.expected, bound)
+			assert.Equal(t, tt.age, AgeAt(bound, tt.on))
+			assert.Equal(t, tt.age-1, AgeAt(bound.AddDate(0, 0, 1), tt.on))
+		})
+	}
+}
+
+func TestValidateClientAgeIsOptional(t *testing.T) {
+	defer func() { Now = time.Now }()
+	Now = func() time.Time { return date(2024, time.June, 15) }
+
+	client := Client{Name: "John", LastName: "Doe", Email: "john@example.com", BirthDay: date(1990, time.June, 16), Telephone: "600123456"}
+	assert.Empty(t, ValidateClient(&client))
+
+	client.Age = 33
+	assert.Empty(t, ValidateClient(&client))
+
+	client.Age = 34
+	assert.Equal(t, CodeAgeMismatch, ValidateClient(&client)[0].Code)
+}
+
+func TestNextBirthday(t *testing.T) {
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	tests := []struct {
+		name     string
+		birthDay time.Time
+		on       time.Time
+		expected time.Time
+	}{
+		{"Later this year", date(1990, time.June, 20), date(2024, time.June, 15), date(2024, time.June, 20)},
+		{"Today", date(1990, time.June, 15), date(2024, time.June, 15), date(2024, time.June, 15)},
+		{"Already passed wraps to next year", date(1990, time.January, 3), date(2024, time.December, 28), date(2025, time.January, 3)},
+		{"Leap day in a leap year", date(2000, time.February, 29), date(2024, time.February, 1), date(2024, time.February, 29)},
+		{"Leap day in a common year", date(2000, time.February, 29), date(2023, time.February, 1), date(2023, time.March, 1)},
+		{"Leap day after March 1 wraps to the next leap year date", date(2000, time.February, 29), date(2023, time.March, 2), date(2024, time.February, 29)},
+		{"Date of on in its time zone", date(1990, time.June, 16), time.Date(2024, time.June, 15, 23, 30, 0, 0, time.UTC).In(madrid), date(2024, time.June, 16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NextBirthday(tt.birthDay, tt.on))
+		})
+	}
+}
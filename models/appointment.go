@@ -0,0 +1,145 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Estados de un turno
+const (
+	AppointmentScheduled = "scheduled"
+	AppointmentCancelled = "cancelled"
+)
+
+// Cambios registrados en el historial de un turno
+const (
+	ActionReschedule = "reschedule"
+	ActionCancel     = "cancel"
+)
+
+// ClientDeletedReason es el motivo de los turnos que se cancelan al eliminar a su cliente
+const ClientDeletedReason = "Client deleted"
+
+// MaxServiceMinutes es la duración máxima de un servicio
+const MaxServiceMinutes = 12 * 60
+
+// ServiceType es un servicio que se reserva con un turno, como un baño o una consulta veterinaria, y cuánto dura
+type ServiceType struct {
+	ID              int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name            string    `json:"name" gorm:"not null;unique"`
+	DurationMinutes int       `json:"duration_minutes" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Appointment es un turno de un cliente, y opcionalmente de una de sus mascotas, para un servicio en una
+// tienda con un miembro del personal. EndsAt se calcula con la duración del servicio. Los turnos cancelados
+// se conservan con su historial de cambios y dejan libre el horario
+type Appointment struct {
+	ID            int                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID      int                 `json:"client_id" gorm:"not null;index"`
+	PetID         *int                `json:"pet_id" gorm:"index"`
+	ServiceTypeID int                 `json:"service_type_id" gorm:"not null"`
+	StoreID       int                 `json:"store_id" gorm:"not null;index"`
+	StaffID       int                 `json:"staff_id" gorm:"not null;index"`
+	StartsAt      time.Time           `json:"starts_at" gorm:"not null;index"`
+	EndsAt        time.Time           `json:"ends_at" gorm:"not null"`
+	Status        string              `json:"status" gorm:"not null;default:scheduled" enums:"scheduled,cancelled"`
+	Notes         string              `json:"notes"`
+	Changes       []AppointmentChange `json:"changes" gorm:"foreignKey:AppointmentID"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// AppointmentChange es una reprogramación o cancelación de un turno, con su motivo. En las
+// reprogramaciones guarda el horario y el miembro del personal anteriores
+type AppointmentChange struct {
+	ID               int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	AppointmentID    int        `json:"appointment_id" gorm:"not null;index"`
+	Action           string     `json:"action" gorm:"not null" enums:"reschedule,cancel"`
+	Reason           string     `json:"reason" gorm:"not null"`
+	PreviousStartsAt *time.Time `json:"previous_starts_at"`
+	PreviousStaffID  *int       `json:"previous_staff_id"`
+	Actor            string     `json:"actor"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func init() {
+	ClientReferences = append(ClientReferences, ClientReference{Table: "appointments", Column: "client_id"})
+}
+
+// BeforeSave guarda los horarios en UTC y al minuto, para que se comparen igual sin importar la zona
+// horaria con la que se enviaron
+func (a *Appointment) BeforeSave(tx *gorm.DB) error {
+	a.StartsAt = a.StartsAt.UTC().Truncate(time.Minute)
+	a.EndsAt = a.EndsAt.UTC().Truncate(time.Minute)
+	return nil
+}
+
+// Schedule fija el comienzo del turno y calcula su fin con la duración del servicio
+func (a *Appointment) Schedule(start time.Time, service ServiceType) {
+	a.StartsAt = start.UTC().Truncate(time.Minute)
+	a.EndsAt = a.StartsAt.Add(time.Duration(service.DurationMinutes) * time.Minute)
+}
+
+// AppointmentConflicts filtra los turnos vigentes que se superponen con a, sin contarlo, del mismo miembro
+// del personal o de la misma mascota. Los de clientes eliminados no cuentan, aunque se hayan eliminado antes
+// de que se cancelaran sus turnos al eliminarlos
+func AppointmentConflicts(db *gorm.DB, a *Appointment) *gorm.DB {
+	db = db.Model(&Appointment{}).
+		Where("status = ? AND id <> ? AND starts_at < ? AND ends_at > ?", AppointmentScheduled, a.ID, a.EndsAt, a.StartsAt).
+		Where("client_id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&Client{}).Select("id"))
+	if a.PetID != nil {
+		return db.Where("(staff_id = ? OR pet_id = ?)", a.StaffID, *a.PetID)
+	}
+	return db.Where("staff_id = ?", a.StaffID)
+}
+
+// CancelClientAppointments cancela dentro de tx los turnos vigentes del cliente que todavía no terminaron, con
+// un motivo del sistema, para que un cliente eliminado no retenga horarios. Restaurar al cliente no los reactiva
+func CancelClientAppointments(tx *gorm.DB, clientID int, actor string) error {
+	var ids []int
+	err := tx.Model(&Appointment{}).
+		Where("client_id = ? AND status = ? AND ends_at > ?", clientID, AppointmentScheduled, Now().UTC()).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	if err := tx.Model(&Appointment{}).Where("id IN ?", ids).Update("status", AppointmentCancelled).Error; err != nil {
+		return err
+	}
+	changes := make([]AppointmentChange, len(ids))
+	for i, id := range ids {
+		changes[i] = AppointmentChange{AppointmentID: id, Action: ActionCancel, Reason: ClientDeletedReason, Actor: actor}
+	}
+	return tx.Create(&changes).Error
+}
+
+// ValidateServiceType comprueba todas las reglas de un servicio y devuelve cada violación encontrada
+func ValidateServiceType(service *ServiceType) ValidationErrors {
+	var errs ValidationErrors
+	if strings.TrimSpace(service.Name) == "" {
+		errs.add("name", CodeRequired, "Name is required")
+	}
+	if service.DurationMinutes <= 0 || service.DurationMinutes > MaxServiceMinutes {
+		errs.add("duration_minutes", CodeOutOfRange, "Duration must be between 1 and 720 minutes")
+	}
+	return errs
+}
+
+// ValidateAppointmentTime comprueba que el turno empiece en el futuro y que la tienda esté abierta durante
+// todo el servicio
+func ValidateAppointmentTime(a *Appointment, store *Store) ValidationErrors {
+	var errs ValidationErrors
+	switch {
+	case a.StartsAt.IsZero():
+		errs.add("starts_at", CodeRequired, "Starts At is required")
+	case !a.StartsAt.After(Now()):
+		errs.add("starts_at", CodeOutOfRange, "Starts At must be in the future")
+	case !store.IsOpen(a.StartsAt, a.EndsAt):
+		errs.add("starts_at", CodeOutsideHours, "The store is not open for the whole appointment")
+	}
+	return errs
+}
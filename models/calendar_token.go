@@ -0,0 +1,34 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// CalendarToken da acceso sin contraseña a los calendarios de un usuario, para que las aplicaciones de
+// calendario puedan suscribirse. Solo se guarda el hash del token; cada usuario tiene a lo sumo uno
+type CalendarToken struct {
+	ID        int       `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID    int       `json:"user_id" gorm:"uniqueIndex;not null"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewCalendarToken genera un token aleatorio y devuelve el token y su hash
+func NewCalendarToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashCalendarToken(token), nil
+}
+
+// HashCalendarToken devuelve el hash con el que se guarda y se busca un token
+func HashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,13 +1,66 @@
 package models
 
-import "time"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Client es un cliente. EmailKey es la forma canónica del email con la que se evitan duplicados.
+// Telephone se guarda en formato E.164 y TelephoneDisplay conserva el número tal como se escribió.
+// CreatedAt y UpdatedAt los controla el servidor; son null en los clientes que no tienen historial
 type Client struct {
-	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string    `json:"name" gorm:"not null"`
-	LastName  string    `json:"last_name" gorm:"not null"`
-	Email     string    `json:"email" gorm:"unique;not null"`
-	BirthDay  time.Time `json:"birth_day" gorm:"not null"`
-	Age       int       `json:"age" gorm:"not null"`
-	Telephone string    `json:"telephone"`
+	ID               int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name             string         `json:"name" gorm:"not null"`
+	LastName         string         `json:"last_name" gorm:"not null"`
+	Email            string         `json:"email" gorm:"unique;not null"`
+	EmailKey         string         `json:"-" gorm:"uniqueIndex;not null;default:''"`
+	BirthDay         time.Time      `json:"birth_day" gorm:"not null"`
+	Age              int            `json:"age" gorm:"-"`
+	Telephone        string         `json:"telephone"`
+	TelephoneType    string         `json:"telephone_type"`
+	TelephoneDisplay string         `json:"telephone_display"`
+	Version          int            `json:"version" gorm:"not null;default:1"`
+	CreatedAt        *time.Time     `json:"created_at" gorm:"<-:create;index"`
+	UpdatedAt        *time.Time     `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
+}
+
+// CurrentAge calcula la edad del cliente a partir de su fecha de nacimiento
+func (c *Client) CurrentAge() int {
+	if c.BirthDay.IsZero() {
+		return 0
+	}
+	return AgeAt(c.BirthDay, Now())
+}
+
+// BeforeCreate inicia la versión del cliente, que se incrementa en cada modificación, y deja que
+// GORM complete las fechas de alta y modificación
+func (c *Client) BeforeCreate(tx *gorm.DB) error {
+	c.Version = 1
+	c.CreatedAt, c.UpdatedAt = nil, nil
+	return nil
+}
+
+// BeforeSave normaliza el email y el teléfono y calcula la clave de unicidad del email.
+// Los valores inválidos se rechazan al validar, aquí se dejan tal como llegan
+func (c *Client) BeforeSave(tx *gorm.DB) error {
+	if email, err := NormalizeEmail(c.Email); err == nil {
+		c.Email = email
+	}
+	c.EmailKey = EmailKey(c.Email)
+	c.NormalizeTelephone(DefaultPhoneRegion)
+	return nil
+}
+
+// AfterFind completa la edad, que no se almacena sino que se deriva de birth_day
+func (c *Client) AfterFind(tx *gorm.DB) error {
+	c.Age = c.CurrentAge()
+	return nil
+}
+
+// AfterSave completa la edad del cliente recién guardado
+func (c *Client) AfterSave(tx *gorm.DB) error {
+	c.Age = c.CurrentAge()
+	return nil
 }
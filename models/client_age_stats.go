@@ -0,0 +1,245 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// clientAgeStatsID es el id de la única fila de ClientAgeStats
+const clientAgeStatsID = 1
+
+// ClientAgeStats son los agregados de las edades de los clientes activos, que se actualizan con cada
+// cambio de un cliente para que el KPI no tenga que recorrer la tabla. AsOf es el día (YYYY-MM-DD) al
+// que corresponden las edades; al cambiar de día se pasan a la edad nueva los clientes que cumplen años
+type ClientAgeStats struct {
+	ID      int    `gorm:"primaryKey;autoIncrement:false"`
+	AsOf    string `gorm:"not null"`
+	Welford `gorm:"embedded"`
+}
+
+// ClientAgeCount es la cantidad de clientes activos con cada edad. Junto con ClientAgeStats permite
+// calcular la mediana, los percentiles y el histograma sin recorrer la tabla de clientes
+type ClientAgeCount struct {
+	Age   int   `gorm:"primaryKey;autoIncrement:false"`
+	Count int64 `gorm:"not null"`
+}
+
+// ApplyClientAgeChange actualiza dentro de tx los agregados de edades con el cambio de un cliente de
+// before a after. before es nil al crear y after es nil al eliminar definitivamente; un cliente
+// eliminado lógicamente no cuenta. tx ya debe haber guardado el cambio
+func ApplyClientAgeChange(tx *gorm.DB, before, after *Client) error {
+	var stats ClientAgeStats
+	err := tx.First(&stats, clientAgeStatsID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Sin agregados se calculan desde cero, ya con el cambio incluido
+		return RebuildClientAgeStats(tx)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Los agregados corresponden a AsOf: el cambio se aplica con las edades de ese día y después,
+	// si cambió el día, se pasan todos los clientes, incluido este, a las edades de hoy
+	on, err := time.Parse("2006-01-02", stats.AsOf)
+	if err != nil {
+		return err
+	}
+	removed, hadAge := activeAge(before, on)
+	added, hasAge := activeAge(after, on)
+	if hadAge != hasAge || removed != added {
+		if hadAge {
+			stats.Remove(float64(removed), 1)
+			if err := addClientAgeCount(tx, removed, -1); err != nil {
+				return err
+			}
+		}
+		if hasAge {
+			stats.Add(float64(added), 1)
+			if err := addClientAgeCount(tx, added, 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	today := Now().UTC().Format("2006-01-02")
+	if stats.AsOf != today {
+		if err := rollOverClientAges(tx, &stats, today); err != nil {
+			return err
+		}
+	}
+	return tx.Save(&stats).Error
+}
+
+// LoadClientAgeStats devuelve los agregados y la distribución de edades de los clientes activos al día de
+// hoy. Si cambió el día desde la última actualización, primero pasa a su nueva edad a quienes cumplieron años
+func LoadClientAgeStats(db *gorm.DB) (ClientAgeStats, Distribution, error) {
+	var stats ClientAgeStats
+	var ages Distribution
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&stats, clientAgeStatsID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := RebuildClientAgeStats(tx); err != nil {
+				return err
+			}
+			err = tx.First(&stats, clientAgeStatsID).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if today := Now().UTC().Format("2006-01-02"); stats.AsOf != today {
+			// Si otra petición ya actualizó el día, se usan sus agregados
+			res := tx.Model(&ClientAgeStats{}).Where("id = ? AND as_of = ?", clientAgeStatsID, stats.AsOf).Update("as_of", today)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return tx.First(&stats, clientAgeStatsID).Error
+			}
+			if err := rollOverClientAges(tx, &stats, today); err != nil {
+				return err
+			}
+			if err := tx.Save(&stats).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, nil, err
+	}
+	err = db.Model(&ClientAgeCount{}).Select("age AS value, count").Where("count > 0").Order("age").Scan(&ages).Error
+	return stats, ages, err
+}
+
+// RebuildClientAgeStats recalcula desde cero los agregados de edades a partir de la tabla de clientes
+func RebuildClientAgeStats(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		today := Now().UTC().Format("2006-01-02")
+		ages, err := ClientAgeDistribution(tx, today)
+		if err != nil {
+			return err
+		}
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&ClientAgeCount{}).Error; err != nil {
+			return err
+		}
+		if len(ages) > 0 {
+			counts := make([]ClientAgeCount, len(ages))
+			for i, vc := range ages {
+				counts[i] = ClientAgeCount{Age: int(vc.Value), Count: vc.Count}
+			}
+			if err := tx.Create(&counts).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&ClientAgeStats{ID: clientAgeStatsID, AsOf: today, Welford: ages.Welford()}).Error
+	})
+}
+
+// VerifyClientAgeStats compara los agregados de edades con los que resultan de la tabla de clientes y
+// devuelve las diferencias encontradas; ninguna si están al día
+func VerifyClientAgeStats(db *gorm.DB) ([]string, error) {
+	stats, stored, err := LoadClientAgeStats(db)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := ClientAgeDistribution(db, stats.AsOf)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	expected := actual.Welford()
+	if stats.Count != expected.Count {
+		diffs = append(diffs, fmt.Sprintf("count is %d, expected %d", stats.Count, expected.Count))
+	}
+	if !closeEnough(stats.Mean, expected.Mean) {
+		diffs = append(diffs, fmt.Sprintf("mean is %v, expected %v", stats.Mean, expected.Mean))
+	}
+	if !closeEnough(stats.M2, expected.M2) {
+		diffs = append(diffs, fmt.Sprintf("m2 is %v, expected %v", stats.M2, expected.M2))
+	}
+
+	counts := map[float64]int64{}
+	for _, vc := range stored {
+		counts[vc.Value] = vc.Count
+	}
+	for _, vc := range actual {
+		if counts[vc.Value] != vc.Count {
+			diffs = append(diffs, fmt.Sprintf("age %v has %d clients, expected %d", vc.Value, counts[vc.Value], vc.Count))
+		}
+		delete(counts, vc.Value)
+	}
+	for _, vc := range stored {
+		if _, ok := counts[vc.Value]; ok {
+			diffs = append(diffs, fmt.Sprintf("age %v has %d clients, expected 0", vc.Value, vc.Count))
+		}
+	}
+	return diffs, nil
+}
+
+// rollOverClientAges pasa los agregados de las edades del día stats.AsOf a las de today, moviendo a los
+// clientes que cumplieron años entre ambos días. Recorre la tabla una vez, agrupando en SQL
+func rollOverClientAges(tx *gorm.DB, stats *ClientAgeStats, today string) error {
+	var rows []struct {
+		Previous int
+		Current  int
+		Count    int64
+	}
+	err := tx.Model(&Client{}).
+		Select(ClientAgeSQL+" AS previous, "+ClientAgeSQL+" AS current, COUNT(*) AS count", stats.AsOf, stats.AsOf, today, today).
+		Group("previous, current").
+		Having("previous <> current").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		stats.Remove(float64(row.Previous), row.Count)
+		stats.Add(float64(row.Current), row.Count)
+		if err := addClientAgeCount(tx, row.Previous, -row.Count); err != nil {
+			return err
+		}
+		if err := addClientAgeCount(tx, row.Current, row.Count); err != nil {
+			return err
+		}
+	}
+	stats.AsOf = today
+	return nil
+}
+
+// ClientAgeDistribution cuenta en SQL los clientes activos de cada edad en el día on (YYYY-MM-DD)
+func ClientAgeDistribution(db *gorm.DB, on string) (Distribution, error) {
+	ages := Distribution{}
+	err := db.Model(&Client{}).
+		Select(ClientAgeSQL+" AS value, COUNT(*) AS count", on, on).
+		Group("value").Order("value").
+		Scan(&ages).Error
+	return ages, err
+}
+
+// addClientAgeCount suma delta a la cantidad de clientes con la edad age
+func addClientAgeCount(tx *gorm.DB, age int, delta int64) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "age"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("client_age_counts.count + ?", delta)}),
+	}).Create(&ClientAgeCount{Age: age, Count: delta}).Error
+}
+
+// activeAge devuelve la edad en on de un cliente activo; false si no hay cliente o está eliminado
+func activeAge(c *Client, on time.Time) (int, bool) {
+	if c == nil || c.DeletedAt.Valid {
+		return 0, false
+	}
+	return AgeAt(c.BirthDay, on), true
+}
+
+// closeEnough compara dos valores calculados por caminos distintos, tolerando errores de redondeo
+func closeEnough(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
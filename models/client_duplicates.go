@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Pesos de cada criterio en la puntuación de duplicados
+const (
+	duplicateNameWeight  = 0.5
+	duplicatePhoneWeight = 0.3
+	duplicateEmailWeight = 0.2
+)
+
+// DuplicateScore es la probabilidad de que dos clientes sean la misma persona, en total y por criterio
+type DuplicateScore struct {
+	Score float64 `json:"score"`
+	Name  float64 `json:"name"`
+	Phone float64 `json:"phone"`
+	Email float64 `json:"email"`
+}
+
+// ScoreDuplicate compara dos clientes por nombre completo, teléfono normalizado y parte local del email
+func ScoreDuplicate(a, b *Client) DuplicateScore {
+	var s DuplicateScore
+	s.Name = JaroWinkler(DuplicateName(a), DuplicateName(b))
+	if pa, pb := DuplicatePhone(a.Telephone), DuplicatePhone(b.Telephone); pa != "" && pa == pb {
+		s.Phone = 1
+	}
+	s.Email = JaroWinkler(EmailLocalPart(a.Email), EmailLocalPart(b.Email))
+	s.Score = duplicateNameWeight*s.Name + duplicatePhoneWeight*s.Phone + duplicateEmailWeight*s.Email
+	return s
+}
+
+// DuplicateName es el nombre completo sin tildes ni mayúsculas
+func DuplicateName(c *Client) string {
+	return foldText(c.Name + " " + c.LastName)
+}
+
+// DuplicatePhone deja solo los dígitos del teléfono y se queda con los 9 últimos,
+// para que el mismo número con y sin prefijo internacional coincida
+func DuplicatePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) > 9 {
+		digits = digits[len(digits)-9:]
+	}
+	return digits
+}
+
+// EmailLocalPart devuelve la parte local del email en minúsculas, sin la etiqueta +tag ni puntos
+func EmailLocalPart(email string) string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	local, _, _ = strings.Cut(local, "+")
+	return strings.ReplaceAll(local, ".", "")
+}
+
+// DuplicateBlockingKeys devuelve las claves que agrupan a los clientes que vale la pena comparar:
+// mismo teléfono, mismo inicio de la parte local del email o mismo inicio del apellido
+func DuplicateBlockingKeys(c *Client) []string {
+	var keys []string
+	if phone := DuplicatePhone(c.Telephone); len(phone) >= 7 {
+		keys = append(keys, "phone:"+phone)
+	}
+	if local := []rune(EmailLocalPart(c.Email)); len(local) > 0 {
+		keys = append(keys, "email:"+string(local[:min(4, len(local))]))
+	}
+	if last := []rune(strings.ReplaceAll(foldText(c.LastName), " ", "")); len(last) > 0 {
+		keys = append(keys, "last_name:"+string(last[:min(3, len(last))]))
+	}
+	return keys
+}
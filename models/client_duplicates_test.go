@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.813},
+		{"same", "same", 1},
+		{"abc", "xyz", 0},
+		{"", "", 1},
+		{"abc", "", 0},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.expected, JaroWinkler(tt.a, tt.b), 0.001, "%s / %s", tt.a, tt.b)
+	}
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	john := &Client{Name: "José", LastName: "García", Email: "jose.garcia@example.com", Telephone: "+34 600 123 456"}
+	typo := &Client{Name: "Jose", LastName: "Garcia", Email: "josegarcia+shop@exmaple.com", Telephone: "600123456"}
+	other := &Client{Name: "Ana", LastName: "López", Email: "ana@example.com", Telephone: "911234567"}
+
+	score := ScoreDuplicate(john, typo)
+	assert.Equal(t, 1.0, score.Name, "Las tildes y mayúsculas no cuentan")
+	assert.Equal(t, 1.0, score.Phone, "El prefijo internacional y los espacios no cuentan")
+	assert.Equal(t, 1.0, score.Email, "Los puntos y la etiqueta +tag no cuentan")
+	assert.InDelta(t, 1.0, score.Score, 0.0001)
+
+	assert.Less(t, ScoreDuplicate(john, other).Score, 0.5)
+}
+
+func TestDuplicateBlockingKeys(t *testing.T) {
+	client := &Client{LastName: "Ñúñez de Balboa", Email: "Jo.S+x@example.com", Telephone: "12"}
+	assert.Equal(t, []string{"email:jos", "last_name:nun"}, DuplicateBlockingKeys(client))
+}
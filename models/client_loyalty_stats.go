@@ -0,0 +1,205 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// clientLoyaltyStatsID es el id de la única fila de ClientLoyaltyStats
+const clientLoyaltyStatsID = 1
+
+// ClientLoyaltyStats son la cantidad y el total de los pedidos y la suma del libro de puntos de los clientes
+// activos. Se actualizan al registrar pedidos y movimientos de puntos y al eliminar o restaurar clientes, para
+// que el KPI no tenga que recorrer la tabla. NextExpiry es el vencimiento más próximo de las acumulaciones y
+// traspasos que todavía no se cerraron, de cualquier cliente; null si no hay ninguno
+type ClientLoyaltyStats struct {
+	ID         int   `gorm:"primaryKey;autoIncrement:false"`
+	Orders     int64 `gorm:"not null"`
+	SpendCents int64 `gorm:"not null"`
+	Points     int64 `gorm:"not null"`
+	NextExpiry *time.Time
+}
+
+// AfterCreate suma el pedido a los agregados si su cliente está activo
+func (o *Order) AfterCreate(tx *gorm.DB) error {
+	return addClientLoyaltyStats(tx, o.ClientID, ClientLoyaltyStats{Orders: 1, SpendCents: o.TotalCents})
+}
+
+// AfterCreate suma el movimiento a los agregados si su cliente está activo y adelanta el próximo vencimiento
+// si los puntos que suma vencen antes. Los vencimientos que ya estaban registrados no se insertan y no cuentan
+func (e *LoyaltyEntry) AfterCreate(tx *gorm.DB) error {
+	if tx.Statement.RowsAffected == 0 {
+		return nil
+	}
+	if e.Points > 0 && e.ExpiresAt != nil {
+		err := tx.Session(&gorm.Session{NewDB: true}).Model(&ClientLoyaltyStats{}).
+			Where("id = ? AND (next_expiry IS NULL OR next_expiry > ?)", clientLoyaltyStatsID, *e.ExpiresAt).
+			UpdateColumn("next_expiry", *e.ExpiresAt).Error
+		if err != nil {
+			return err
+		}
+	}
+	return addClientLoyaltyStats(tx, e.ClientID, ClientLoyaltyStats{Points: e.Points})
+}
+
+// ApplyClientLoyaltyChange actualiza dentro de tx los agregados de compras y puntos con el cambio de un cliente
+// de before a after: al eliminarlo se restan sus pedidos y puntos y al restaurarlo se vuelven a sumar. Un
+// cliente recién creado no tiene pedidos ni puntos
+func ApplyClientLoyaltyChange(tx *gorm.DB, before, after *Client) error {
+	if before == nil {
+		return nil
+	}
+	wasActive := !before.DeletedAt.Valid
+	isActive := after != nil && !after.DeletedAt.Valid
+	if wasActive == isActive {
+		return nil
+	}
+	totals, err := clientLoyaltyTotals(tx, []int{before.ID})
+	if err != nil {
+		return err
+	}
+	if wasActive {
+		totals = ClientLoyaltyStats{Orders: -totals.Orders, SpendCents: -totals.SpendCents, Points: -totals.Points}
+	}
+	return addClientLoyaltyStats(tx, 0, totals)
+}
+
+// LoadClientLoyaltyStats devuelve los agregados de compras y puntos, calculándolos desde cero si no existen.
+// Si ya llegó el próximo vencimiento, primero registra los vencimientos pendientes para no contar puntos vencidos
+func LoadClientLoyaltyStats(db *gorm.DB) (ClientLoyaltyStats, error) {
+	var stats ClientLoyaltyStats
+	err := db.First(&stats, clientLoyaltyStatsID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := RebuildClientLoyaltyStats(db); err != nil {
+			return stats, err
+		}
+		err = db.First(&stats, clientLoyaltyStatsID).Error
+	}
+	if err != nil || !loyaltyExpiryDue(stats) {
+		return stats, err
+	}
+	if _, err := ExpireLoyaltyPoints(db, 0); err != nil {
+		return stats, err
+	}
+	err = db.First(&stats, clientLoyaltyStatsID).Error
+	return stats, err
+}
+
+// ExpireDueLoyaltyPoints registra los vencimientos pendientes solo si ya llegó el próximo vencimiento, para
+// leer el libro de puntos sin puntos vencidos sin recorrerlo cada vez
+func ExpireDueLoyaltyPoints(db *gorm.DB) error {
+	_, err := LoadClientLoyaltyStats(db)
+	return err
+}
+
+// RebuildClientLoyaltyStats recalcula desde cero los agregados de compras y puntos de los clientes activos y el
+// próximo vencimiento
+func RebuildClientLoyaltyStats(db *gorm.DB) error {
+	stats, err := clientLoyaltyTotals(db, db.Model(&Client{}).Select("id"))
+	if err != nil {
+		return err
+	}
+	if stats.NextExpiry, err = nextLoyaltyExpiry(db); err != nil {
+		return err
+	}
+	stats.ID = clientLoyaltyStatsID
+	return db.Save(&stats).Error
+}
+
+// VerifyClientLoyaltyStats compara los agregados de compras y puntos con los que resultan de los pedidos y el
+// libro de puntos y devuelve las diferencias encontradas; ninguna si están al día
+func VerifyClientLoyaltyStats(db *gorm.DB) ([]string, error) {
+	stats, err := LoadClientLoyaltyStats(db)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := clientLoyaltyTotals(db, db.Model(&Client{}).Select("id"))
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []string
+	if stats.Orders != actual.Orders {
+		diffs = append(diffs, fmt.Sprintf("orders is %d, expected %d", stats.Orders, actual.Orders))
+	}
+	if stats.SpendCents != actual.SpendCents {
+		diffs = append(diffs, fmt.Sprintf("spend is %d cents, expected %d", stats.SpendCents, actual.SpendCents))
+	}
+	if stats.Points != actual.Points {
+		diffs = append(diffs, fmt.Sprintf("points is %d, expected %d", stats.Points, actual.Points))
+	}
+	// Un próximo vencimiento anterior al real solo hace que se revise el libro antes de tiempo
+	next, err := nextLoyaltyExpiry(db)
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && (stats.NextExpiry == nil || stats.NextExpiry.After(*next)) {
+		diffs = append(diffs, fmt.Sprintf("next expiry is %s, expected %s", formatExpiry(stats.NextExpiry), formatExpiry(next)))
+	}
+	return diffs, nil
+}
+
+// loyaltyExpiryDue indica si ya llegó el próximo vencimiento de los agregados
+func loyaltyExpiryDue(stats ClientLoyaltyStats) bool {
+	return stats.NextExpiry != nil && !stats.NextExpiry.After(Now())
+}
+
+// nextLoyaltyExpiry devuelve el vencimiento más próximo de las acumulaciones y traspasos que todavía no se
+// cerraron, o nil si no hay ninguno
+func nextLoyaltyExpiry(db *gorm.DB) (*time.Time, error) {
+	var next []LoyaltyEntry
+	err := openLoyaltyLots(db).Where("expires_at IS NOT NULL").Order("expires_at").Limit(1).Find(&next).Error
+	if err != nil || len(next) == 0 {
+		return nil, err
+	}
+	return next[0].ExpiresAt, nil
+}
+
+// updateLoyaltyNextExpiry recalcula el próximo vencimiento después de registrar los vencimientos pendientes
+func updateLoyaltyNextExpiry(db *gorm.DB) error {
+	next, err := nextLoyaltyExpiry(db)
+	if err != nil {
+		return err
+	}
+	return db.Model(&ClientLoyaltyStats{}).Where("id = ?", clientLoyaltyStatsID).UpdateColumn("next_expiry", next).Error
+}
+
+func formatExpiry(t *time.Time) string {
+	if t == nil {
+		return "none"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// clientLoyaltyTotals suma en SQL los pedidos y el libro de puntos de los clientes clientIDs, que puede ser
+// una subconsulta
+func clientLoyaltyTotals(db *gorm.DB, clientIDs interface{}) (ClientLoyaltyStats, error) {
+	var totals ClientLoyaltyStats
+	err := db.Model(&Order{}).Where("client_id IN (?)", clientIDs).
+		Select("COUNT(*) AS orders, COALESCE(SUM(total_cents), 0) AS spend_cents").
+		Scan(&totals).Error
+	if err != nil {
+		return totals, err
+	}
+	err = db.Model(&LoyaltyEntry{}).Where("client_id IN (?)", clientIDs).
+		Select("COALESCE(SUM(points), 0)").
+		Scan(&totals.Points).Error
+	return totals, err
+}
+
+// addClientLoyaltyStats suma delta a los agregados; si clientID no es 0, solo si ese cliente está activo. Si los
+// agregados todavía no existen no hace nada: se calcularán desde cero, ya con el cambio, al leerlos
+func addClientLoyaltyStats(tx *gorm.DB, clientID int, delta ClientLoyaltyStats) error {
+	row := tx.Session(&gorm.Session{NewDB: true}).Model(&ClientLoyaltyStats{}).Where("id = ?", clientLoyaltyStatsID)
+	if clientID != 0 {
+		row = row.Where("EXISTS (?)", tx.Session(&gorm.Session{NewDB: true}).Model(&Client{}).Select("1").Where("id = ?", clientID))
+	}
+	return row.UpdateColumns(map[string]interface{}{
+		"orders":      gorm.Expr("orders + ?", delta.Orders),
+		"spend_cents": gorm.Expr("spend_cents + ?", delta.SpendCents),
+		"points":      gorm.Expr("points + ?", delta.Points),
+	}).Error
+}
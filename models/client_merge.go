@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ActionMerge es la acción registrada en el historial del cliente que absorbe a otros
+const ActionMerge = "merge"
+
+// MergeableClientFields son los campos que se pueden elegir de cualquiera de los clientes fusionados
+var MergeableClientFields = []string{"name", "last_name", "email", "birth_day", "telephone"}
+
+// ClientReference es una columna de otra tabla que apunta a un cliente
+type ClientReference struct {
+	Table  string
+	Column string
+}
+
+// ClientReferences son las columnas que se reasignan al cliente que sobrevive a una fusión.
+// Cada modelo que se relacione con clientes debe agregar aquí su columna, salvo el libro de puntos, que solo
+// admite altas y se traspasa con TransferLoyaltyPoints
+var ClientReferences []ClientReference
+
+// ClientMerge es el registro de auditoría de una fusión de clientes
+type ClientMerge struct {
+	ID         int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	SurvivorID int            `json:"survivor_id" gorm:"not null;index"`
+	MergedIDs  []int          `json:"merged_ids" gorm:"serializer:json"`
+	Choices    map[string]int `json:"choices" gorm:"serializer:json"`
+	Merged     []Client       `json:"merged" gorm:"serializer:json"`
+	Result     Client         `json:"result" gorm:"serializer:json"`
+	Actor      string         `json:"actor"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// SetClientField copia el campo field de src en dst; devuelve false si el campo no se puede fusionar
+func SetClientField(dst, src *Client, field string) bool {
+	switch field {
+	case "name":
+		dst.Name = src.Name
+	case "last_name":
+		dst.LastName = src.LastName
+	case "email":
+		dst.Email = src.Email
+	case "birth_day":
+		dst.BirthDay = src.BirthDay
+	case "telephone":
+		dst.Telephone, dst.TelephoneType, dst.TelephoneDisplay = src.Telephone, src.TelephoneType, src.TelephoneDisplay
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package models
+
+import "strings"
+
+// Códigos de error de validación
+const (
+	CodeRequired      = "required"
+	CodeInvalidFormat = "invalid_format"
+	CodeAgeMismatch   = "age_mismatch"
+	CodeFutureDate    = "future_date"
+	CodeDeleted       = "deleted"
+	CodeDisposable    = "disposable"
+	CodeNoMailServer  = "no_mail_server"
+	CodeInvalidChoice = "invalid_choice"
+	CodeChecksum      = "invalid_checksum"
+	CodeOutOfRange    = "out_of_range"
+	CodeNotFound      = "not_found"
+	CodeOutsideHours  = "outside_opening_hours"
+)
+
+// FieldError describe una regla incumplida por un campo
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors es la lista de todos los errores de validación de un registro
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, e := range v {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (v *ValidationErrors) add(field, code, message string) {
+	*v = append(*v, FieldError{Field: field, Code: code, Message: message})
+}
+
+// ValidateClient comprueba todas las reglas de un cliente y devuelve cada violación encontrada.
+// Si el email y el teléfono son válidos los deja normalizados.
+// Lo usan el alta, la modificación, el patch y la importación de clientes
+func ValidateClient(client *Client) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(client.Name) == "" {
+		errs.add("name", CodeRequired, "Name is required")
+	}
+	if strings.TrimSpace(client.LastName) == "" {
+		errs.add("last_name", CodeRequired, "Last Name is required")
+	}
+
+	if strings.TrimSpace(client.Email) == "" {
+		errs.add("email", CodeRequired, "Email is required")
+	} else if email, err := NormalizeEmail(client.Email); err != nil {
+		errs.add("email", CodeInvalidFormat, "Invalid email format")
+	} else {
+		client.Email, client.EmailKey = email, EmailKey(email)
+		if IsDisposableEmail(email) {
+			errs.add("email", CodeDisposable, "Disposable email addresses are not allowed")
+		} else if CheckEmailMX(email) != nil {
+			errs.add("email", CodeNoMailServer, "Email domain does not accept mail")
+		}
+	}
+
+	if strings.TrimSpace(client.Telephone) == "" {
+		errs.add("telephone", CodeRequired, "Telephone is required")
+	} else if err := client.NormalizeTelephone(DefaultPhoneRegion); err != nil {
+		errs.add("telephone", CodeInvalidFormat, "Invalid phone number")
+	}
+
+	now := Now()
+	switch {
+	case client.BirthDay.IsZero():
+		errs.add("birth_day", CodeRequired, "Birth Day is required")
+	case client.BirthDay.After(now):
+		errs.add("birth_day", CodeFutureDate, "Birth Day cannot be in the future")
+	}
+
+	// La edad no se almacena; si se informa solo se comprueba que coincida con birth_day
+	if client.Age != 0 && !client.BirthDay.IsZero() && client.Age != AgeAt(client.BirthDay, now) {
+		errs.add("age", CodeAgeMismatch, "Age does not match birth date")
+	}
+
+	return errs
+}
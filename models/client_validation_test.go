@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateClientReportsAllErrors(t *testing.T) {
+	client := Client{
+		Email:     "not-an-email",
+		BirthDay:  time.Now().AddDate(1, 0, 0),
+		Age:       30,
+		Telephone: "12ab",
+	}
+
+	errs := ValidateClient(&client)
+
+	assert.Equal(t, ValidationErrors{
+		{Field: "name", Code: CodeRequired, Message: "Name is required"},
+		{Field: "last_name", Code: CodeRequired, Message: "Last Name is required"},
+		{Field: "email", Code: CodeInvalidFormat, Message: "Invalid email format"},
+		{Field: "telephone", Code: CodeInvalidFormat, Message: "Invalid phone number"},
+		{Field: "birth_day", Code: CodeFutureDate, Message: "Birth Day cannot be in the future"},
+		{Field: "age", Code: CodeAgeMismatch, Message: "Age does not match birth date"},
+	}, errs)
+}
+
+func TestValidateClientValid(t *testing.T) {
+	client := Client{
+		Name:      "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+		BirthDay:  time.Now().AddDate(-30, 0, -1),
+		Age:       30,
+		Telephone: "+34 600 123 456",
+	}
+
+	assert.Empty(t, ValidateClient(&client))
+	assert.Equal(t, "+34600123456", client.Telephone)
+	assert.Equal(t, PhoneMobile, client.TelephoneType)
+	assert.Equal(t, "+34 600 123 456", client.TelephoneDisplay)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Acciones registradas en el historial de clientes
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRevert  = "revert"
+)
+
+// ErrImmutableVersion se devuelve al intentar modificar o borrar una versión del historial
+var ErrImmutableVersion = errors.New("client versions are immutable")
+
+// FieldChange es el valor anterior y el nuevo de un campo modificado
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ClientVersion es una versión inmutable de un cliente, registrada en cada cambio
+type ClientVersion struct {
+	ID        int                    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID  int                    `json:"client_id" gorm:"not null;uniqueIndex:idx_client_versions_client_version"`
+	Version   int                    `json:"version" gorm:"not null;uniqueIndex:idx_client_versions_client_version"`
+	Action    string                 `json:"action" gorm:"not null"`
+	Actor     string                 `json:"actor"`
+	Changes   map[string]FieldChange `json:"changes" gorm:"serializer:json"`
+	Client    Client                 `json:"client" gorm:"serializer:json"`
+	CreatedAt time.Time              `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// BeforeUpdate impide modificar versiones ya registradas
+func (v *ClientVersion) BeforeUpdate(tx *gorm.DB) error {
+	return ErrImmutableVersion
+}
+
+// BeforeDelete impide borrar versiones ya registradas
+func (v *ClientVersion) BeforeDelete(tx *gorm.DB) error {
+	return ErrImmutableVersion
+}
+
+// PurgeClientVersions borra el historial de los clientes que se eliminan definitivamente, dados por clientIDs,
+// que puede ser una subconsulta, para que no queden copias de sus datos personales. Es la única baja que admite
+// el historial, así que se salta BeforeDelete
+func PurgeClientVersions(tx *gorm.DB, clientIDs interface{}) error {
+	return tx.Session(&gorm.Session{SkipHooks: true}).Where("client_id IN (?)", clientIDs).Delete(&ClientVersion{}).Error
+}
+
+// DiffClients compara dos estados de un cliente campo a campo; before nil indica un alta.
+// Se omiten el id, la versión y la edad, que se deriva de birth_day
+func DiffClients(before, after *Client) map[string]FieldChange {
+	from, to := clientFields(before), clientFields(after)
+	changes := map[string]FieldChange{}
+	for field, value := range to {
+		if old, ok := from[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = FieldChange{From: from[field], To: value}
+		}
+	}
+	for field, old := range from {
+		if _, ok := to[field]; !ok {
+			changes[field] = FieldChange{From: old}
+		}
+	}
+	return changes
+}
+
+func clientFields(client *Client) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if client == nil {
+		return fields
+	}
+	raw, _ := json.Marshal(client)
+	json.Unmarshal(raw, &fields)
+	delete(fields, "id")
+	delete(fields, "age")
+	delete(fields, "version")
+	delete(fields, "created_at")
+	delete(fields, "updated_at")
+	return fields
+}
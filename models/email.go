@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Errores de normalización y verificación de emails
+var (
+	ErrInvalidEmail    = errors.New("invalid email")
+	ErrDisposableEmail = errors.New("disposable email domain")
+	ErrNoMailServer    = errors.New("email domain does not accept mail")
+)
+
+// MXResolver consulta los servidores de correo de un dominio. *net.Resolver la implementa;
+// los tests pueden usar una implementación falsa
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// EmailResolver es el resolver con el que se comprueba que el dominio reciba correo; nil no lo comprueba
+var EmailResolver MXResolver
+
+// MXLookupTimeout es el tiempo máximo de la consulta MX de un email
+var MXLookupTimeout = 3 * time.Second
+
+// StripEmailPlusTag hace que la clave de unicidad ignore el +etiqueta de la parte local (ana+promo@x.com = ana@x.com)
+var StripEmailPlusTag = false
+
+// DisposableEmailDomains son los dominios de correo temporal rechazados. Incluye sus subdominios
+var DisposableEmailDomains = map[string]bool{
+	"10minutemail.com":  true,
+	"discard.email":     true,
+	"dispostable.com":   true,
+	"getnada.com":       true,
+	"guerrillamail.com": true,
+	"mailinator.com":    true,
+	"maildrop.cc":       true,
+	"sharklasers.com":   true,
+	"temp-mail.org":     true,
+	"throwawaymail.com": true,
+	"trashmail.com":     true,
+	"yopmail.com":       true,
+}
+
+// idnaProfile valida dominios internacionalizados (IDNA 2008) y sus longitudes DNS
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true), idna.Transitional(false))
+
+// Caracteres permitidos en la parte local además de letras y dígitos (RFC 5322 atext)
+const emailLocalSymbols = "!#$%&'*+-/=?^_`{|}~"
+
+// NormalizeEmail valida un email, admitiendo direcciones internacionalizadas (RFC 6531), y devuelve
+// la parte local tal como se escribió con el dominio en minúsculas y en Unicode
+func NormalizeEmail(raw string) (string, error) {
+	local, domain, err := splitEmail(raw)
+	if err != nil {
+		return "", err
+	}
+	unicodeDomain, err := idnaProfile.ToUnicode(domain)
+	if err != nil {
+		return "", ErrInvalidEmail
+	}
+	return local + "@" + unicodeDomain, nil
+}
+
+// EmailKey devuelve la clave con la que se comprueba que un email no esté repetido: la parte local
+// en minúsculas (sin +etiqueta si StripEmailPlusTag) y el dominio en ASCII (punycode)
+func EmailKey(email string) string {
+	local, domain, err := splitEmail(email)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(email))
+	}
+	local = strings.ToLower(local)
+	if StripEmailPlusTag {
+		if i := strings.IndexByte(local, '+'); i > 0 {
+			local = local[:i]
+		}
+	}
+	return local + "@" + domain
+}
+
+// EmailDomain devuelve el dominio de un email en ASCII, o "" si el email no es válido
+func EmailDomain(email string) string {
+	_, domain, err := splitEmail(email)
+	if err != nil {
+		return ""
+	}
+	return domain
+}
+
+// IsDisposableEmail indica si el dominio del email, o alguno de sus dominios padre, está en DisposableEmailDomains
+func IsDisposableEmail(email string) bool {
+	domain := EmailDomain(email)
+	for domain != "" {
+		if DisposableEmailDomains[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// CheckEmailMX comprueba con EmailResolver que el dominio del email tenga servidores de correo.
+// Solo se rechaza un dominio inexistente, sin MX o con MX nulo (RFC 7505); si el DNS falla se acepta
+func CheckEmailMX(email string) error {
+	if EmailResolver == nil {
+		return nil
+	}
+	domain := EmailDomain(email)
+	if domain == "" {
+		return ErrInvalidEmail
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), MXLookupTimeout)
+	defer cancel()
+
+	records, err := EmailResolver.LookupMX(ctx, domain)
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return ErrNoMailServer
+	case err != nil:
+		return nil
+	case len(records) == 0, len(records) == 1 && records[0].Host == ".":
+		return ErrNoMailServer
+	}
+	return nil
+}
+
+// splitEmail separa la parte local del dominio, validando ambas; el dominio se devuelve en ASCII y minúsculas
+func splitEmail(raw string) (string, string, error) {
+	email := strings.TrimSpace(raw)
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 || len(email) > 254 || !utf8.ValidString(email) {
+		return "", "", ErrInvalidEmail
+	}
+	local, domain := email[:at], email[at+1:]
+	if !validEmailLocal(local) {
+		return "", "", ErrInvalidEmail
+	}
+
+	ascii, err := idnaProfile.ToASCII(domain)
+	if err != nil || !strings.Contains(ascii, ".") {
+		return "", "", ErrInvalidEmail
+	}
+	// El dominio de primer nivel es alfabético o un IDN (xn--)
+	tld := ascii[strings.LastIndexByte(ascii, '.')+1:]
+	if len(tld) < 2 || (!strings.HasPrefix(tld, "xn--") && strings.IndexFunc(tld, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0) {
+		return "", "", ErrInvalidEmail
+	}
+	return local, ascii, nil
+}
+
+// validEmailLocal valida la parte local en formato dot-atom, admitiendo letras y dígitos Unicode
+func validEmailLocal(local string) bool {
+	if len(local) > 64 || strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return false
+	}
+	for _, r := range local {
+		if r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) && !strings.ContainsRune(emailLocalSymbols, r) {
+			return false
+		}
+	}
+	return true
+}
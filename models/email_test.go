@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeResolver responde consultas MX desde un mapa; los dominios ausentes no existen
+type fakeResolver map[string][]*net.MX
+
+func (f fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if name == "timeout.example" {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"test@test.com", "test@test.com"},
+		{"test@sub.test.com", "test@sub.test.com"},
+		{" John.Doe@Example.COM ", "John.Doe@example.com"},
+		{"ana+promo@example.com", "ana+promo@example.com"},
+		{"josé@Bücher.DE", "josé@bücher.de"},
+		{"info@xn--bcher-kva.de", "info@bücher.de"},
+		{"用户@例子.广告", "用户@例子.广告"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			email, err := NormalizeEmail(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, email)
+		})
+	}
+}
+
+func TestNormalizeEmailInvalid(t *testing.T) {
+	for _, input := range []string{"", "test@", "testtest.com", "@example.com", "a..b@example.com", ".a@example.com", "a b@example.com", "a@localhost", "a@example.c0m", "a@exa_mple.com"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := NormalizeEmail(input)
+			assert.ErrorIs(t, err, ErrInvalidEmail)
+		})
+	}
+}
+
+func TestEmailKey(t *testing.T) {
+	assert.Equal(t, "john.doe@example.com", EmailKey("John.Doe@Example.com"))
+	assert.Equal(t, "josé@xn--bcher-kva.de", EmailKey("José@bücher.de"))
+	assert.Equal(t, "ana+promo@example.com", EmailKey("ana+promo@example.com"))
+
+	StripEmailPlusTag = true
+	defer func() { StripEmailPlusTag = false }()
+	assert.Equal(t, "ana@example.com", EmailKey("Ana+Promo@example.com"))
+	assert.Equal(t, "+ana@example.com", EmailKey("+ana@example.com"))
+}
+
+func TestIsDisposableEmail(t *testing.T) {
+	assert.True(t, IsDisposableEmail("a@mailinator.com"))
+	assert.True(t, IsDisposableEmail("a@Eu.Mailinator.com"))
+	assert.False(t, IsDisposableEmail("a@notmailinator.com"))
+	assert.False(t, IsDisposableEmail("a@example.com"))
+}
+
+func TestCheckEmailMX(t *testing.T) {
+	assert.NoError(t, CheckEmailMX("a@nowhere.example"), "Sin resolver no se consulta el DNS")
+
+	EmailResolver = fakeResolver{
+		"example.com":      {{Host: "mx.example.com.", Pref: 10}},
+		"xn--bcher-kva.de": {{Host: "mx.xn--bcher-kva.de.", Pref: 10}},
+		"nullmx.example":   {{Host: ".", Pref: 0}},
+	}
+	defer func() { EmailResolver = nil }()
+
+	assert.NoError(t, CheckEmailMX("a@example.com"))
+	assert.NoError(t, CheckEmailMX("a@Bücher.de"), "Los IDN se consultan en punycode")
+	assert.NoError(t, CheckEmailMX("a@timeout.example"), "Un fallo temporal del DNS no rechaza el email")
+	assert.True(t, errors.Is(CheckEmailMX("a@nowhere.example"), ErrNoMailServer))
+	assert.True(t, errors.Is(CheckEmailMX("a@nullmx.example"), ErrNoMailServer))
+}
+
+func TestValidateClientEmailRules(t *testing.T) {
+	client := Client{Name: "Ana", LastName: "Silva", Email: "Ana@Example.COM", BirthDay: date(1990, 1, 1), Telephone: "600123456"}
+	assert.Empty(t, ValidateClient(&client))
+	assert.Equal(t, "Ana@example.com", client.Email)
+	assert.Equal(t, "ana@example.com", client.EmailKey)
+
+	client.Email = "ana@yopmail.com"
+	assert.Equal(t, ValidationErrors{{Field: "email", Code: CodeDisposable, Message: "Disposable email addresses are not allowed"}}, ValidateClient(&client))
+
+	EmailResolver = fakeResolver{}
+	defer func() { EmailResolver = nil }()
+	client.Email = "ana@nowhere.example"
+	assert.Equal(t, ValidationErrors{{Field: "email", Code: CodeNoMailServer, Message: "Email domain does not accept mail"}}, ValidateClient(&client))
+}
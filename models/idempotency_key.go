@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey guarda la respuesta a una petición POST para repetirla si el cliente reintenta con la misma clave
+type IdempotencyKey struct {
+	ID          int               `json:"id" gorm:"primaryKey;autoIncrement"`
+	Key         string            `json:"key" gorm:"not null;uniqueIndex:idx_idempotency_keys_actor_key"`
+	Actor       string            `json:"actor" gorm:"not null;uniqueIndex:idx_idempotency_keys_actor_key"`
+	Fingerprint string            `json:"fingerprint" gorm:"not null"`
+	Status      int               `json:"status"` // 0 mientras la petición original está en curso
+	ContentType string            `json:"content_type"`
+	Body        []byte            `json:"body"`
+	Headers     map[string]string `json:"headers" gorm:"serializer:json"` // Cabeceras de la respuesta que se repiten, como ETag
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   time.Time         `json:"expires_at" gorm:"index"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// KPISnapshot guarda el estado de los KPI de clientes en un momento. Se guarda la distribución de
+// edades para poder calcular después cualquier percentil o modo de desviación estándar
+type KPISnapshot struct {
+	ID           int          `json:"id" gorm:"primaryKey;autoIncrement"`
+	TakenAt      time.Time    `json:"taken_at" gorm:"not null;index"`
+	Count        int64        `json:"count"`
+	DeletedCount int64        `json:"deleted_count"`
+	Ages         Distribution `json:"ages" gorm:"serializer:json"`
+}
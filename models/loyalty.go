@@ -0,0 +1,342 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Movimientos del libro de puntos de fidelidad
+const (
+	LoyaltyAccrual    = "accrual"
+	LoyaltyRedemption = "redemption"
+	LoyaltyExpiry     = "expiry"
+	LoyaltyTransfer   = "transfer"
+)
+
+// LoyaltyExpiryMonths son los meses que duran los puntos desde que se acumulan; 0 hace que no venzan
+var LoyaltyExpiryMonths = 12
+
+// ErrImmutableLedger se devuelve al intentar modificar o borrar un movimiento de puntos
+var ErrImmutableLedger = errors.New("loyalty entries are append-only")
+
+// LoyaltyRule es una regla de acumulación de puntos. Un pedido suma, por cada regla que cumple, points_per_euro
+// puntos por euro del total, redondeando hacia abajo, más bonus_points. Las condiciones vacías valen para
+// cualquier pedido; starts_on y ends_on limitan las fechas de pedido en que la regla está vigente
+type LoyaltyRule struct {
+	ID            int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name          string     `json:"name" gorm:"not null;unique"`
+	Channel       string     `json:"channel" enums:"in_store,online,phone"`
+	StoreID       *int       `json:"store_id"`
+	MinTotalCents int64      `json:"min_total_cents" gorm:"not null;default:0"`
+	PointsPerEuro float64    `json:"points_per_euro" gorm:"not null;default:0"`
+	BonusPoints   int64      `json:"bonus_points" gorm:"not null;default:0"`
+	StartsOn      *time.Time `json:"starts_on"`
+	EndsOn        *time.Time `json:"ends_on"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// LoyaltyEntry es un movimiento del libro de puntos de un cliente, que solo admite altas. Las acumulaciones
+// suman puntos y vencen en expires_at; los canjes y vencimientos restan. Un vencimiento cierra la acumulación
+// source_id con los puntos que le quedaban, y su fecha es la del vencimiento. Al fusionar clientes los puntos
+// vigentes del fusionado pasan al que sobrevive con un traspaso por acumulación, con el mismo vencimiento
+type LoyaltyEntry struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID    int        `json:"client_id" gorm:"not null;index"`
+	Kind        string     `json:"kind" gorm:"not null" enums:"accrual,redemption,expiry,transfer"`
+	Points      int64      `json:"points" gorm:"not null"`
+	OrderID     *int       `json:"order_id" gorm:"index"`
+	SourceID    *int       `json:"source_id" gorm:"uniqueIndex"`
+	Description string     `json:"description"`
+	Actor       string     `json:"actor"`
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index"`
+}
+
+// LoyaltyLot es lo que queda de una acumulación o de un traspaso recibido después de los canjes, que consumen
+// primero los puntos que vencen antes, y de su vencimiento
+type LoyaltyLot struct {
+	EntryID   int        `json:"entry_id"`
+	Points    int64      `json:"points"`
+	EarnedAt  time.Time  `json:"earned_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	closed    bool
+}
+
+// BeforeSave guarda las fechas de vigencia al comienzo del día en UTC y el nombre sin espacios alrededor
+func (r *LoyaltyRule) BeforeSave(tx *gorm.DB) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.StartsOn != nil {
+		starts := startOfDay(*r.StartsOn)
+		r.StartsOn = &starts
+	}
+	if r.EndsOn != nil {
+		ends := startOfDay(*r.EndsOn)
+		r.EndsOn = &ends
+	}
+	return nil
+}
+
+// Applies indica si el pedido cumple las condiciones de la regla
+func (r *LoyaltyRule) Applies(o *Order) bool {
+	placed := o.PlacedAt.UTC().Format("2006-01-02")
+	switch {
+	case r.Channel != "" && r.Channel != o.Channel:
+		return false
+	case r.StoreID != nil && (o.StoreID == nil || *r.StoreID != *o.StoreID):
+		return false
+	case o.TotalCents < r.MinTotalCents:
+		return false
+	case r.StartsOn != nil && placed < r.StartsOn.Format("2006-01-02"):
+		return false
+	case r.EndsOn != nil && placed > r.EndsOn.Format("2006-01-02"):
+		return false
+	}
+	return true
+}
+
+// OrderPoints suma los puntos que da el pedido con cada regla que cumple
+func OrderPoints(rules []LoyaltyRule, o *Order) int64 {
+	var points int64
+	for i := range rules {
+		if rules[i].Applies(o) {
+			points += int64(math.Floor(float64(o.TotalCents)*rules[i].PointsPerEuro/100)) + rules[i].BonusPoints
+		}
+	}
+	return points
+}
+
+// LoyaltyExpiresAt devuelve cuándo vencen los puntos acumulados en at, o nil si no vencen
+func LoyaltyExpiresAt(at time.Time) *time.Time {
+	if LoyaltyExpiryMonths <= 0 {
+		return nil
+	}
+	expires := at.UTC().AddDate(0, LoyaltyExpiryMonths, 0)
+	return &expires
+}
+
+// BeforeCreate guarda las fechas del movimiento en UTC, para que el libro se ordene igual sin importar la
+// zona horaria con la que se registró
+func (e *LoyaltyEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = Now()
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	if e.ExpiresAt != nil {
+		expires := e.ExpiresAt.UTC()
+		e.ExpiresAt = &expires
+	}
+	return nil
+}
+
+// BeforeUpdate impide modificar movimientos ya registrados
+func (e *LoyaltyEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrImmutableLedger
+}
+
+// BeforeDelete impide borrar movimientos ya registrados
+func (e *LoyaltyEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrImmutableLedger
+}
+
+// LoyaltyLots reparte los canjes y vencimientos entre las acumulaciones y traspasos de entries, que deben estar
+// en orden cronológico. Cada canje consume primero los puntos que vencen antes entre los que seguían vigentes
+// en su fecha; uncovered son los puntos canjeados que no alcanzaron a cubrirse
+func LoyaltyLots(entries []LoyaltyEntry) (lots []LoyaltyLot, uncovered int64) {
+	index := map[int]int{}
+	for _, e := range entries {
+		switch {
+		case e.Kind == LoyaltyAccrual || e.Kind == LoyaltyTransfer:
+			index[e.ID] = len(lots)
+			lots = append(lots, LoyaltyLot{EntryID: e.ID, Points: e.Points, EarnedAt: e.CreatedAt, ExpiresAt: e.ExpiresAt})
+		case e.Kind == LoyaltyExpiry:
+			if i, ok := index[derefInt(e.SourceID)]; ok {
+				lots[i].Points += e.Points
+				lots[i].closed = true
+			}
+		case e.Kind == LoyaltyRedemption:
+			needed := -e.Points
+			for _, i := range byExpiry(lots) {
+				lot := &lots[i]
+				if needed == 0 {
+					break
+				}
+				if lot.closed || lot.Points == 0 || !lot.ValidAt(e.CreatedAt) {
+					continue
+				}
+				taken := min(needed, lot.Points)
+				lot.Points -= taken
+				needed -= taken
+			}
+			uncovered += needed
+		}
+	}
+	return lots, uncovered
+}
+
+// byExpiry devuelve los índices de lots de los que vencen antes a los que no vencen, y en cada fecha en el
+// orden en que se acumularon
+func byExpiry(lots []LoyaltyLot) []int {
+	order := make([]int, len(lots))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		x, y := lots[order[a]].ExpiresAt, lots[order[b]].ExpiresAt
+		return x != nil && (y == nil || x.Before(*y))
+	})
+	return order
+}
+
+// ValidAt indica si los puntos del lote se pueden usar en at
+func (l LoyaltyLot) ValidAt(at time.Time) bool {
+	return !l.closed && (l.ExpiresAt == nil || l.ExpiresAt.After(at))
+}
+
+// LoadLoyaltyEntries devuelve el libro de puntos de un cliente en orden cronológico
+func LoadLoyaltyEntries(db *gorm.DB, clientID int) ([]LoyaltyEntry, error) {
+	entries := []LoyaltyEntry{}
+	err := db.Where("client_id = ?", clientID).Order("created_at, id").Find(&entries).Error
+	return entries, err
+}
+
+// openLoyaltyLots filtra las acumulaciones y traspasos recibidos que todavía no se cerraron con un vencimiento
+func openLoyaltyLots(db *gorm.DB) *gorm.DB {
+	return db.Model(&LoyaltyEntry{}).
+		Where("kind IN ? AND points > 0", []string{LoyaltyAccrual, LoyaltyTransfer}).
+		Where("NOT EXISTS (SELECT 1 FROM loyalty_entries e WHERE e.source_id = loyalty_entries.id)")
+}
+
+// ExpireLoyaltyPoints registra el vencimiento de las acumulaciones cuya fecha de vencimiento ya pasó, con los
+// puntos que les quedaban, de un cliente o de todos si clientID es 0. Las acumulaciones canjeadas por completo
+// se cierran con un vencimiento de 0 puntos para no volver a revisarlas. Al revisar todos los clientes recalcula
+// el próximo vencimiento de los agregados. Devuelve los puntos vencidos
+func ExpireLoyaltyPoints(db *gorm.DB, clientID int) (int64, error) {
+	now := Now().UTC()
+	pending := openLoyaltyLots(db).Where("expires_at <= ?", now)
+	if clientID != 0 {
+		pending = pending.Where("client_id = ?", clientID)
+	}
+	var clientIDs []int
+	if err := pending.Distinct().Pluck("client_id", &clientIDs).Error; err != nil {
+		return 0, err
+	}
+
+	var expired int64
+	for _, id := range clientIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			entries, err := LoadLoyaltyEntries(tx, id)
+			if err != nil {
+				return err
+			}
+			lots, _ := LoyaltyLots(entries)
+			for _, lot := range lots {
+				if lot.closed || lot.ValidAt(now) {
+					continue
+				}
+				entry := LoyaltyEntry{
+					ClientID:    id,
+					Kind:        LoyaltyExpiry,
+					Points:      -lot.Points,
+					SourceID:    &lot.EntryID,
+					Description: "Points earned on " + lot.EarnedAt.UTC().Format("2006-01-02") + " expired",
+					CreatedAt:   *lot.ExpiresAt,
+				}
+				res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected > 0 {
+					expired += lot.Points
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	if clientID == 0 {
+		return expired, updateLoyaltyNextExpiry(db)
+	}
+	return expired, nil
+}
+
+// TransferLoyaltyPoints traspasa los puntos vigentes de un cliente que se fusiona al que sobrevive. Cada parte del
+// saldo entra en el superviviente con un traspaso que conserva su vencimiento; los puntos vencidos no pasan.
+// El libro del fusionado no cambia: se borra con él, con PurgeLoyaltyEntries. Devuelve los puntos traspasados
+func TransferLoyaltyPoints(tx *gorm.DB, fromID, toID int, actor string) (int64, error) {
+	entries, err := LoadLoyaltyEntries(tx, fromID)
+	if err != nil {
+		return 0, err
+	}
+	now := Now().UTC()
+	lots, _ := LoyaltyLots(entries)
+	var transferred int64
+	for _, lot := range lots {
+		if lot.Points <= 0 || !lot.ValidAt(now) {
+			continue
+		}
+		entry := LoyaltyEntry{
+			ClientID:    toID,
+			Kind:        LoyaltyTransfer,
+			Points:      lot.Points,
+			Description: "Points transferred from client #" + strconv.Itoa(fromID),
+			Actor:       actor,
+			ExpiresAt:   lot.ExpiresAt,
+			CreatedAt:   now,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return transferred, err
+		}
+		transferred += lot.Points
+	}
+	return transferred, nil
+}
+
+// PurgeLoyaltyEntries borra el libro de puntos de los clientes que se eliminan definitivamente al purgarlos o
+// fusionarlos, dados por clientIDs, que puede ser una subconsulta. Es la única baja que admite el libro, así
+// que se salta BeforeDelete
+func PurgeLoyaltyEntries(tx *gorm.DB, clientIDs interface{}) error {
+	return tx.Session(&gorm.Session{SkipHooks: true}).Where("client_id IN (?)", clientIDs).Delete(&LoyaltyEntry{}).Error
+}
+
+// ValidateLoyaltyRule comprueba todas las reglas de una regla de acumulación y devuelve cada violación encontrada
+func ValidateLoyaltyRule(r *LoyaltyRule) ValidationErrors {
+	var errs ValidationErrors
+	if strings.TrimSpace(r.Name) == "" {
+		errs.add("name", CodeRequired, "Name is required")
+	}
+	if r.Channel != "" && !contains(OrderChannels, r.Channel) {
+		errs.add("channel", CodeInvalidChoice, "Channel must be one of "+strings.Join(OrderChannels, ", "))
+	}
+	if r.MinTotalCents < 0 {
+		errs.add("min_total_cents", CodeOutOfRange, "Min Total cannot be negative")
+	}
+	if r.PointsPerEuro < 0 || math.IsNaN(r.PointsPerEuro) || math.IsInf(r.PointsPerEuro, 0) {
+		errs.add("points_per_euro", CodeOutOfRange, "Points Per Euro cannot be negative")
+	}
+	if r.BonusPoints < 0 {
+		errs.add("bonus_points", CodeOutOfRange, "Bonus Points cannot be negative")
+	} else if r.BonusPoints == 0 && r.PointsPerEuro == 0 {
+		errs.add("points_per_euro", CodeRequired, "A rule must give points per euro or bonus points")
+	}
+	if r.StartsOn != nil && r.EndsOn != nil && r.EndsOn.Before(*r.StartsOn) {
+		errs.add("ends_on", CodeOutOfRange, "Ends On must not be before Starts On")
+	}
+	return errs
+}
+
+func derefInt(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoyaltyLotsRedeemOldestValidPointsFirst(t *testing.T) {
+	at := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	expires := func(s string) *time.Time {
+		d := at(s)
+		return &d
+	}
+	entries := []LoyaltyEntry{
+		{ID: 1, Kind: LoyaltyAccrual, Points: 100, CreatedAt: at("2023-01-10"), ExpiresAt: expires("2024-01-10")},
+		{ID: 2, Kind: LoyaltyAccrual, Points: 50, CreatedAt: at("2023-06-01"), ExpiresAt: expires("2024-06-01")},
+		{ID: 3, Kind: LoyaltyRedemption, Points: -120, CreatedAt: at("2023-09-01")},
+		// El primer lote ya venció: el canje sale del segundo, que no alcanza
+		{ID: 4, Kind: LoyaltyRedemption, Points: -40, CreatedAt: at("2024-02-01")},
+		{ID: 5, Kind: LoyaltyAccrual, Points: 70, CreatedAt: at("2024-03-01")},
+	}
+
+	lots, uncovered := LoyaltyLots(entries)
+	assert.Equal(t, int64(10), uncovered)
+	assert.Equal(t, []int64{0, 0, 70}, []int64{lots[0].Points, lots[1].Points, lots[2].Points})
+	assert.False(t, lots[1].ValidAt(at("2024-06-01")), "Vence al comienzo de su fecha de vencimiento")
+	assert.True(t, lots[2].ValidAt(at("2099-01-01")), "Sin fecha de vencimiento no vence")
+
+	// Un vencimiento registrado cierra el lote aunque le quedaran puntos
+	lots, _ = LoyaltyLots(append(entries[:2:2], LoyaltyEntry{Kind: LoyaltyExpiry, Points: -100, SourceID: &entries[0].ID, CreatedAt: at("2024-01-10")}))
+	assert.Equal(t, int64(0), lots[0].Points)
+	assert.False(t, lots[0].ValidAt(at("2023-02-01")))
+}
+
+func TestOrderPoints(t *testing.T) {
+	store := 3
+	order := Order{Channel: ChannelOnline, PlacedAt: time.Date(2024, time.June, 15, 23, 30, 0, 0, time.UTC),
+		Lines: []OrderLine{{Product: "Pienso", Quantity: 2, UnitPriceCents: 2499}, {Product: "Snack", Quantity: 1, UnitPriceCents: 350}}, DiscountCents: 348}
+	order.ComputeTotals()
+	assert.Equal(t, []int64{4998, 350}, []int64{order.Lines[0].TotalCents, order.Lines[1].TotalCents})
+	assert.Equal(t, []int64{5348, 5000}, []int64{order.SubtotalCents, order.TotalCents})
+
+	rules := []LoyaltyRule{
+		{Name: "Base", PointsPerEuro: 1.5},
+		{Name: "Online", Channel: ChannelOnline, PointsPerEuro: 1},
+		{Name: "Tienda", StoreID: &store, BonusPoints: 500},
+		{Name: "Cesta grande", MinTotalCents: 5000, BonusPoints: 100, EndsOn: day("2024-06-15")},
+		{Name: "Verano", BonusPoints: 1000, StartsOn: day("2024-06-21")},
+	}
+	assert.Equal(t, int64(75+50+100), OrderPoints(rules, &order))
+}
+
+func TestValidateOrderReportsAllErrors(t *testing.T) {
+	order := Order{Channel: ChannelInStore, PlacedAt: Now().Add(time.Hour), DiscountCents: 100,
+		Lines: []OrderLine{{Product: " ", Quantity: 0, UnitPriceCents: -1}}}
+	order.ComputeTotals()
+
+	assert.Equal(t, ValidationErrors{
+		{Field: "store_id", Code: CodeRequired, Message: "Store is required for in-store orders"},
+		{Field: "placed_at", Code: CodeFutureDate, Message: "Placed At cannot be in the future"},
+		{Field: "lines[0].product", Code: CodeRequired, Message: "Product is required"},
+		{Field: "lines[0].quantity", Code: CodeOutOfRange, Message: "Quantity must be positive"},
+		{Field: "lines[0].unit_price_cents", Code: CodeOutOfRange, Message: "Unit Price cannot be negative"},
+		{Field: "discount_cents", Code: CodeOutOfRange, Message: "Discount must be between 0 and the subtotal"},
+	}, ValidateOrder(&order))
+	assert.Equal(t, CodeInvalidChoice, ValidateOrder(&Order{Channel: "fax", Lines: order.Lines[:0]})[0].Code)
+}
+
+func day(s string) *time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return &d
+}
+
+func TestLoyaltyLotsTransfers(t *testing.T) {
+	at := func(s string) time.Time {
+		return *day(s)
+	}
+	entries := []LoyaltyEntry{
+		{ID: 1, Kind: LoyaltyAccrual, Points: 100, CreatedAt: at("2024-03-01"), ExpiresAt: day("2025-03-01")},
+		// Un traspaso recibido de una fusión que vence antes que los puntos propios
+		{ID: 3, Kind: LoyaltyTransfer, Points: 30, CreatedAt: at("2024-04-01"), ExpiresAt: day("2024-09-01")},
+		{ID: 4, Kind: LoyaltyRedemption, Points: -50, CreatedAt: at("2024-05-01")},
+	}
+	lots, uncovered := LoyaltyLots(entries)
+	assert.Zero(t, uncovered)
+	assert.Equal(t, []int64{80, 0}, []int64{lots[0].Points, lots[1].Points})
+
+	// El traspaso vence en su fecha como una acumulación
+	lots, _ = LoyaltyLots(append(entries[:2:2], LoyaltyEntry{ID: 5, Kind: LoyaltyExpiry, Points: -30, SourceID: &entries[1].ID, CreatedAt: at("2024-09-01")}))
+	assert.Equal(t, int64(0), lots[1].Points)
+	assert.False(t, lots[1].ValidAt(at("2024-08-01")))
+}
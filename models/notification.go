@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Tipos de notificación
+const (
+	NotificationTreatmentDue = "treatment_due"
+)
+
+// Notification es un aviso para un cliente, guardado para que lo entregue el canal de mensajería de la
+// tienda. Los recordatorios de tratamientos llevan el registro y la fecha que recuerdan; se emite uno solo
+// por cada fecha de repetición de un registro
+type Notification struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID    int        `json:"client_id" gorm:"not null;index"`
+	Kind        string     `json:"kind" gorm:"not null" enums:"treatment_due"`
+	Message     string     `json:"message" gorm:"not null"`
+	PetID       *int       `json:"pet_id"`
+	TreatmentID *int       `json:"treatment_id" gorm:"uniqueIndex:idx_notifications_treatment_due"`
+	DueOn       *time.Time `json:"due_on" gorm:"uniqueIndex:idx_notifications_treatment_due"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func init() {
+	ClientReferences = append(ClientReferences, ClientReference{Table: "notifications", Column: "client_id"})
+}
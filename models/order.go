@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Canales por los que se hace un pedido
+const (
+	ChannelInStore = "in_store"
+	ChannelOnline  = "online"
+	ChannelPhone   = "phone"
+)
+
+// OrderChannels son los canales válidos de un pedido
+var OrderChannels = []string{ChannelInStore, ChannelOnline, ChannelPhone}
+
+// MaxOrderLines es la cantidad máxima de líneas de un pedido
+const MaxOrderLines = 200
+
+// Order es una compra de un cliente. Los importes se guardan en céntimos; subtotal_cents es la suma de las
+// líneas y total_cents lo que se cobró después del descuento. points_earned son los puntos de fidelidad que
+// sumó el pedido según las reglas vigentes al registrarlo
+type Order struct {
+	ID            int         `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID      int         `json:"client_id" gorm:"not null;index"`
+	StoreID       *int        `json:"store_id" gorm:"index"`
+	Channel       string      `json:"channel" gorm:"not null" enums:"in_store,online,phone"`
+	PlacedAt      time.Time   `json:"placed_at" gorm:"not null;index"`
+	Lines         []OrderLine `json:"lines" gorm:"foreignKey:OrderID"`
+	SubtotalCents int64       `json:"subtotal_cents" gorm:"not null"`
+	DiscountCents int64       `json:"discount_cents" gorm:"not null;default:0"`
+	TotalCents    int64       `json:"total_cents" gorm:"not null"`
+	PointsEarned  int64       `json:"points_earned" gorm:"not null;default:0"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// OrderLine es un producto de un pedido; total_cents es quantity por unit_price_cents
+type OrderLine struct {
+	ID             int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	OrderID        int    `json:"order_id" gorm:"not null;index"`
+	Product        string `json:"product" gorm:"not null"`
+	Quantity       int    `json:"quantity" gorm:"not null"`
+	UnitPriceCents int64  `json:"unit_price_cents" gorm:"not null"`
+	TotalCents     int64  `json:"total_cents" gorm:"not null"`
+}
+
+func init() {
+	ClientReferences = append(ClientReferences, ClientReference{Table: "orders", Column: "client_id"})
+}
+
+// BeforeSave guarda la fecha del pedido en UTC
+func (o *Order) BeforeSave(tx *gorm.DB) error {
+	o.PlacedAt = o.PlacedAt.UTC()
+	return nil
+}
+
+// ComputeTotals calcula el importe de cada línea, el subtotal y el total del pedido
+func (o *Order) ComputeTotals() {
+	o.SubtotalCents = 0
+	for i := range o.Lines {
+		line := &o.Lines[i]
+		line.Product = strings.TrimSpace(line.Product)
+		line.TotalCents = int64(line.Quantity) * line.UnitPriceCents
+		o.SubtotalCents += line.TotalCents
+	}
+	o.TotalCents = o.SubtotalCents - o.DiscountCents
+}
+
+// ValidateOrder comprueba todas las reglas de un pedido con sus totales ya calculados y devuelve cada
+// violación encontrada
+func ValidateOrder(o *Order) ValidationErrors {
+	var errs ValidationErrors
+	if !contains(OrderChannels, o.Channel) {
+		errs.add("channel", CodeInvalidChoice, "Channel must be one of "+strings.Join(OrderChannels, ", "))
+	} else if o.Channel == ChannelInStore && o.StoreID == nil {
+		errs.add("store_id", CodeRequired, "Store is required for in-store orders")
+	}
+	if o.PlacedAt.After(Now()) {
+		errs.add("placed_at", CodeFutureDate, "Placed At cannot be in the future")
+	}
+	switch {
+	case len(o.Lines) == 0:
+		errs.add("lines", CodeRequired, "An order needs at least one line")
+	case len(o.Lines) > MaxOrderLines:
+		errs.add("lines", CodeOutOfRange, fmt.Sprintf("An order can have at most %d lines", MaxOrderLines))
+	}
+	for i, line := range o.Lines {
+		field := fmt.Sprintf("lines[%d].", i)
+		if line.Product == "" {
+			errs.add(field+"product", CodeRequired, "Product is required")
+		}
+		if line.Quantity <= 0 {
+			errs.add(field+"quantity", CodeOutOfRange, "Quantity must be positive")
+		}
+		if line.UnitPriceCents < 0 {
+			errs.add(field+"unit_price_cents", CodeOutOfRange, "Unit Price cannot be negative")
+		}
+	}
+	if o.DiscountCents < 0 || o.DiscountCents > o.SubtotalCents {
+		errs.add("discount_cents", CodeOutOfRange, "Discount must be between 0 and the subtotal")
+	}
+	return errs
+}
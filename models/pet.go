@@ -0,0 +1,215 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Especies de mascota
+const (
+	SpeciesDog     = "dog"
+	SpeciesCat     = "cat"
+	SpeciesBird    = "bird"
+	SpeciesRabbit  = "rabbit"
+	SpeciesRodent  = "rodent"
+	SpeciesReptile = "reptile"
+	SpeciesFish    = "fish"
+	SpeciesOther   = "other"
+)
+
+// PetSpecies son las especies que se pueden registrar
+var PetSpecies = []string{SpeciesDog, SpeciesCat, SpeciesBird, SpeciesRabbit, SpeciesRodent, SpeciesReptile, SpeciesFish, SpeciesOther}
+
+// Sexos de mascota
+const (
+	SexMale    = "male"
+	SexFemale  = "female"
+	SexUnknown = "unknown"
+)
+
+// MicrochipLength es la cantidad de dígitos de un microchip ISO 11784/11785
+const MicrochipLength = 15
+
+// Pet es una mascota de un cliente. Microchip es null si no tiene; BirthDay es null si no se conoce.
+// Weights es el historial de pesos, del más antiguo al más reciente. Al eliminar al cliente se eliminan
+// sus mascotas, marcadas con DeletedWithClient, y al restaurarlo vuelven las que se eliminaron con él.
+// El microchip es único entre las mascotas activas; el índice único parcial lo crea config.Migrate
+type Pet struct {
+	ID                int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID          int            `json:"client_id" gorm:"not null;index"`
+	Name              string         `json:"name" gorm:"not null"`
+	Species           string         `json:"species" gorm:"not null" enums:"dog,cat,bird,rabbit,rodent,reptile,fish,other"`
+	Breed             string         `json:"breed"`
+	Sex               string         `json:"sex" gorm:"not null;default:unknown" enums:"male,female,unknown"`
+	BirthDay          *time.Time     `json:"birth_day"`
+	Neutered          bool           `json:"neutered" gorm:"not null;default:false"`
+	Microchip         *string        `json:"microchip"`
+	Weights           []PetWeight    `json:"weights" gorm:"foreignKey:PetID"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
+	DeletedWithClient bool           `json:"-" gorm:"not null;default:false"`
+}
+
+// PetWeight es un peso de una mascota en kilos, medido el día MeasuredOn
+type PetWeight struct {
+	ID         int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PetID      int       `json:"pet_id" gorm:"not null;index"`
+	Kilograms  float64   `json:"kilograms" gorm:"not null"`
+	MeasuredOn time.Time `json:"measured_on" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func init() {
+	ClientReferences = append(ClientReferences, ClientReference{Table: "pets", Column: "client_id"})
+}
+
+// BeforeSave normaliza la especie, el sexo y el microchip. Los valores inválidos se rechazan al validar
+func (p *Pet) BeforeSave(tx *gorm.DB) error {
+	p.Species = strings.ToLower(strings.TrimSpace(p.Species))
+	p.Sex = strings.ToLower(strings.TrimSpace(p.Sex))
+	if p.Sex == "" {
+		p.Sex = SexUnknown
+	}
+	if p.Microchip != nil {
+		chip := NormalizeMicrochip(*p.Microchip)
+		if chip == "" {
+			p.Microchip = nil
+		} else {
+			p.Microchip = &chip
+		}
+	}
+	return nil
+}
+
+// NormalizeMicrochip quita los espacios, puntos y guiones con los que se suele escribir un microchip
+func NormalizeMicrochip(chip string) string {
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(strings.TrimSpace(chip))
+}
+
+// ValidMicrochip indica si chip tiene 15 dígitos y su último dígito es el de control Luhn de los
+// anteriores, lo que detecta los errores de tipeo al copiar el número del lector
+func ValidMicrochip(chip string) bool {
+	if len(chip) != MicrochipLength {
+		return false
+	}
+	sum := 0
+	for i := len(chip) - 1; i >= 0; i-- {
+		d := int(chip[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		// Desde la derecha, se duplica uno de cada dos dígitos empezando por el anterior al de control
+		if (len(chip)-1-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// DeleteClientPets elimina lógicamente dentro de tx las mascotas activas del cliente, con la misma fecha
+// de eliminación que el cliente, y las marca para restaurarlas con él
+func DeleteClientPets(tx *gorm.DB, client *Client) error {
+	return tx.Model(&Pet{}).Where("client_id = ?", client.ID).Updates(map[string]interface{}{
+		"deleted_at":          client.DeletedAt,
+		"deleted_with_client": true,
+	}).Error
+}
+
+// ErrMicrochipTaken indica que una mascota a restaurar tiene un microchip que ya registró otra mascota activa
+var ErrMicrochipTaken = errors.New("a pet of the client has a microchip that is already registered to another pet")
+
+// RestoreClientPets restaura dentro de tx las mascotas que se eliminaron junto con el cliente. Las que se
+// habían eliminado antes siguen eliminadas. Devuelve ErrMicrochipTaken si el microchip de alguna se registró
+// en otra mascota mientras estaba eliminada
+func RestoreClientPets(tx *gorm.DB, clientID int) error {
+	var taken int64
+	chips := tx.Unscoped().Model(&Pet{}).Select("microchip").
+		Where("client_id = ? AND deleted_with_client = ? AND microchip IS NOT NULL", clientID, true)
+	if err := tx.Model(&Pet{}).Where("microchip IN (?)", chips).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return ErrMicrochipTaken
+	}
+	return tx.Unscoped().Model(&Pet{}).Where("client_id = ? AND deleted_with_client = ?", clientID, true).Updates(map[string]interface{}{
+		"deleted_at":          nil,
+		"deleted_with_client": false,
+	}).Error
+}
+
+// ValidatePet comprueba todas las reglas de una mascota y devuelve cada violación encontrada.
+// Si el microchip es válido lo deja normalizado
+func ValidatePet(pet *Pet) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(pet.Name) == "" {
+		errs.add("name", CodeRequired, "Name is required")
+	}
+	switch species := strings.ToLower(strings.TrimSpace(pet.Species)); {
+	case species == "":
+		errs.add("species", CodeRequired, "Species is required")
+	case !contains(PetSpecies, species):
+		errs.add("species", CodeInvalidChoice, "Species must be one of "+strings.Join(PetSpecies, ", "))
+	}
+	switch strings.ToLower(strings.TrimSpace(pet.Sex)) {
+	case "", SexMale, SexFemale, SexUnknown:
+	default:
+		errs.add("sex", CodeInvalidChoice, "Sex must be male, female or unknown")
+	}
+
+	now := Now()
+	if pet.BirthDay != nil && pet.BirthDay.After(now) {
+		errs.add("birth_day", CodeFutureDate, "Birth Day cannot be in the future")
+	}
+
+	if pet.Microchip != nil {
+		chip := NormalizeMicrochip(*pet.Microchip)
+		switch {
+		case chip == "":
+		case len(chip) != MicrochipLength || strings.Trim(chip, "0123456789") != "":
+			errs.add("microchip", CodeInvalidFormat, "Microchip must have 15 digits")
+		case !ValidMicrochip(chip):
+			errs.add("microchip", CodeChecksum, "Microchip check digit does not match, check the number")
+		default:
+			pet.Microchip = &chip
+		}
+	}
+
+	for i := range pet.Weights {
+		errs = append(errs, ValidatePetWeight(&pet.Weights[i], "weights["+strconv.Itoa(i)+"].")...)
+	}
+	return errs
+}
+
+// ValidatePetWeight comprueba un peso; prefix antecede al nombre de los campos con error
+func ValidatePetWeight(weight *PetWeight, prefix string) ValidationErrors {
+	var errs ValidationErrors
+	if weight.Kilograms <= 0 || math.IsNaN(weight.Kilograms) || math.IsInf(weight.Kilograms, 0) {
+		errs.add(prefix+"kilograms", CodeOutOfRange, "Weight must be a positive number of kilograms")
+	}
+	switch {
+	case weight.MeasuredOn.IsZero():
+		errs.add(prefix+"measured_on", CodeRequired, "Measured On is required")
+	case weight.MeasuredOn.After(Now()):
+		errs.add(prefix+"measured_on", CodeFutureDate, "Measured On cannot be in the future")
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidMicrochip(t *testing.T) {
+	assert.True(t, ValidMicrochip("985102000123458"))
+	assert.True(t, ValidMicrochip("941000001234568"))
+	assert.False(t, ValidMicrochip("985102000123459"), "Dígito de control incorrecto")
+	assert.False(t, ValidMicrochip("985102000213458"), "Dos dígitos intercambiados")
+	assert.False(t, ValidMicrochip("98510200012345"))
+	assert.False(t, ValidMicrochip("98510200012345a"))
+	assert.Equal(t, "985102000123458", NormalizeMicrochip(" 985-102-000.123 458 "))
+}
+
+func TestValidatePetReportsAllErrors(t *testing.T) {
+	future := time.Now().AddDate(0, 1, 0)
+	chip := "985102000123459"
+	pet := Pet{
+		Species:   "dragon",
+		Sex:       "both",
+		BirthDay:  &future,
+		Microchip: &chip,
+		Weights:   []PetWeight{{Kilograms: 4.2, MeasuredOn: time.Now().AddDate(0, -1, 0)}, {Kilograms: -1}},
+	}
+
+	assert.Equal(t, ValidationErrors{
+		{Field: "name", Code: CodeRequired, Message: "Name is required"},
+		{Field: "species", Code: CodeInvalidChoice, Message: "Species must be one of dog, cat, bird, rabbit, rodent, reptile, fish, other"},
+		{Field: "sex", Code: CodeInvalidChoice, Message: "Sex must be male, female or unknown"},
+		{Field: "birth_day", Code: CodeFutureDate, Message: "Birth Day cannot be in the future"},
+		{Field: "microchip", Code: CodeChecksum, Message: "Microchip check digit does not match, check the number"},
+		{Field: "weights[1].kilograms", Code: CodeOutOfRange, Message: "Weight must be a positive number of kilograms"},
+		{Field: "weights[1].measured_on", Code: CodeRequired, Message: "Measured On is required"},
+	}, ValidatePet(&pet))
+}
+
+func TestValidatePetNormalizesMicrochip(t *testing.T) {
+	chip := "985 102 000 123 458"
+	pet := Pet{Name: "Luna", Species: "Cat", Microchip: &chip}
+
+	assert.Empty(t, ValidatePet(&pet))
+	assert.Equal(t, "985102000123458", *pet.Microchip)
+
+	short := "12345"
+	pet.Microchip = &short
+	assert.Equal(t, ValidationErrors{{Field: "microchip", Code: CodeInvalidFormat, Message: "Microchip must have 15 digits"}}, ValidatePet(&pet))
+}
+
+func TestValidatePetTreatmentReportsAllErrors(t *testing.T) {
+	future := time.Now().AddDate(0, 1, 0)
+	assert.Equal(t, ValidationErrors{
+		{Field: "kind", Code: CodeInvalidChoice, Message: "Kind must be one of vaccination, deworming, treatment"},
+		{Field: "product", Code: CodeRequired, Message: "Product is required"},
+		{Field: "administered_on", Code: CodeFutureDate, Message: "Administered On cannot be in the future"},
+	}, ValidatePetTreatment(&PetTreatment{Kind: "surgery", Product: " ", AdministeredOn: future}))
+
+	given := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	treatment := PetTreatment{Kind: "Vaccination", Product: "Nobivac Rabies", AdministeredOn: given, NextDueOn: &given}
+	assert.Equal(t, ValidationErrors{{Field: "next_due_on", Code: CodeOutOfRange, Message: "Next Due On must be after Administered On"}}, ValidatePetTreatment(&treatment))
+
+	due := given.AddDate(1, 0, 0)
+	treatment.NextDueOn = &due
+	assert.Empty(t, ValidatePetTreatment(&treatment))
+	assert.Equal(t, ValidationErrors{{Field: "kind", Code: CodeRequired, Message: "Kind is required"}, {Field: "administered_on", Code: CodeRequired, Message: "Administered On is required"}},
+		ValidatePetTreatment(&PetTreatment{Product: "Milbemax"}))
+}
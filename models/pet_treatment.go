@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de registro sanitario de una mascota
+const (
+	TreatmentVaccination = "vaccination"
+	TreatmentDeworming   = "deworming"
+	TreatmentMedication  = "treatment"
+)
+
+// TreatmentKinds son los tipos de registro sanitario que se pueden cargar
+var TreatmentKinds = []string{TreatmentVaccination, TreatmentDeworming, TreatmentMedication}
+
+// PetTreatment es una vacuna, desparasitación o tratamiento aplicado a una mascota el día AdministeredOn.
+// NextDueOn es el día en que toca repetirlo, o null si no se repite. Un registro posterior del mismo tipo
+// y producto para la misma mascota lo da por cumplido
+type PetTreatment struct {
+	ID             int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	PetID          int        `json:"pet_id" gorm:"not null;index"`
+	Kind           string     `json:"kind" gorm:"not null" enums:"vaccination,deworming,treatment"`
+	Product        string     `json:"product" gorm:"not null"`
+	Batch          string     `json:"batch"`
+	AdministeredOn time.Time  `json:"administered_on" gorm:"not null"`
+	NextDueOn      *time.Time `json:"next_due_on" gorm:"index"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// BeforeSave normaliza el tipo, quita los espacios del producto y el lote y guarda las fechas como días a
+// las 0 UTC, para que se comparen igual sin importar la zona horaria con la que se enviaron
+func (t *PetTreatment) BeforeSave(tx *gorm.DB) error {
+	t.Kind = strings.ToLower(strings.TrimSpace(t.Kind))
+	t.Product = strings.TrimSpace(t.Product)
+	t.Batch = strings.TrimSpace(t.Batch)
+	t.AdministeredOn = startOfDay(t.AdministeredOn)
+	if t.NextDueOn != nil {
+		due := startOfDay(*t.NextDueOn)
+		t.NextDueOn = &due
+	}
+	return nil
+}
+
+// startOfDay devuelve el día de t a las 0 UTC
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// PendingTreatments filtra los registros con fecha de repetición que todavía no se cumplieron: no hay otro
+// posterior del mismo tipo y producto para la misma mascota. Los productos se comparan sin distinguir
+// mayúsculas
+func PendingTreatments(db *gorm.DB) *gorm.DB {
+	return db.Where("pet_treatments.next_due_on IS NOT NULL").
+		Where(`NOT EXISTS (SELECT 1 FROM pet_treatments later WHERE later.pet_id = pet_treatments.pet_id
+			AND later.kind = pet_treatments.kind AND LOWER(later.product) = LOWER(pet_treatments.product)
+			AND (later.administered_on > pet_treatments.administered_on
+				OR (later.administered_on = pet_treatments.administered_on AND later.id > pet_treatments.id)))`)
+}
+
+// ValidatePetTreatment comprueba todas las reglas de un registro sanitario y devuelve cada violación encontrada
+func ValidatePetTreatment(treatment *PetTreatment) ValidationErrors {
+	var errs ValidationErrors
+
+	switch kind := strings.ToLower(strings.TrimSpace(treatment.Kind)); {
+	case kind == "":
+		errs.add("kind", CodeRequired, "Kind is required")
+	case !contains(TreatmentKinds, kind):
+		errs.add("kind", CodeInvalidChoice, "Kind must be one of "+strings.Join(TreatmentKinds, ", "))
+	}
+	if strings.TrimSpace(treatment.Product) == "" {
+		errs.add("product", CodeRequired, "Product is required")
+	}
+
+	switch {
+	case treatment.AdministeredOn.IsZero():
+		errs.add("administered_on", CodeRequired, "Administered On is required")
+	case treatment.AdministeredOn.After(Now()):
+		errs.add("administered_on", CodeFutureDate, "Administered On cannot be in the future")
+	case treatment.NextDueOn != nil && !treatment.NextDueOn.After(treatment.AdministeredOn):
+		errs.add("next_due_on", CodeOutOfRange, "Next Due On must be after Administered On")
+	}
+	return errs
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// Tipos de número de teléfono según el plan de numeración del país
+const (
+	PhoneMobile           = "mobile"
+	PhoneLandline         = "landline"
+	PhoneMobileOrLandline = "mobile_or_landline"
+	PhoneTollFree         = "toll_free"
+	PhonePremiumRate      = "premium_rate"
+	PhoneSharedCost       = "shared_cost"
+	PhoneVoIP             = "voip"
+	PhonePersonal         = "personal"
+	PhonePager            = "pager"
+	PhoneUAN              = "uan"
+	PhoneVoicemail        = "voicemail"
+	PhoneUnknown          = "unknown"
+)
+
+// ErrInvalidPhone se devuelve cuando un número no existe en el plan de numeración de ningún país probado
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// DefaultPhoneRegion es el país (ISO 3166-1) con el que se interpretan los números sin prefijo internacional
+var DefaultPhoneRegion = "ES"
+
+// PhoneRegions son los países que se prueban, después de DefaultPhoneRegion, con los números sin prefijo internacional
+var PhoneRegions = []string{"ES", "PT", "IT"}
+
+var phoneTypes = map[phonenumbers.PhoneNumberType]string{
+	phonenumbers.MOBILE:               PhoneMobile,
+	phonenumbers.FIXED_LINE:           PhoneLandline,
+	phonenumbers.FIXED_LINE_OR_MOBILE: PhoneMobileOrLandline,
+	phonenumbers.TOLL_FREE:            PhoneTollFree,
+	phonenumbers.PREMIUM_RATE:         PhonePremiumRate,
+	phonenumbers.SHARED_COST:          PhoneSharedCost,
+	phonenumbers.VOIP:                 PhoneVoIP,
+	phonenumbers.PERSONAL_NUMBER:      PhonePersonal,
+	phonenumbers.PAGER:                PhonePager,
+	phonenumbers.UAN:                  PhoneUAN,
+	phonenumbers.VOICEMAIL:            PhoneVoicemail,
+}
+
+// Phone es un número de teléfono validado
+type Phone struct {
+	// E164 es el número en formato internacional (+34600123456)
+	E164 string
+	// Type es el tipo de línea (mobile, landline, ...)
+	Type string
+	// Region es el país al que pertenece el número
+	Region string
+}
+
+// IsPhoneRegion indica si region es un país con plan de numeración conocido
+func IsPhoneRegion(region string) bool {
+	return phonenumbers.GetCountryCodeForRegion(strings.ToUpper(region)) != 0
+}
+
+// ParsePhone interpreta raw y lo valida contra el plan de numeración. Los números con prefijo
+// internacional (+ o 00) se validan en su país; el resto se prueba con region y luego con PhoneRegions
+func ParsePhone(raw, region string) (Phone, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Phone{}, ErrInvalidPhone
+	}
+	if strings.HasPrefix(raw, "00") {
+		raw = "+" + raw[2:]
+	}
+
+	regions := []string{strings.ToUpper(region)}
+	if !strings.HasPrefix(raw, "+") {
+		for _, r := range PhoneRegions {
+			if r != regions[0] {
+				regions = append(regions, r)
+			}
+		}
+	}
+
+	for _, r := range regions {
+		number, err := phonenumbers.Parse(raw, r)
+		if err != nil || !phonenumbers.IsValidNumber(number) {
+			continue
+		}
+		kind, ok := phoneTypes[phonenumbers.GetNumberType(number)]
+		if !ok {
+			kind = PhoneUnknown
+		}
+		return Phone{
+			E164:   phonenumbers.Format(number, phonenumbers.E164),
+			Type:   kind,
+			Region: phonenumbers.GetRegionCodeForNumber(number),
+		}, nil
+	}
+	return Phone{}, ErrInvalidPhone
+}
+
+// NormalizeTelephone guarda el teléfono del cliente en E.164 junto con su tipo. El texto tal como
+// se escribió queda en TelephoneDisplay, salvo que ya hubiera uno equivalente al mismo número
+func (c *Client) NormalizeTelephone(region string) error {
+	phone, err := ParsePhone(c.Telephone, region)
+	if err != nil {
+		return err
+	}
+	if c.Telephone != phone.E164 || c.TelephoneDisplay == "" {
+		c.TelephoneDisplay = strings.TrimSpace(c.Telephone)
+	} else if current, err := ParsePhone(c.TelephoneDisplay, region); err != nil || current.E164 != phone.E164 {
+		c.TelephoneDisplay = phone.E164
+	}
+	c.Telephone = phone.E164
+	c.TelephoneType = phone.Type
+	return nil
+}
+
+// PhonePrefix convierte el comienzo de un número en el comienzo de su forma E.164: sin prefijo
+// internacional se asume el código de país de region. Devuelve "" si prefix no tiene dígitos
+func PhonePrefix(prefix, region string) string {
+	prefix = strings.TrimSpace(prefix)
+	international := strings.HasPrefix(prefix, "+")
+	var digits strings.Builder
+	for _, r := range prefix {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if d == "" {
+		return ""
+	}
+	if international {
+		return "+" + d
+	}
+	if strings.HasPrefix(d, "00") {
+		return "+" + d[2:]
+	}
+	return "+" + strconv.Itoa(phonenumbers.GetCountryCodeForRegion(strings.ToUpper(region))) + d
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		region   string
+		expected Phone
+	}{
+		{"Spanish mobile with spaces", "+34 600 123 456", "ES", Phone{E164: "+34600123456", Type: PhoneMobile, Region: "ES"}},
+		{"Spanish national mobile", "600123456", "ES", Phone{E164: "+34600123456", Type: PhoneMobile, Region: "ES"}},
+		{"Spanish landline", "91 234 56 78", "ES", Phone{E164: "+34912345678", Type: PhoneLandline, Region: "ES"}},
+		{"Spanish toll free", "900 123 456", "ES", Phone{E164: "+34900123456", Type: PhoneTollFree, Region: "ES"}},
+		{"International 00 prefix", "0039 312 345 6789", "ES", Phone{E164: "+393123456789", Type: PhoneMobile, Region: "IT"}},
+		{"Default region wins ambiguous numbers", "912345678", "PT", Phone{E164: "+351912345678", Type: PhoneMobile, Region: "PT"}},
+		{"Falls back to Italy", "312 345 6789", "ES", Phone{E164: "+393123456789", Type: PhoneMobile, Region: "IT"}},
+		{"Italian landline keeps leading zero", "02 1234 5678", "IT", Phone{E164: "+390212345678", Type: PhoneLandline, Region: "IT"}},
+		{"Other countries need the + prefix", "+1 202 555 0143", "ES", Phone{E164: "+12025550143", Type: PhoneMobileOrLandline, Region: "US"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := ParsePhone(tt.input, tt.region)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, phone)
+		})
+	}
+}
+
+func TestParsePhoneInvalid(t *testing.T) {
+	for _, input := range []string{"", "0000000", "123456789", "555555555", "12ab", "999999999999999999999", "+34 123"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParsePhone(input, "ES")
+			assert.ErrorIs(t, err, ErrInvalidPhone)
+		})
+	}
+}
+
+func TestNormalizeTelephone(t *testing.T) {
+	client := Client{Telephone: " 600 12 34 56 "}
+	assert.NoError(t, client.NormalizeTelephone("ES"))
+	assert.Equal(t, "+34600123456", client.Telephone)
+	assert.Equal(t, PhoneMobile, client.TelephoneType)
+	assert.Equal(t, "600 12 34 56", client.TelephoneDisplay)
+
+	// Volver a normalizar el número ya guardado conserva cómo se escribió
+	assert.NoError(t, client.NormalizeTelephone("ES"))
+	assert.Equal(t, "600 12 34 56", client.TelephoneDisplay)
+
+	// Un número nuevo en E.164 reemplaza la forma anterior
+	client.Telephone = "+34912345678"
+	assert.NoError(t, client.NormalizeTelephone("ES"))
+	assert.Equal(t, "+34912345678", client.TelephoneDisplay)
+	assert.Equal(t, PhoneLandline, client.TelephoneType)
+
+	invalid := Client{Telephone: "0000000"}
+	assert.ErrorIs(t, invalid.NormalizeTelephone("ES"), ErrInvalidPhone)
+	assert.Equal(t, "0000000", invalid.Telephone)
+}
+
+func TestPhonePrefix(t *testing.T) {
+	assert.Equal(t, "+34600", PhonePrefix("600", "ES"))
+	assert.Equal(t, "+351912", PhonePrefix("912", "PT"))
+	assert.Equal(t, "+3519", PhonePrefix("+351 9", "ES"))
+	assert.Equal(t, "+39", PhonePrefix("0039", "ES"))
+	assert.Equal(t, "", PhonePrefix("abc", "ES"))
+}
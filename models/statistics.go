@@ -0,0 +1,201 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// HistogramBucket cuenta los valores en [From, To). Un extremo nil indica que el intervalo no tiene límite
+type HistogramBucket struct {
+	From  *float64 `json:"from"`
+	To    *float64 `json:"to"`
+	Count int64    `json:"count"`
+}
+
+// ValueCount es un valor y la cantidad de veces que aparece
+type ValueCount struct {
+	Value float64 `json:"value"`
+	Count int64   `json:"count"`
+}
+
+// Distribution es una tabla de frecuencias ordenada por valor. Permite calcular las estadísticas
+// a partir de un GROUP BY sin cargar cada fila
+type Distribution []ValueCount
+
+// NewDistribution arma la distribución de una lista de valores
+func NewDistribution(values []float64) Distribution {
+	counts := map[float64]int64{}
+	for _, v := range values {
+		counts[v]++
+	}
+	d := make(Distribution, 0, len(counts))
+	for v, n := range counts {
+		d = append(d, ValueCount{Value: v, Count: n})
+	}
+	return d.Sorted()
+}
+
+// Sorted ordena la distribución por valor, uniendo los valores repetidos
+func (d Distribution) Sorted() Distribution {
+	sort.Slice(d, func(i, j int) bool { return d[i].Value < d[j].Value })
+	merged := d[:0]
+	for _, vc := range d {
+		if n := len(merged); n > 0 && merged[n-1].Value == vc.Value {
+			merged[n-1].Count += vc.Count
+			continue
+		}
+		merged = append(merged, vc)
+	}
+	return merged
+}
+
+// Count devuelve la cantidad total de valores
+func (d Distribution) Count() int64 {
+	var n int64
+	for _, vc := range d {
+		n += vc.Count
+	}
+	return n
+}
+
+// Min devuelve el menor valor; false si no hay valores
+func (d Distribution) Min() (float64, bool) {
+	if len(d) == 0 {
+		return 0, false
+	}
+	return d[0].Value, true
+}
+
+// Max devuelve el mayor valor; false si no hay valores
+func (d Distribution) Max() (float64, bool) {
+	if len(d) == 0 {
+		return 0, false
+	}
+	return d[len(d)-1].Value, true
+}
+
+// Mean devuelve la media; false si no hay valores
+func (d Distribution) Mean() (float64, bool) {
+	n := d.Count()
+	if n == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, vc := range d {
+		sum += vc.Value * float64(vc.Count)
+	}
+	return sum / float64(n), true
+}
+
+// StdDev devuelve la desviación estándar poblacional o, con sample, la muestral (n-1).
+// false si no está definida: sin valores, o con menos de dos valores para la muestral
+func (d Distribution) StdDev(sample bool) (float64, bool) {
+	return d.Welford().StdDev(sample)
+}
+
+// Welford devuelve la cantidad, la media y M2 de la distribución
+func (d Distribution) Welford() Welford {
+	mean, ok := d.Mean()
+	if !ok {
+		return Welford{}
+	}
+	w := Welford{Count: d.Count(), Mean: mean}
+	for _, vc := range d {
+		w.M2 += (vc.Value - mean) * (vc.Value - mean) * float64(vc.Count)
+	}
+	return w
+}
+
+// Quantile devuelve el percentil p (0 a 100), interpolando linealmente entre los dos valores más
+// cercanos (el mismo criterio que PERCENTILE.INC de las hojas de cálculo). false si no hay valores
+func (d Distribution) Quantile(p float64) (float64, bool) {
+	n := d.Count()
+	if n == 0 {
+		return 0, false
+	}
+	rank := p / 100 * float64(n-1)
+	lower := d.at(int64(math.Floor(rank)))
+	upper := d.at(int64(math.Ceil(rank)))
+	return lower + (rank-math.Floor(rank))*(upper-lower), true
+}
+
+// at devuelve el valor en la posición i (desde 0) de la lista ordenada de valores
+func (d Distribution) at(i int64) float64 {
+	for _, vc := range d {
+		if i < vc.Count {
+			return vc.Value
+		}
+		i -= vc.Count
+	}
+	return d[len(d)-1].Value
+}
+
+// Histogram reparte los valores entre los intervalos que definen edges, ordenados de menor a mayor.
+// Se agregan un intervalo inicial y uno final abiertos, por lo que hay len(edges)+1 intervalos
+func (d Distribution) Histogram(edges []float64) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(edges)+1)
+	for i := range edges {
+		buckets[i].To = &edges[i]
+		buckets[i+1].From = &edges[i]
+	}
+	for _, vc := range d {
+		// El primer borde mayor que el valor marca su intervalo
+		buckets[sort.Search(len(edges), func(i int) bool { return edges[i] > vc.Value })].Count += vc.Count
+	}
+	return buckets
+}
+
+// Welford acumula la cantidad, la media y la suma de los cuadrados de las diferencias con la media (M2)
+// de una serie de valores. Permite agregar y quitar valores sin recorrer los demás (algoritmo de Welford)
+type Welford struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+}
+
+// Add agrega n veces el valor x
+func (w *Welford) Add(x float64, n int64) {
+	if n <= 0 {
+		return
+	}
+	total := w.Count + n
+	delta := x - w.Mean
+	w.Mean += delta * float64(n) / float64(total)
+	w.M2 += delta * delta * float64(w.Count) * float64(n) / float64(total)
+	w.Count = total
+}
+
+// Remove quita n veces el valor x, que debe haberse agregado antes
+func (w *Welford) Remove(x float64, n int64) {
+	if n <= 0 {
+		return
+	}
+	if n >= w.Count {
+		*w = Welford{}
+		return
+	}
+	rest := w.Count - n
+	mean := (w.Mean*float64(w.Count) - x*float64(n)) / float64(rest)
+	delta := x - mean
+	w.M2 -= delta * delta * float64(rest) * float64(n) / float64(w.Count)
+	// El redondeo puede dejar M2 apenas por debajo de cero
+	w.M2 = math.Max(w.M2, 0)
+	w.Mean, w.Count = mean, rest
+}
+
+// MeanValue devuelve la media; false si no hay valores
+func (w Welford) MeanValue() (float64, bool) {
+	return w.Mean, w.Count > 0
+}
+
+// StdDev devuelve la desviación estándar poblacional o, con sample, la muestral (n-1).
+// false si no está definida: sin valores, o con menos de dos valores para la muestral
+func (w Welford) StdDev(sample bool) (float64, bool) {
+	if w.Count == 0 || (sample && w.Count < 2) {
+		return 0, false
+	}
+	if sample {
+		return math.Sqrt(w.M2 / float64(w.Count-1)), true
+	}
+	return math.Sqrt(w.M2 / float64(w.Count)), true
+}
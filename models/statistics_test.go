@@ -0,0 +1,131 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDistribution(t *testing.T) {
+	d := NewDistribution([]float64{5, 2, 5, 9})
+	assert.Equal(t, Distribution{{2, 1}, {5, 2}, {9, 1}}, d)
+	assert.Equal(t, int64(4), d.Count())
+
+	merged := Distribution{{9, 1}, {5, 1}, {5, 2}}.Sorted()
+	assert.Equal(t, Distribution{{5, 3}, {9, 1}}, merged)
+}
+
+func TestDistributionMeanAndStdDev(t *testing.T) {
+	d := NewDistribution([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+
+	mean, ok := d.Mean()
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, mean)
+
+	std, ok := d.StdDev(false)
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, std)
+
+	std, ok = d.StdDev(true)
+	assert.True(t, ok)
+	assert.InDelta(t, 2.138090, std, 0.000001)
+
+	min, _ := d.Min()
+	max, _ := d.Max()
+	assert.Equal(t, []float64{2, 9}, []float64{min, max})
+
+	var empty Distribution
+	_, ok = empty.Mean()
+	assert.False(t, ok)
+	_, ok = empty.Min()
+	assert.False(t, ok)
+	_, ok = Distribution{{3, 1}}.StdDev(true)
+	assert.False(t, ok, "La desviación muestral necesita al menos dos valores")
+	std, ok = Distribution{{3, 1}}.StdDev(false)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, std)
+}
+
+func TestDistributionQuantile(t *testing.T) {
+	d := NewDistribution([]float64{15, 20, 35, 40, 50})
+
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{0, 15},
+		{10, 17},
+		{25, 20},
+		{50, 35},
+		{75, 40},
+		{90, 46},
+		{100, 50},
+	}
+	for _, tt := range tests {
+		q, ok := d.Quantile(tt.p)
+		assert.True(t, ok)
+		assert.InDelta(t, tt.expected, q, 0.000001, "p%v", tt.p)
+	}
+
+	// Con valores repetidos la posición se cuenta sobre la lista completa: 1 1 1 4
+	q, _ := Distribution{{1, 3}, {4, 1}}.Quantile(75)
+	assert.InDelta(t, 1.75, q, 0.000001)
+
+	q, ok := Distribution{{42, 1}}.Quantile(90)
+	assert.True(t, ok)
+	assert.Equal(t, 42.0, q)
+
+	_, ok = Distribution(nil).Quantile(50)
+	assert.False(t, ok)
+}
+
+func TestDistributionHistogram(t *testing.T) {
+	buckets := NewDistribution([]float64{10, 18, 24.5, 25, 70}).Histogram([]float64{18, 25, 65})
+
+	if assert.Len(t, buckets, 4) {
+		assert.Nil(t, buckets[0].From)
+		assert.Equal(t, 18.0, *buckets[0].To)
+		assert.Equal(t, 65.0, *buckets[3].From)
+		assert.Nil(t, buckets[3].To)
+		assert.Equal(t, []int64{1, 2, 1, 1}, []int64{buckets[0].Count, buckets[1].Count, buckets[2].Count, buckets[3].Count})
+	}
+
+	buckets = Distribution(nil).Histogram(nil)
+	assert.Equal(t, []HistogramBucket{{}}, buckets, "Sin bordes hay un único intervalo abierto")
+}
+
+func TestWelfordAddAndRemove(t *testing.T) {
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	var w Welford
+	for _, v := range values {
+		w.Add(v, 1)
+	}
+	assert.Equal(t, int64(8), w.Count)
+	assert.InDelta(t, 5.0, w.Mean, 0.000001)
+	std, _ := w.StdDev(false)
+	assert.InDelta(t, 2.0, std, 0.000001)
+
+	var grouped Welford
+	grouped.Add(4, 3)
+	grouped.Add(5, 2)
+	grouped.Add(2, 1)
+	grouped.Add(7, 1)
+	grouped.Add(9, 1)
+	assert.InDelta(t, w.Mean, grouped.Mean, 0.000001)
+	assert.InDelta(t, w.M2, grouped.M2, 0.000001)
+
+	// Quitar valores deja los agregados de los que quedan
+	w.Remove(9, 1)
+	w.Remove(4, 2)
+	expected := NewDistribution([]float64{2, 4, 5, 5, 7}).Welford()
+	assert.Equal(t, expected.Count, w.Count)
+	assert.InDelta(t, expected.Mean, w.Mean, 0.000001)
+	assert.InDelta(t, expected.M2, w.M2, 0.000001)
+
+	w.Remove(5, 10)
+	assert.Equal(t, Welford{}, w, "Quitar todos los valores vacía los agregados")
+	_, ok := w.MeanValue()
+	assert.False(t, ok)
+	_, ok = w.StdDev(false)
+	assert.False(t, ok)
+}
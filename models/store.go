@@ -0,0 +1,112 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Weekdays son los días con los que se indica el horario de una tienda, en el orden de time.Weekday
+var Weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// Store es una tienda en la que se atienden turnos. OpeningHours son los tramos en que abre cada día, en
+// la hora local de TimeZone; un día puede tener varios tramos y los días sin tramos está cerrada
+type Store struct {
+	ID           int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name         string         `json:"name" gorm:"not null;unique"`
+	TimeZone     string         `json:"time_zone" gorm:"not null" example:"Europe/Madrid"`
+	OpeningHours []OpeningHours `json:"opening_hours" gorm:"serializer:json"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// OpeningHours es un tramo de apertura de un día de la semana, de Opens a Closes (HH:MM)
+type OpeningHours struct {
+	Day    string `json:"day" enums:"monday,tuesday,wednesday,thursday,friday,saturday,sunday"`
+	Opens  string `json:"opens" example:"09:00"`
+	Closes string `json:"closes" example:"14:00"`
+}
+
+// Location devuelve la zona horaria de la tienda
+func (s *Store) Location() (*time.Location, error) {
+	return time.LoadLocation(s.TimeZone)
+}
+
+// IsOpen indica si la tienda está abierta todo el tiempo de start a end, dentro de un mismo tramo
+func (s *Store) IsOpen(start, end time.Time) bool {
+	loc, err := s.Location()
+	if err != nil {
+		return false
+	}
+	start, end = start.In(loc), end.In(loc)
+	y, m, d := start.Date()
+	day := Weekdays[start.Weekday()]
+	for _, hours := range s.OpeningHours {
+		opens, ok1 := clockMinutes(hours.Opens)
+		closes, ok2 := clockMinutes(hours.Closes)
+		if hours.Day == day && ok1 && ok2 &&
+			!start.Before(time.Date(y, m, d, opens/60, opens%60, 0, 0, loc)) &&
+			!end.After(time.Date(y, m, d, closes/60, closes%60, 0, 0, loc)) {
+			return true
+		}
+	}
+	return false
+}
+
+// clockMinutes convierte una hora HH:MM en minutos desde la medianoche
+func clockMinutes(clock string) (int, bool) {
+	h, m, ok := strings.Cut(clock, ":")
+	if !ok || len(h) != 2 || len(m) != 2 {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hours < 0 || hours > 23 || minutes < 0 || minutes > 59 {
+		return 0, false
+	}
+	return hours*60 + minutes, true
+}
+
+// BeforeSave quita los espacios del nombre y normaliza los días del horario
+func (s *Store) BeforeSave(tx *gorm.DB) error {
+	s.Name = strings.TrimSpace(s.Name)
+	for i := range s.OpeningHours {
+		s.OpeningHours[i].Day = strings.ToLower(strings.TrimSpace(s.OpeningHours[i].Day))
+	}
+	return nil
+}
+
+// ValidateStore comprueba todas las reglas de una tienda y devuelve cada violación encontrada
+func ValidateStore(store *Store) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(store.Name) == "" {
+		errs.add("name", CodeRequired, "Name is required")
+	}
+	if store.TimeZone == "" {
+		errs.add("time_zone", CodeRequired, "Time Zone is required")
+	} else if _, err := store.Location(); err != nil {
+		errs.add("time_zone", CodeInvalidChoice, "Time Zone must be an IANA time zone such as Europe/Madrid")
+	}
+
+	for i, hours := range store.OpeningHours {
+		prefix := "opening_hours[" + strconv.Itoa(i) + "]."
+		if !contains(Weekdays, strings.ToLower(strings.TrimSpace(hours.Day))) {
+			errs.add(prefix+"day", CodeInvalidChoice, "Day must be one of monday, tuesday, wednesday, thursday, friday, saturday, sunday")
+		}
+		opens, ok1 := clockMinutes(hours.Opens)
+		if !ok1 {
+			errs.add(prefix+"opens", CodeInvalidFormat, "Opens must be a time such as 09:00")
+		}
+		closes, ok2 := clockMinutes(hours.Closes)
+		if !ok2 {
+			errs.add(prefix+"closes", CodeInvalidFormat, "Closes must be a time such as 14:00")
+		}
+		if ok1 && ok2 && closes <= opens {
+			errs.add(prefix+"closes", CodeOutOfRange, "Closes must be after Opens")
+		}
+	}
+	return errs
+}
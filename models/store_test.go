@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreIsOpen(t *testing.T) {
+	store := Store{TimeZone: "Europe/Madrid", OpeningHours: []OpeningHours{
+		{Day: "monday", Opens: "09:00", Closes: "14:00"},
+		{Day: "monday", Opens: "16:00", Closes: "20:00"},
+		{Day: "sunday", Opens: "10:00", Closes: "13:00"},
+	}}
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, madrid)
+	}
+
+	assert.True(t, store.IsOpen(at(time.June, 17, 9, 0), at(time.June, 17, 10, 0)))
+	assert.True(t, store.IsOpen(at(time.June, 17, 13, 0), at(time.June, 17, 14, 0)), "Puede terminar a la hora de cierre")
+	assert.False(t, store.IsOpen(at(time.June, 17, 13, 30), at(time.June, 17, 14, 30)), "Termina después del cierre")
+	assert.False(t, store.IsOpen(at(time.June, 17, 13, 30), at(time.June, 17, 16, 30)), "No puede abarcar dos tramos")
+	assert.False(t, store.IsOpen(at(time.June, 18, 9, 0), at(time.June, 18, 10, 0)), "Los martes está cerrada")
+	assert.True(t, store.IsOpen(time.Date(2024, time.June, 17, 7, 0, 0, 0, time.UTC), time.Date(2024, time.June, 17, 8, 0, 0, 0, time.UTC)), "Las 7 UTC son las 9 en Madrid")
+
+	// El día del cambio de hora el horario sigue la hora local
+	assert.True(t, store.IsOpen(at(time.March, 31, 10, 0), at(time.March, 31, 11, 0)))
+	assert.Equal(t, 8, at(time.March, 31, 10, 0).UTC().Hour())
+}
+
+func TestValidateStoreReportsAllErrors(t *testing.T) {
+	store := Store{Name: " ", TimeZone: "Mars/Olympus", OpeningHours: []OpeningHours{
+		{Day: "Monday", Opens: "09:00", Closes: "14:00"},
+		{Day: "someday", Opens: "9", Closes: "25:00"},
+		{Day: "friday", Opens: "18:00", Closes: "10:00"},
+	}}
+
+	assert.Equal(t, ValidationErrors{
+		{Field: "name", Code: CodeRequired, Message: "Name is required"},
+		{Field: "time_zone", Code: CodeInvalidChoice, Message: "Time Zone must be an IANA time zone such as Europe/Madrid"},
+		{Field: "opening_hours[1].day", Code: CodeInvalidChoice, Message: "Day must be one of monday, tuesday, wednesday, thursday, friday, saturday, sunday"},
+		{Field: "opening_hours[1].opens", Code: CodeInvalidFormat, Message: "Opens must be a time such as 09:00"},
+		{Field: "opening_hours[1].closes", Code: CodeInvalidFormat, Message: "Closes must be a time such as 14:00"},
+		{Field: "opening_hours[2].closes", Code: CodeOutOfRange, Message: "Closes must be after Opens"},
+	}, ValidateStore(&store))
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -14,4 +18,11 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	LastLogin time.Time `json:"last_login"`
 	Groups    []Group   `json:"groups" gorm:"many2many:user_groups"`
+	Version   int       `json:"version" gorm:"not null;default:1"`
+}
+
+// BeforeCreate inicia la versión del usuario, que se incrementa en cada modificación
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	u.Version = 1
+	return nil
 }
\ No newline at end of file